@@ -0,0 +1,510 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type entry[T any] struct {
+	Key   string
+	Value T
+}
+
+type ordered[T any] []entry[T]
+
+func (o *ordered[T]) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		*o = append(*o, entry[T]{tok.(string), v})
+	}
+	return nil
+}
+
+type schema struct {
+	Ref                  string           `json:"$ref"`
+	Type                 string           `json:"type"`
+	Format               string           `json:"format"`
+	Items                *schema          `json:"items"`
+	Properties           ordered[*schema] `json:"properties"`
+	AdditionalProperties *schema          `json:"additionalProperties"`
+	Required             []string         `json:"required"`
+	Partial              bool             `json:"x-partial"`
+}
+
+type content map[string]struct {
+	Schema *schema `json:"schema"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Rest     bool    `json:"x-rest"`
+	Schema   *schema `json:"schema"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool    `json:"required"`
+		Content  content `json:"content"`
+	} `json:"requestBody"`
+	Responses ordered[struct {
+		Content content `json:"content"`
+	}] `json:"responses"`
+}
+
+type document struct {
+	Paths      ordered[ordered[json.RawMessage]] `json:"paths"`
+	Components struct {
+		Schemas ordered[*schema] `json:"schemas"`
+	} `json:"components"`
+}
+
+var initialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "urls": "URLs", "abr": "ABR", "dvr": "DVR", "epg": "EPG",
+	"ip": "IP", "http": "HTTP", "json": "JSON", "api": "API",
+}
+
+func goName(s string) string {
+	var b strings.Builder
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if v, ok := initialisms[strings.ToLower(w)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+func refName(ref string) string { return ref[strings.LastIndex(ref, "/")+1:] }
+
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+func (g *generator) printf(format string, args ...any) { fmt.Fprintf(&g.buf, format, args...) }
+
+func (g *generator) goType(s *schema, where string) (string, error) {
+	switch {
+	case s == nil:
+		return "any", nil
+	case s.Ref != "":
+		return refName(s.Ref), nil
+	case s.Type == "array":
+		t, err := g.goType(s.Items, where)
+		return "[]" + t, err
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		t, err := g.goType(s.AdditionalProperties, where)
+		return "map[string]" + t, err
+	case s.Type == "object" && len(s.Properties) > 0:
+		return "", fmt.Errorf("%s: inline object schemas are not supported, move it to components", where)
+	case s.Type == "object":
+		return "map[string]any", nil
+	case s.Type == "string" && s.Format == "date-time":
+		g.imports["time"] = true
+		return "time.Time", nil
+	case s.Type == "string" && s.Format == "binary":
+		return "[]byte", nil
+	case s.Type == "string":
+		return "string", nil
+	case s.Type == "integer" && s.Format == "int64":
+		return "int64", nil
+	case s.Type == "integer":
+		return "int", nil
+	case s.Type == "number":
+		return "float64", nil
+	case s.Type == "boolean":
+		return "bool", nil
+	}
+	return "", fmt.Errorf("%s: unsupported schema type %q", where, s.Type)
+}
+
+func (g *generator) typeDecl(name string, s *schema) error {
+	if s.Type != "object" || len(s.Properties) == 0 {
+		t, err := g.goType(s, name)
+		if err != nil {
+			return err
+		}
+		g.printf("type %s %s\n\n", name, t)
+		return nil
+	}
+	g.printf("type %s struct {\n", name)
+	for _, p := range s.Properties {
+		t, err := g.goType(p.Value, name+"."+p.Key)
+		if err != nil {
+			return err
+		}
+		tag := p.Key
+		if !slices.Contains(s.Required, p.Key) {
+			switch {
+			case p.Value.Ref != "" || s.Partial:
+				t = "*" + t
+				tag += ",omitempty"
+			case t == "time.Time":
+				tag += ",omitzero"
+			default:
+				tag += ",omitempty"
+			}
+		}
+		g.printf("\t%s %s `json:%q`\n", goName(p.Key), t, tag)
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+func jsonSchema(c content) (*schema, bool) {
+	if v, ok := c["application/json"]; ok {
+		return v.Schema, true
+	}
+	return nil, false
+}
+
+func onlyKey(c content) string {
+	for k := range c {
+		return k
+	}
+	return ""
+}
+
+func (g *generator) method(method, path string, op operation) error {
+	name := goName(strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:])
+	args := []string{"ctx context.Context"}
+	pathExpr := fmt.Sprintf("%q", path)
+	var query, header []parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			escape := "url.PathEscape"
+			if p.Rest {
+				escape = "escapeRest"
+			}
+			args = append(args, p.Name+" string")
+			pathExpr = strings.Replace(pathExpr, "{"+p.Name+"}", `" + `+escape+`(`+p.Name+`) + "`, 1)
+		case "query":
+			query = append(query, p)
+		case "header":
+			header = append(header, p)
+		default:
+			return fmt.Errorf("%s: unsupported parameter location %q", op.OperationID, p.In)
+		}
+	}
+	pathExpr = strings.TrimSuffix(pathExpr, ` + ""`)
+
+	var jsonBody bool
+	var contentType string
+	if rb := op.RequestBody; rb != nil {
+		if s, ok := jsonSchema(rb.Content); ok {
+			t, err := g.goType(s, op.OperationID+" request body")
+			if err != nil {
+				return err
+			}
+			if !rb.Required {
+				t = "*" + t
+			}
+			args = append(args, "body "+t)
+			jsonBody = true
+		} else {
+			contentType = onlyKey(rb.Content)
+			args = append(args, "body io.Reader")
+		}
+	}
+	if len(query)+len(header) > 0 {
+		args = append(args, "params "+name+"Params")
+	}
+
+	var result string
+	var redirect bool
+	for _, r := range op.Responses {
+		if r.Key == "default" || r.Key[0] != '2' && r.Key[0] != '3' {
+			continue
+		}
+		switch s, ok := jsonSchema(r.Value.Content); {
+		case ok:
+			t, err := g.goType(s, op.OperationID+" response")
+			if err != nil {
+				return err
+			}
+			result = t
+		case len(r.Value.Content) > 0:
+			result = "[]byte"
+		case r.Key[0] == '3':
+			result, redirect = "string", true
+		}
+		break
+	}
+
+	if len(query)+len(header) > 0 {
+		g.printf("type %sParams struct {\n", name)
+		for _, p := range slices.Concat(query, header) {
+			t, err := g.goType(p.Schema, op.OperationID+"."+p.Name)
+			if err != nil {
+				return err
+			}
+			g.printf("\t%s %s\n", goName(p.Name), t)
+		}
+		g.printf("}\n\n")
+	}
+
+	returns := "error"
+	zero := ""
+	if result != "" {
+		returns = "(" + result + ", error)"
+		zero = "out, "
+	}
+	g.printf("// %s %s\n", strings.ToUpper(method), path)
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+	if result != "" && !redirect {
+		g.printf("\tvar out %s\n", result)
+	}
+	g.printf("\treq := request{method: %q, path: %s}\n", strings.ToUpper(method), pathExpr)
+	if len(query) > 0 {
+		g.printf("\treq.query = url.Values{}\n")
+		for _, p := range query {
+			field := "params." + goName(p.Name)
+			switch p.Schema.Type {
+			case "integer":
+				g.imports["strconv"] = true
+				conv := "strconv.Itoa(" + field + ")"
+				if p.Schema.Format == "int64" {
+					conv = "strconv.FormatInt(" + field + ", 10)"
+				}
+				g.printf("\tif %s != 0 {\n\t\treq.query.Set(%q, %s)\n\t}\n", field, p.Name, conv)
+			case "boolean":
+				g.printf("\tif %s {\n\t\treq.query.Set(%q, \"true\")\n\t}\n", field, p.Name)
+			default:
+				g.printf("\tif %s != \"\" {\n\t\treq.query.Set(%q, %s)\n\t}\n", field, p.Name, field)
+			}
+		}
+	}
+	if len(header) > 0 {
+		g.printf("\treq.header = http.Header{}\n")
+		for _, p := range header {
+			field := "params." + goName(p.Name)
+			g.printf("\tif %s != \"\" {\n\t\treq.header.Set(%q, %s)\n\t}\n", field, p.Name, field)
+		}
+	}
+	switch {
+	case jsonBody && op.RequestBody.Required:
+		g.printf("\tif err := req.setJSON(body); err != nil {\n\t\treturn %serr\n\t}\n", zero)
+	case jsonBody:
+		g.printf("\tif body != nil {\n\t\tif err := req.setJSON(body); err != nil {\n\t\t\treturn %serr\n\t\t}\n\t}\n", zero)
+	case contentType != "":
+		g.printf("\treq.body, req.contentType = body, %q\n", contentType)
+	}
+	switch {
+	case redirect:
+		g.printf("\treturn c.location(ctx, req)\n")
+	case result != "":
+		g.printf("\terr := c.do(ctx, req, &out)\n\treturn out, err\n")
+	default:
+		g.printf("\treturn c.do(ctx, req, nil)\n")
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+const runtime = `
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (p *Problem) Error() string {
+	if p.Code == "" {
+		return fmt.Sprintf("%d %s", p.Status, p.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Code, p.Detail)
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        io.Reader
+	contentType string
+}
+
+func (r *request) setJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.body, r.contentType = bytes.NewReader(b), "application/json"
+	return nil
+}
+
+func escapeRest(s string) string {
+	parts := strings.Split(s, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func (c *Client) send(ctx context.Context, r request, follow bool) (*http.Response, error) {
+	u := c.BaseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, r.body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	if !follow {
+		nc := *hc
+		nc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		hc = &nc
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		defer res.Body.Close()
+		p := &Problem{Status: res.StatusCode, Title: http.StatusText(res.StatusCode)}
+		_ = json.NewDecoder(res.Body).Decode(p)
+		return nil, p
+	}
+	return res, nil
+}
+
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	res, err := c.send(ctx, r, true)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch out := out.(type) {
+	case nil:
+		_, err = io.Copy(io.Discard, res.Body)
+		return err
+	case *[]byte:
+		*out, err = io.ReadAll(res.Body)
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (c *Client) location(ctx context.Context, r request) (string, error) {
+	res, err := c.send(ctx, r, false)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	return res.Header.Get("Location"), nil
+}
+`
+
+func generate(doc document, pkg, source string) ([]byte, error) {
+	g := &generator{imports: map[string]bool{}}
+	for _, s := range doc.Components.Schemas {
+		if err := g.typeDecl(s.Key, s.Value); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range doc.Paths {
+		for _, m := range p.Value {
+			if m.Key == "parameters" {
+				return nil, fmt.Errorf("%s: path-level parameters are not supported", p.Key)
+			}
+			var op operation
+			if err := json.Unmarshal(m.Value, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", m.Key, p.Key, err)
+			}
+			if err := g.method(m.Key, p.Key, op); err != nil {
+				return nil, err
+			}
+		}
+	}
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"}
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	slices.Sort(imports)
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by apiclientgen from %s. DO NOT EDIT.\n\npackage %s\n\nimport (\n", source, pkg)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n")
+	out.WriteString(runtime)
+	out.WriteString("\n")
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+func main() {
+	specPath := flag.String("spec", "openapi.json", "OpenAPI document")
+	pkg := flag.String("package", "apiclient", "package name of the generated client")
+	outPath := flag.String("out", "", "output file (default stdout)")
+	flag.Parse()
+
+	b, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		log.Fatalf("%s: %v", *specPath, err)
+	}
+	src, err := generate(doc, *pkg, filepath.Base(*specPath))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outPath == "" {
+		_, _ = os.Stdout.Write(src)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(*outPath), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outPath, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
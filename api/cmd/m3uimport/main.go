@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type change struct {
+	StreamID string               `json:"stream_id"`
+	Name     string               `json:"name"`
+	Line     int                  `json:"line"`
+	Changes  map[string][2]string `json:"changes"`
+}
+
+type report struct {
+	DryRun    bool     `json:"dry_run"`
+	Created   []change `json:"created"`
+	Updated   []change `json:"updated"`
+	Unchanged []change `json:"unchanged"`
+	Skipped   []struct {
+		Line   int    `json:"line"`
+		Reason string `json:"reason"`
+	} `json:"skipped"`
+}
+
+func main() {
+	api := os.Getenv("STREAMWEB_API")
+	if api == "" {
+		api = "http://127.0.0.1:8080"
+	}
+	flag.StringVar(&api, "api", api, "control-plane API base URL")
+	token := flag.String("token", os.Getenv("STREAMWEB_TOKEN"), "admin access token")
+	dryRun := flag.Bool("dry-run", false, "show the diff without changing anything")
+	pointsRate := flag.Int("points-rate", 0, "points rate for new streams (server default when 0)")
+	maxSessions := flag.Int("max-sessions", 0, "max concurrent sessions for new streams (server default when 0)")
+	preset := flag.String("abr-preset", "", "ABR preset for new streams")
+	flag.Parse()
+	if flag.NArg() != 1 || *token == "" {
+		fmt.Println("usage: m3uimport -token TOKEN [-dry-run] [-points-rate N] <file.m3u|->")
+		os.Exit(2)
+	}
+
+	var in io.Reader = os.Stdin
+	if name := flag.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	q := url.Values{"dry_run": {strconv.FormatBool(*dryRun)}}
+	if *pointsRate > 0 {
+		q.Set("points_rate", strconv.Itoa(*pointsRate))
+	}
+	if *maxSessions > 0 {
+		q.Set("max_concurrent_sessions", strconv.Itoa(*maxSessions))
+	}
+	if *preset != "" {
+		q.Set("abr_preset", *preset)
+	}
+	req, err := http.NewRequest(http.MethodPost, api+"/streams/import?"+q.Encode(), in)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	req.Header.Set("Content-Type", "audio/x-mpegurl")
+	res, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		fmt.Printf("import failed: %s: %s", res.Status, body)
+		os.Exit(1)
+	}
+	var rep report
+	if err := json.NewDecoder(res.Body).Decode(&rep); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	printReport(rep)
+}
+
+func printReport(rep report) {
+	for _, c := range rep.Created {
+		fmt.Printf("+ %-24s %s\n", c.StreamID, c.Name)
+		printChanges(c.Changes)
+	}
+	for _, c := range rep.Updated {
+		fmt.Printf("~ %-24s %s\n", c.StreamID, c.Name)
+		printChanges(c.Changes)
+	}
+	for _, p := range rep.Skipped {
+		fmt.Printf("! line %d: %s\n", p.Line, p.Reason)
+	}
+	verb := "applied"
+	if rep.DryRun {
+		verb = "dry run, nothing changed"
+	}
+	fmt.Printf("%d created, %d updated, %d unchanged, %d skipped (%s)\n", len(rep.Created), len(rep.Updated), len(rep.Unchanged), len(rep.Skipped), verb)
+}
+
+func printChanges(changes map[string][2]string) {
+	fields := make([]string, 0, len(changes))
+	for f := range changes {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		if old := changes[f][0]; old != "" {
+			fmt.Printf("    %s: %q -> %q\n", f, old, changes[f][1])
+		} else {
+			fmt.Printf("    %s: %q\n", f, changes[f][1])
+		}
+	}
+}
@@ -1,22 +1,170 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata"
 
+	"streamweb/api/internal/auth"
+	"streamweb/api/internal/config"
+	"streamweb/api/internal/gateway"
 	"streamweb/api/internal/httpapi"
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/lifecycle"
+	"streamweb/api/internal/segstore"
 	"streamweb/api/internal/service"
 	"streamweb/api/internal/store"
 )
 
-func main() {
-	st := store.NewMemoryStore()
-	svc := service.New(st)
+func main() { os.Exit(run()) }
+
+func run() int {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Println("config:", err)
+		return 1
+	}
+	tokens := auth.EphemeralSigner()
+	if len(cfg.Tokens.SigningKeys) > 0 {
+		if tokens, err = auth.NewSigner(cfg.Tokens.SigningKeys...); err != nil {
+			fmt.Println("config:", err)
+			return 1
+		}
+	} else {
+		log.Printf("config: tokens.signing_keys not set, using an ephemeral key; tokens will not survive a restart")
+	}
+	if cfg.Workers.Token == "" {
+		log.Printf("config: workers.token not set, /internal worker endpoints reject every request")
+	}
+
+	gen := ids.NewULID()
+	var st store.Repository = store.NewMemoryStore(gen)
+	if cfg.Store.Backend == "postgres" {
+		pg, err := store.OpenPostgres(cfg.Store.DSN, gen)
+		if err != nil {
+			fmt.Println("store:", err)
+			return 1
+		}
+		defer pg.Close()
+		st = pg
+	}
+	sc := cfg.Storage
+	segments, err := segstore.Open(segstore.Config{
+		Backend: sc.Backend,
+		Dir:     sc.Dir,
+		S3:      segstore.S3Config{Endpoint: sc.S3Endpoint, Region: sc.S3Region, Bucket: sc.S3Bucket, AccessKey: sc.S3AccessKey, SecretKey: sc.S3SecretKey},
+	})
+	if err != nil {
+		fmt.Println("segment storage:", err)
+		return 1
+	}
+	svc := service.New(st, segments, gen, tokens)
 	srv := httpapi.NewServer(svc)
+	if err := applyConfig(cfg, svc, srv); err != nil {
+		fmt.Println("config:", err)
+		return 1
+	}
+	log.Printf("config: %s", cfg)
+
+	router := httpapi.NewRouter(httpapi.RequestID(gen), httpapi.Logging, httpapi.Recover, httpapi.CORS(cfg.CORSOrigins))
+	srv.Register(router)
+	gateway.New(svc, segments).Register(router)
+
+	bg := cfg.Background
+	policy := service.RetentionPolicy{DVRMargin: bg.RetentionDVRMargin, OrphanGrace: bg.RetentionOrphanGrace}
+	lc := lifecycle.New()
+	lc.Go("config-reload", func(ctx context.Context) { reloadOnHangup(ctx, cfg, svc, srv) })
+	lc.Go("retention", func(ctx context.Context) { svc.RunRetention(ctx, bg.RetentionInterval, policy) })
+	lc.Go("segment-billing", func(ctx context.Context) { svc.RunSegmentBilling(ctx, service.BillingInterval) })
+	lc.Go("scheduler", func(ctx context.Context) { svc.RunScheduler(ctx, bg.SchedulerInterval) })
+	lc.Serve("http", &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	})
 
-	mux := http.NewServeMux()
-	srv.Register(mux)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := lc.Start(ctx); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	code := 0
+	select {
+	case <-ctx.Done():
+		log.Printf("shutdown: signal received, draining for up to %s", cfg.HTTP.ShutdownTimeout)
+	case err := <-lc.Failed():
+		log.Printf("shutdown: %v", err)
+		code = 1
+	}
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := lc.Stop(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+		code = 1
+	}
+	log.Printf("shutdown: done")
+	return code
+}
+
+func applyConfig(cfg config.Config, svc *service.Service, srv *httpapi.Server) error {
+	err := svc.Configure(service.Options{
+		PublicBaseURL:      cfg.PublicBaseURL,
+		PlayTokenTTL:       cfg.Tokens.PlayTTL,
+		LauncherTokenTTL:   cfg.Tokens.LauncherTTL,
+		FFmpegPath:         cfg.Media.FFmpeg,
+		FFprobePath:        cfg.Media.FFprobe,
+		DefaultBillingMode: cfg.Media.BillingMode,
+		RTMPPublishURL:     cfg.Ingest.RTMPPublishURL,
+	})
+	if err != nil {
+		return err
+	}
+	rl := cfg.RateLimits
+	srv.SetRateLimits(httpapi.RateLimits{Login: rl.Login, PlaybackStart: rl.PlaybackStart, Launch: rl.Launch, Window: rl.Window})
+	srv.SetTrustedProxies(cfg.HTTP.TrustedProxies)
+	srv.SetWorkerToken(cfg.Workers.Token)
+	return nil
+}
 
-	fmt.Println("API listening on :8080")
-	_ = http.ListenAndServe(":8080", mux)
+func reloadOnHangup(ctx context.Context, cfg config.Config, svc *service.Service, srv *httpapi.Server) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		next, err := config.Load(os.Args[1:], os.Getenv)
+		if err != nil {
+			log.Printf("config: reload failed, keeping current settings: %v", err)
+			continue
+		}
+		merged, ignored := cfg.Reload(next)
+		for _, key := range ignored {
+			log.Printf("config: %s changed, restart to apply", key)
+		}
+		if err := applyConfig(merged, svc, srv); err != nil {
+			log.Printf("config: reload failed, keeping current settings: %v", err)
+			continue
+		}
+		cfg = merged
+		log.Printf("config: reloaded: %s", cfg)
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"streamweb/api/internal/pipeline"
+	"streamweb/api/internal/segstore"
+)
+
+func main() {
+	host, _ := os.Hostname()
+	api := os.Getenv("STREAMWEB_API")
+	if api == "" {
+		api = "http://127.0.0.1:8080"
+	}
+	cfg := pipeline.Config{}
+	var streams string
+	flag.StringVar(&cfg.WorkerID, "id", "worker-"+host, "worker id reported to the control plane")
+	flag.StringVar(&cfg.APIBase, "api", api, "control-plane API base URL")
+	flag.StringVar(&cfg.APIToken, "token", os.Getenv("STREAMWEB_WORKER_TOKEN"), "shared worker token for the control-plane /internal endpoints (env STREAMWEB_WORKER_TOKEN)")
+	flag.StringVar(&cfg.OutputDir, "out", "./hls", "local HLS output directory")
+	flag.StringVar(&cfg.FFmpegPath, "ffmpeg", "ffmpeg", "ffmpeg binary")
+	flag.StringVar(&streams, "streams", "", "comma separated stream ids to run")
+	flag.DurationVar(&cfg.HeartbeatInterval, "heartbeat", 10*time.Second, "heartbeat interval")
+	flag.StringVar(&cfg.RTMPAddr, "rtmp", "", "RTMP push ingest listen address, e.g. :1935 (disabled when empty)")
+	flag.Parse()
+
+	for _, id := range strings.Split(streams, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.StreamIDs = append(cfg.StreamIDs, id)
+		}
+	}
+	if len(cfg.StreamIDs) == 0 {
+		fmt.Println("usage: worker -streams <id,id,...> [-api URL] [-out DIR]")
+		os.Exit(1)
+	}
+
+	store, err := segstore.FromEnv()
+	if err != nil {
+		fmt.Println("segment storage:", err)
+		os.Exit(1)
+	}
+	cfg.Store = store
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("worker %s running %d streams\n", cfg.WorkerID, len(cfg.StreamIDs))
+	_ = pipeline.NewWorker(cfg).Run(ctx)
+}
@@ -1,19 +1,92 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func TokenForUser(userID, role string) string {
-	return fmt.Sprintf("token:%s:%s", userID, role)
+const MinKeyLength = 32
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Signer struct {
+	keys [][]byte
+}
+
+func NewSigner(keys ...string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	s := &Signer{}
+	for i, k := range keys {
+		if len(k) < MinKeyLength {
+			return nil, fmt.Errorf("signing key %d is shorter than %d bytes", i+1, MinKeyLength)
+		}
+		s.keys = append(s.keys, []byte(k))
+	}
+	return s, nil
 }
 
-func ParseUserToken(token string) (string, string, error) {
-	parts := strings.Split(token, ":")
-	if len(parts) != 3 || parts[0] != "token" {
-		return "", "", errors.New("invalid token")
+func EphemeralSigner() *Signer {
+	key := make([]byte, MinKeyLength)
+	_, _ = rand.Read(key)
+	return &Signer{keys: [][]byte{key}}
+}
+
+func (s *Signer) mac(key []byte, payload string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (s *Signer) sign(payload string) string { return payload + "." + s.mac(s.keys[0], payload) }
+
+func (s *Signer) verify(token string) (string, bool) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	for _, k := range s.keys {
+		if hmac.Equal([]byte(sig), []byte(s.mac(k, payload))) {
+			return payload, true
+		}
+	}
+	return "", false
+}
+
+func (s *Signer) UserToken(userID, role string) string {
+	return s.sign(fmt.Sprintf("token:%s:%s", userID, role))
+}
+
+func (s *Signer) ParseUserToken(token string) (string, string, error) {
+	payload, ok := s.verify(token)
+	parts := strings.Split(payload, ":")
+	if !ok || len(parts) != 3 || parts[0] != "token" {
+		return "", "", ErrInvalidToken
 	}
 	return parts[1], parts[2], nil
 }
+
+func (s *Signer) PlayToken(sessionID string, expires time.Time) string {
+	return s.sign(fmt.Sprintf("play:%s:%d", sessionID, expires.Unix()))
+}
+
+func (s *Signer) ParsePlayToken(token string) (string, time.Time, error) {
+	payload, ok := s.verify(token)
+	parts := strings.Split(payload, ":")
+	if !ok || len(parts) != 3 || parts[0] != "play" {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	return parts[1], time.Unix(exp, 0), nil
+}
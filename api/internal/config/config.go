@@ -0,0 +1,434 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/auth"
+)
+
+type Config struct {
+	Listen        string
+	PublicBaseURL string
+	CORSOrigins   []string
+	HTTP          HTTP
+	Tokens        Tokens
+	Workers       Workers
+	RateLimits    RateLimits
+	Store         Store
+	Storage       Storage
+	Ingest        Ingest
+	Media         Media
+	Background    Background
+}
+
+type HTTP struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	TrustedProxies    []netip.Prefix
+}
+
+type Tokens struct {
+	PlayTTL     time.Duration
+	LauncherTTL time.Duration
+	SigningKeys []string
+}
+
+type Workers struct {
+	Token string
+}
+
+type RateLimits struct {
+	Login         int
+	PlaybackStart int
+	Launch        int
+	Window        time.Duration
+}
+
+type Store struct {
+	Backend string
+	DSN     string
+}
+
+type Storage struct {
+	Backend     string
+	Dir         string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+type Ingest struct {
+	RTMPPublishURL string
+}
+
+type Media struct {
+	FFmpeg      string
+	FFprobe     string
+	BillingMode string
+}
+
+type Background struct {
+	SchedulerInterval    time.Duration
+	RetentionInterval    time.Duration
+	RetentionDVRMargin   time.Duration
+	RetentionOrphanGrace time.Duration
+}
+
+func Default() Config {
+	return Config{
+		Listen:        ":8080",
+		PublicBaseURL: "http://localhost:8088",
+		HTTP: HTTP{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Tokens:     Tokens{PlayTTL: 90 * time.Second, LauncherTTL: 6 * time.Hour},
+		RateLimits: RateLimits{Login: 20, PlaybackStart: 30, Launch: 60, Window: time.Minute},
+		Store:      Store{Backend: "memory"},
+		Storage:    Storage{Backend: "fs", Dir: "./data/streams", S3Region: "us-east-1"},
+		Ingest:     Ingest{RTMPPublishURL: "rtmp://localhost:1935/live/"},
+		Media:      Media{FFmpeg: "ffmpeg", FFprobe: "ffprobe", BillingMode: "heartbeat"},
+		Background: Background{
+			SchedulerInterval:    15 * time.Second,
+			RetentionInterval:    time.Minute,
+			RetentionOrphanGrace: 10 * time.Minute,
+		},
+	}
+}
+
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	reload bool
+	secret bool
+	value  flag.Value
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "listen", env: "STREAMWEB_LISTEN", usage: "HTTP listen address", value: (*stringValue)(&c.Listen)},
+		{key: "public_base_url", env: "STREAMWEB_PUBLIC_BASE_URL", usage: "base URL of play, launch and guide links", reload: true, value: (*stringValue)(&c.PublicBaseURL)},
+		{key: "cors_origins", env: "STREAMWEB_CORS_ORIGINS", usage: "comma-separated CORS origins or *", value: (*listValue)(&c.CORSOrigins)},
+		{key: "http.read_header_timeout", env: "STREAMWEB_HTTP_READ_HEADER_TIMEOUT", usage: "time allowed to read request headers", value: (*durationValue)(&c.HTTP.ReadHeaderTimeout)},
+		{key: "http.read_timeout", env: "STREAMWEB_HTTP_READ_TIMEOUT", usage: "time allowed to read a whole request", value: (*durationValue)(&c.HTTP.ReadTimeout)},
+		{key: "http.write_timeout", env: "STREAMWEB_HTTP_WRITE_TIMEOUT", usage: "time allowed to write a response", value: (*durationValue)(&c.HTTP.WriteTimeout)},
+		{key: "http.idle_timeout", env: "STREAMWEB_HTTP_IDLE_TIMEOUT", usage: "keep-alive idle timeout", value: (*durationValue)(&c.HTTP.IdleTimeout)},
+		{key: "http.shutdown_timeout", env: "STREAMWEB_SHUTDOWN_TIMEOUT", usage: "deadline for draining requests and stopping background work", value: (*durationValue)(&c.HTTP.ShutdownTimeout)},
+		{key: "http.trusted_proxies", env: "STREAMWEB_TRUSTED_PROXIES", usage: "comma-separated proxy IPs or CIDRs whose X-Real-IP header is trusted", reload: true, value: (*prefixListValue)(&c.HTTP.TrustedProxies)},
+		{key: "tokens.play_ttl", env: "STREAMWEB_PLAY_TOKEN_TTL", usage: "play token lifetime", reload: true, value: (*durationValue)(&c.Tokens.PlayTTL)},
+		{key: "tokens.launcher_ttl", env: "STREAMWEB_LAUNCHER_TOKEN_TTL", usage: "play token lifetime for launcher sessions", reload: true, value: (*durationValue)(&c.Tokens.LauncherTTL)},
+		{key: "tokens.signing_keys", env: "STREAMWEB_SIGNING_KEYS", usage: "comma-separated token signing keys, the first one signs", secret: true, value: (*listValue)(&c.Tokens.SigningKeys)},
+		{key: "workers.token", env: "STREAMWEB_WORKER_TOKEN", usage: "shared token workers send to the /internal endpoints", reload: true, secret: true, value: (*stringValue)(&c.Workers.Token)},
+		{key: "rate_limits.login", env: "STREAMWEB_RATE_LIMIT_LOGIN", usage: "logins per client IP and window", reload: true, value: (*intValue)(&c.RateLimits.Login)},
+		{key: "rate_limits.playback_start", env: "STREAMWEB_RATE_LIMIT_PLAYBACK_START", usage: "playback starts per client IP and window", reload: true, value: (*intValue)(&c.RateLimits.PlaybackStart)},
+		{key: "rate_limits.launch", env: "STREAMWEB_RATE_LIMIT_LAUNCH", usage: "launcher requests per client IP and window", reload: true, value: (*intValue)(&c.RateLimits.Launch)},
+		{key: "rate_limits.window", env: "STREAMWEB_RATE_LIMIT_WINDOW", usage: "rate limit window", reload: true, value: (*durationValue)(&c.RateLimits.Window)},
+		{key: "store.backend", env: "STREAMWEB_STORE", usage: "repository backend, memory or postgres", value: (*stringValue)(&c.Store.Backend)},
+		{key: "store.dsn", env: "STREAMWEB_STORE_DSN", usage: "PostgreSQL connection string for the postgres backend", secret: true, value: (*stringValue)(&c.Store.DSN)},
+		{key: "storage.backend", env: "STREAMWEB_STORAGE", usage: "segment storage, fs or s3", value: (*stringValue)(&c.Storage.Backend)},
+		{key: "storage.dir", env: "STREAMWEB_STORAGE_DIR", usage: "segment directory for the fs backend", value: (*stringValue)(&c.Storage.Dir)},
+		{key: "storage.s3_endpoint", env: "STREAMWEB_S3_ENDPOINT", usage: "S3-compatible endpoint URL", value: (*stringValue)(&c.Storage.S3Endpoint)},
+		{key: "storage.s3_region", env: "STREAMWEB_S3_REGION", usage: "S3 signing region", value: (*stringValue)(&c.Storage.S3Region)},
+		{key: "storage.s3_bucket", env: "STREAMWEB_S3_BUCKET", usage: "S3 bucket", value: (*stringValue)(&c.Storage.S3Bucket)},
+		{key: "storage.s3_access_key", env: "STREAMWEB_S3_ACCESS_KEY", usage: "S3 access key id", value: (*stringValue)(&c.Storage.S3AccessKey)},
+		{key: "storage.s3_secret_key", env: "STREAMWEB_S3_SECRET_KEY", usage: "S3 secret access key", secret: true, value: (*stringValue)(&c.Storage.S3SecretKey)},
+		{key: "ingest.rtmp_publish_url", env: "STREAMWEB_RTMP_PUBLISH_URL", usage: "RTMP base URL handed out with push stream keys", reload: true, value: (*stringValue)(&c.Ingest.RTMPPublishURL)},
+		{key: "media.ffmpeg", env: "STREAMWEB_FFMPEG", usage: "ffmpeg binary for MP4 clips", reload: true, value: (*stringValue)(&c.Media.FFmpeg)},
+		{key: "media.ffprobe", env: "STREAMWEB_FFPROBE", usage: "ffprobe binary for ingest probes", reload: true, value: (*stringValue)(&c.Media.FFprobe)},
+		{key: "media.billing_mode", env: "STREAMWEB_BILLING_MODE", usage: "default billing mode, heartbeat or segments", reload: true, value: (*stringValue)(&c.Media.BillingMode)},
+		{key: "background.scheduler_interval", env: "STREAMWEB_SCHEDULER_INTERVAL", usage: "schedule evaluation interval", value: (*durationValue)(&c.Background.SchedulerInterval)},
+		{key: "background.retention_interval", env: "STREAMWEB_RETENTION_INTERVAL", usage: "segment retention sweep interval", value: (*durationValue)(&c.Background.RetentionInterval)},
+		{key: "background.retention_dvr_margin", env: "STREAMWEB_RETENTION_DVR_MARGIN", usage: "extra segment age kept beyond the DVR window", value: (*durationValue)(&c.Background.RetentionDVRMargin)},
+		{key: "background.retention_orphan_grace", env: "STREAMWEB_RETENTION_ORPHAN_GRACE", usage: "age before segments of unknown streams are deleted", value: (*durationValue)(&c.Background.RetentionOrphanGrace)},
+	}
+}
+
+func flagName(key string) string { return strings.ReplaceAll(key, "_", "-") }
+
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", getenv("STREAMWEB_CONFIG"), "optional TOML config file (env STREAMWEB_CONFIG)")
+	byFlag := map[string]setting{}
+	for _, s := range settings {
+		fs.String(flagName(s.key), s.value.String(), s.usage+" (env "+s.env+")")
+		byFlag[flagName(s.key)] = s
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	var errs []error
+	if *path != "" {
+		values, err := readFile(*path)
+		if err != nil {
+			return cfg, err
+		}
+		for _, s := range settings {
+			if v, ok := values[s.key]; ok {
+				if err := s.value.Set(v); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %s: %w", *path, s.key, err))
+				}
+				delete(values, s.key)
+			}
+		}
+		unknown := make([]string, 0, len(values))
+		for k := range values {
+			unknown = append(unknown, k)
+		}
+		slices.Sort(unknown)
+		for _, k := range unknown {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %s", *path, k))
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok {
+			if err := s.value.Set(f.Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return cfg, err
+	}
+	cfg.PublicBaseURL = strings.TrimRight(cfg.PublicBaseURL, "/")
+	return cfg, cfg.Validate()
+}
+
+func readFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values, err := parseTOML(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		bad("listen", "must be host:port")
+	}
+	if u, err := url.Parse(c.PublicBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		bad("public_base_url", "must be an absolute http(s) URL without query")
+	}
+	for _, o := range c.CORSOrigins {
+		if u, err := url.Parse(o); o != "*" && (err != nil || u.Scheme == "" || u.Host == "" || u.Path != "") {
+			bad("cors_origins", "%q is not an origin", o)
+		}
+	}
+	for key, d := range map[string]time.Duration{"http.read_header_timeout": c.HTTP.ReadHeaderTimeout, "http.read_timeout": c.HTTP.ReadTimeout, "http.write_timeout": c.HTTP.WriteTimeout, "http.idle_timeout": c.HTTP.IdleTimeout, "http.shutdown_timeout": c.HTTP.ShutdownTimeout} {
+		if d < time.Second {
+			bad(key, "must be at least 1s")
+		}
+	}
+	if c.HTTP.ReadHeaderTimeout > c.HTTP.ReadTimeout {
+		bad("http.read_header_timeout", "must not exceed http.read_timeout")
+	}
+	if c.Tokens.PlayTTL < 10*time.Second {
+		bad("tokens.play_ttl", "must be at least 10s")
+	}
+	if c.Tokens.LauncherTTL < c.Tokens.PlayTTL {
+		bad("tokens.launcher_ttl", "must not be shorter than tokens.play_ttl")
+	}
+	for i, k := range c.Tokens.SigningKeys {
+		if len(k) < auth.MinKeyLength {
+			bad("tokens.signing_keys", "key %d is shorter than %d bytes", i+1, auth.MinKeyLength)
+		}
+	}
+	if c.Workers.Token != "" && len(c.Workers.Token) < auth.MinKeyLength {
+		bad("workers.token", "must be at least %d bytes", auth.MinKeyLength)
+	}
+	for key, n := range map[string]int{"rate_limits.login": c.RateLimits.Login, "rate_limits.playback_start": c.RateLimits.PlaybackStart, "rate_limits.launch": c.RateLimits.Launch} {
+		if n < 1 {
+			bad(key, "must be at least 1")
+		}
+	}
+	if c.RateLimits.Window < time.Second {
+		bad("rate_limits.window", "must be at least 1s")
+	}
+	switch c.Store.Backend {
+	case "memory":
+	case "postgres":
+		if c.Store.DSN == "" {
+			bad("store.dsn", "must not be empty")
+		}
+	default:
+		bad("store.backend", "unsupported backend %q (available: memory, postgres)", c.Store.Backend)
+	}
+	switch c.Storage.Backend {
+	case "fs":
+		if c.Storage.Dir == "" {
+			bad("storage.dir", "must not be empty")
+		}
+	case "s3":
+		if u, err := url.Parse(c.Storage.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad("storage.s3_endpoint", "must be an absolute http(s) URL")
+		}
+		if c.Storage.S3Bucket == "" {
+			bad("storage.s3_bucket", "must not be empty")
+		}
+		if c.Storage.S3Region == "" {
+			bad("storage.s3_region", "must not be empty")
+		}
+	default:
+		bad("storage.backend", "must be fs or s3")
+	}
+	if u, err := url.Parse(c.Ingest.RTMPPublishURL); err != nil || (u.Scheme != "rtmp" && u.Scheme != "rtmps") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		bad("ingest.rtmp_publish_url", "must be an absolute rtmp(s) URL without query")
+	}
+	if c.Media.FFmpeg == "" {
+		bad("media.ffmpeg", "must not be empty")
+	}
+	if c.Media.FFprobe == "" {
+		bad("media.ffprobe", "must not be empty")
+	}
+	if c.Media.BillingMode != "heartbeat" && c.Media.BillingMode != "segments" {
+		bad("media.billing_mode", "must be heartbeat or segments")
+	}
+	if c.Background.SchedulerInterval < time.Second {
+		bad("background.scheduler_interval", "must be at least 1s")
+	}
+	if c.Background.RetentionInterval < time.Second {
+		bad("background.retention_interval", "must be at least 1s")
+	}
+	if c.Background.RetentionDVRMargin < 0 {
+		bad("background.retention_dvr_margin", "must not be negative")
+	}
+	if c.Background.RetentionOrphanGrace < 0 {
+		bad("background.retention_orphan_grace", "must not be negative")
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+func (c Config) Reload(next Config) (Config, []string) {
+	merged := next
+	var ignored []string
+	cur, nx, mg := c.settings(), next.settings(), merged.settings()
+	for i, s := range cur {
+		if s.reload || s.value.String() == nx[i].value.String() {
+			continue
+		}
+		_ = mg[i].value.Set(s.value.String())
+		ignored = append(ignored, s.key)
+	}
+	return merged, ignored
+}
+
+func (c Config) String() string {
+	var b strings.Builder
+	for i, s := range c.settings() {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		v := s.value.String()
+		if s.secret && v != "" {
+			v = "<redacted>"
+		}
+		b.WriteString(s.key + "=" + strconv.Quote(v))
+	}
+	return b.String()
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*v = append(*v, item)
+		}
+	}
+	return nil
+}
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+type prefixListValue []netip.Prefix
+
+func (v *prefixListValue) Set(s string) error {
+	var items listValue
+	_ = items.Set(s)
+	*v = nil
+	for _, item := range items {
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(item)
+			if aerr != nil {
+				return fmt.Errorf("invalid IP or CIDR %q", item)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		*v = append(*v, p.Masked())
+	}
+	return nil
+}
+func (v *prefixListValue) String() string {
+	items := make([]string, len(*v))
+	for i, p := range *v {
+		items[i] = p.String()
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "streamweb.toml")
+	if err := os.WriteFile(file, []byte("[store]\nbackend = \"postgres\"\ndsn = \"postgres://file@db/stream\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		backend string
+		dsn     string
+		wantErr string
+	}{
+		{name: "default", backend: "memory"},
+		{name: "env", env: map[string]string{"STREAMWEB_STORE": "postgres", "STREAMWEB_STORE_DSN": "postgres://env@db/stream"}, backend: "postgres", dsn: "postgres://env@db/stream"},
+		{name: "file", args: []string{"-config", file}, backend: "postgres", dsn: "postgres://file@db/stream"},
+		{name: "flag beats env beats file", args: []string{"-config", file, "-store.dsn", "postgres://flag@db/stream"}, env: map[string]string{"STREAMWEB_STORE_DSN": "postgres://env@db/stream"}, backend: "postgres", dsn: "postgres://flag@db/stream"},
+		{name: "postgres without dsn", env: map[string]string{"STREAMWEB_STORE": "postgres"}, wantErr: "store.dsn: must not be empty"},
+		{name: "unknown backend", env: map[string]string{"STREAMWEB_STORE": "sqlite"}, wantErr: `store.backend: unsupported backend "sqlite" (available: memory, postgres)`},
+		{name: "memory ignores dsn", env: map[string]string{"STREAMWEB_STORE_DSN": "postgres://env@db/stream"}, backend: "memory", dsn: "postgres://env@db/stream"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(tc.args, func(k string) string { return tc.env[k] })
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Store.Backend != tc.backend || cfg.Store.DSN != tc.dsn {
+				t.Errorf("Store = %+v, want backend %q dsn %q", cfg.Store, tc.backend, tc.dsn)
+			}
+		})
+	}
+}
+
+func TestStoreDSNIsSecretAndNotReloaded(t *testing.T) {
+	cfg := Default()
+	cfg.Store = Store{Backend: "postgres", DSN: "postgres://stream:hunter2@db/stream"}
+	if s := cfg.String(); strings.Contains(s, "hunter2") || !strings.Contains(s, `store.dsn="<redacted>"`) {
+		t.Errorf("String() = %s, want the dsn redacted", s)
+	}
+	next := cfg
+	next.Store.DSN = "postgres://other@db/stream"
+	merged, ignored := cfg.Reload(next)
+	if merged.Store.DSN != cfg.Store.DSN || len(ignored) != 1 || ignored[0] != "store.dsn" {
+		t.Errorf("Reload = %q, ignored %v; want the running dsn kept and store.dsn ignored", merged.Store.DSN, ignored)
+	}
+}
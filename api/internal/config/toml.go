@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func parseTOML(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	section := ""
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			name, rest, ok := strings.Cut(line[1:], "]")
+			if rest = strings.TrimSpace(rest); !ok || !isBareKey(name) || rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: invalid table header", n)
+			}
+			section = name + "."
+			continue
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		if key = strings.TrimSpace(key); !ok || !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		v, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, key, err)
+		}
+		if _, dup := values[section+key]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", n, section+key)
+		}
+		values[section+key] = v
+	}
+	return values, sc.Err()
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func parseValue(s string) (string, error) {
+	var v, rest string
+	var err error
+	if strings.HasPrefix(s, "[") {
+		v, rest, err = parseArray(s[1:])
+	} else {
+		v, rest, err = parseScalar(s)
+	}
+	if err != nil {
+		return "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+		return "", fmt.Errorf("unexpected %q after value", rest)
+	}
+	return v, nil
+}
+
+func parseArray(s string) (string, string, error) {
+	var items []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return "", "", fmt.Errorf("unterminated array, arrays must fit on one line")
+		}
+		if s[0] == ']' {
+			return strings.Join(items, ","), s[1:], nil
+		}
+		item, rest, err := parseScalar(s)
+		if err != nil {
+			return "", "", err
+		}
+		if strings.Contains(item, ",") {
+			return "", "", fmt.Errorf("array items must not contain commas")
+		}
+		items = append(items, item)
+		rest = strings.TrimLeft(rest, " \t")
+		if strings.HasPrefix(rest, ",") {
+			rest = rest[1:]
+		} else if !strings.HasPrefix(rest, "]") {
+			return "", "", fmt.Errorf("expected , or ] in array")
+		}
+		s = rest
+	}
+}
+
+func parseScalar(s string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				v, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+				}
+				return v, s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated string")
+	case strings.HasPrefix(s, "'"):
+		v, rest, ok := strings.Cut(s[1:], "'")
+		if !ok {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return v, rest, nil
+	}
+	end := strings.IndexAny(s, ",]#")
+	if end < 0 {
+		end = len(s)
+	}
+	v := strings.TrimSpace(s[:end])
+	if v == "" {
+		return "", "", fmt.Errorf("missing value")
+	}
+	return v, s[end:], nil
+}
@@ -0,0 +1,135 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		expr, same string
+		wantErr    bool
+	}{
+		{expr: "*/15 0-6,22-23 * * mon-fri", same: "0,15,30,45 0,1,2,3,4,5,6,22,23 * * 1,2,3,4,5"},
+		{expr: "5/20 * * * *", same: "5,25,45 * * * *"},
+		{expr: "0 0 * JAN-mar SUN", same: "0 0 * 1,2,3 0"},
+		{expr: "0 0 * * 7", same: "0 0 * * 0"},
+		{expr: "0 0 * * 5-7", same: "0 0 * * 0,5,6"},
+		{expr: "0 12 ? * ?", same: "0 12 * * *"},
+		{expr: "  @yearly ", same: "0 0 1 1 *"},
+		{expr: "@annually", same: "0 0 1 1 *"},
+		{expr: "@monthly", same: "0 0 1 * *"},
+		{expr: "@WEEKLY", same: "0 0 * * 0"},
+		{expr: "@daily", same: "0 0 * * *"},
+		{expr: "@midnight", same: "0 0 * * *"},
+		{expr: "@hourly", same: "0 * * * *"},
+		{expr: "", wantErr: true},
+		{expr: "* * * *", wantErr: true},
+		{expr: "* * * * * *", wantErr: true},
+		{expr: "@reboot", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "* * 32 * *", wantErr: true},
+		{expr: "* * * 13 *", wantErr: true},
+		{expr: "* * * * 8", wantErr: true},
+		{expr: "* * * foo *", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "*/x * * * *", wantErr: true},
+		{expr: "30-10 * * * *", wantErr: true},
+		{expr: "1-x * * * *", wantErr: true},
+		{expr: "1,,2 * * * *", wantErr: true},
+	} {
+		got, err := Parse(tc.expr)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", tc.expr, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.expr, err)
+			continue
+		}
+		want, err := Parse(tc.same)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.same, err)
+		}
+		got.dow, want.dow = got.dow&^(1<<7), want.dow&^(1<<7)
+		if got != want {
+			t.Errorf("Parse(%q) = %+v, want %+v (%s)", tc.expr, got, want, tc.same)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata: %v", err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	local := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tc := range []struct {
+		name, expr string
+		from       time.Time
+		want       []time.Time
+	}{
+		{"every minute truncates seconds", "* * * * *", utc("2026-01-01 10:00").Add(30 * time.Second),
+			[]time.Time{utc("2026-01-01 10:01"), utc("2026-01-01 10:02")}},
+		{"strictly after from", "0 10 * * *", utc("2026-01-01 10:00"),
+			[]time.Time{utc("2026-01-02 10:00")}},
+		{"31st skips short months", "0 0 31 * *", utc("2026-01-31 00:00"),
+			[]time.Time{utc("2026-03-31 00:00"), utc("2026-05-31 00:00"), utc("2026-07-31 00:00"), utc("2026-08-31 00:00")}},
+		{"month end rolls the year", "30 23 31 12 *", utc("2026-06-01 00:00"),
+			[]time.Time{utc("2026-12-31 23:30"), utc("2027-12-31 23:30")}},
+		{"29 february waits for a leap year", "0 0 29 2 *", utc("2026-01-01 00:00"),
+			[]time.Time{utc("2028-02-29 00:00"), utc("2032-02-29 00:00")}},
+		{"30 february never matches", "0 0 30 2 *", utc("2026-01-01 00:00"),
+			[]time.Time{{}}},
+		{"day-of-month or day-of-week", "0 9 13 * fri", utc("2026-02-01 00:00"),
+			[]time.Time{utc("2026-02-06 09:00"), utc("2026-02-13 09:00"), utc("2026-02-20 09:00"), utc("2026-02-27 09:00"), utc("2026-03-06 09:00"), utc("2026-03-13 09:00")}},
+		{"day-of-week with any day-of-month", "0 9 * * 7", utc("2026-02-01 09:00"),
+			[]time.Time{utc("2026-02-08 09:00"), utc("2026-02-15 09:00")}},
+		{"weekly macro", "@weekly", utc("2026-02-04 12:00"),
+			[]time.Time{utc("2026-02-08 00:00"), utc("2026-02-15 00:00")}},
+		{"keeps the location", "0 20 * * *", local("2026-01-10 21:00"),
+			[]time.Time{local("2026-01-11 20:00"), local("2026-01-12 20:00")}},
+		{"spring forward runs a skipped time after the gap", "30 2 * * *", local("2026-03-28 03:00"),
+			[]time.Time{local("2026-03-29 03:30"), local("2026-03-30 02:30"), local("2026-03-31 02:30")}},
+		{"spring forward keeps local wall time", "0 20 * * *", local("2026-03-28 21:00"),
+			[]time.Time{local("2026-03-29 20:00"), local("2026-03-30 20:00")}},
+		{"hourly across spring forward", "0 * * * *", local("2026-03-29 01:30"),
+			[]time.Time{local("2026-03-29 03:00"), local("2026-03-29 04:00")}},
+		{"fall back runs a repeated time once", "30 2 * * *", local("2026-10-24 03:00"),
+			[]time.Time{local("2026-10-25 02:30"), local("2026-10-26 02:30")}},
+		{"hourly across fall back", "0 * * * *", time.Date(2026, 10, 25, 1, 30, 0, 0, berlin),
+			[]time.Time{time.Date(2026, 10, 25, 2, 0, 0, 0, berlin), time.Date(2026, 10, 25, 3, 0, 0, 0, berlin)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tc.from
+			for i, want := range tc.want {
+				got := e.Next(at)
+				if !got.Equal(want) || (!want.IsZero() && got.Location() != tc.from.Location()) {
+					t.Fatalf("Next #%d from %v = %v, want %v", i+1, at, got, want)
+				}
+				at = got
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/httpapi"
+	"streamweb/api/internal/segstore"
+	"streamweb/api/internal/service"
+)
+
+var uriAttr = regexp.MustCompile(`URI="([^"]+)"`)
+
+type Gateway struct {
+	svc   *service.Service
+	store segstore.SegmentStore
+}
+
+func New(svc *service.Service, store segstore.SegmentStore) *Gateway {
+	return &Gateway{svc: svc, store: store}
+}
+
+func (g *Gateway) Register(rt *httpapi.Router) {
+	rt.HandleFunc("GET /play/{sid}/{asset...}", g.play)
+}
+
+func (g *Gateway) play(w http.ResponseWriter, r *http.Request) {
+	sid, asset := r.PathValue("sid"), r.PathValue("asset")
+	streamID, _, _ := strings.Cut(asset, "/")
+	if streamID == "" || asset == streamID {
+		httpapi.WriteError(w, r, service.ErrAssetNotFound)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if err := g.svc.ValidatePlaybackAsset(token, sid, asset); err != nil {
+		httpapi.WriteError(w, r, err)
+		return
+	}
+
+	var playlistParams url.Values
+	if dvr := r.URL.Query().Get("dvr"); dvr != "" {
+		playlistParams = url.Values{"dvr": {dvr}}
+		parts := strings.Split(asset, "/")
+		if len(parts) == 3 && parts[2] == "index.m3u8" {
+			playlist, err := g.svc.DVRPlaylist(r.Context(), sid, parts[1], dvr, time.Now())
+			if err != nil {
+				httpapi.WriteError(w, r, err)
+				return
+			}
+			writePlaylist(w, r, SignPlaylist(playlist, token, playlistParams))
+			return
+		}
+	}
+
+	body, info, err := g.store.Get(r.Context(), asset)
+	if errors.Is(err, segstore.ErrNotFound) || errors.Is(err, segstore.ErrInvalidKey) {
+		httpapi.WriteError(w, r, service.ErrAssetNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("gateway: get %s: %v", asset, err)
+		httpapi.WriteError(w, r, service.ErrStorageUnavailable)
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", segstore.ContentType(asset))
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.Format(http.TimeFormat))
+	}
+	if path.Ext(asset) == ".m3u8" {
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			log.Printf("gateway: read %s: %v", asset, err)
+			httpapi.WriteError(w, r, service.ErrStorageUnavailable)
+			return
+		}
+		writePlaylist(w, r, SignPlaylist(string(raw), token, playlistParams))
+		return
+	}
+	w.Header().Set("Cache-Control", "public,max-age=30")
+	if info.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		if _, err := io.Copy(w, body); err == nil {
+			g.svc.RecordSegmentFetch(sid, time.Now())
+		}
+	}
+}
+
+func writePlaylist(w http.ResponseWriter, r *http.Request, playlist string) {
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(playlist)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = io.WriteString(w, playlist)
+	}
+}
+
+func SignPlaylist(playlist, token string, playlistParams url.Values) string {
+	if token == "" && len(playlistParams) == 0 {
+		return playlist
+	}
+	lines := strings.Split(playlist, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			lines[i] = uriAttr.ReplaceAllStringFunc(line, func(m string) string {
+				return `URI="` + signURI(uriAttr.FindStringSubmatch(m)[1], token, playlistParams) + `"`
+			})
+		default:
+			lines[i] = signURI(trimmed, token, playlistParams)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func signURI(uri, token string, playlistParams url.Values) string {
+	q := url.Values{}
+	if token != "" {
+		q.Set("token", token)
+	}
+	base, _, _ := strings.Cut(uri, "?")
+	if path.Ext(base) == ".m3u8" {
+		for k, v := range playlistParams {
+			q[k] = v
+		}
+	}
+	if len(q) == 0 {
+		return uri
+	}
+	sep := "?"
+	if strings.Contains(uri, "?") {
+		sep = "&"
+	}
+	return uri + sep + q.Encode()
+}
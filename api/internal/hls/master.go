@@ -0,0 +1,107 @@
+package hls
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Variant struct {
+	URI       string
+	Bandwidth int
+	Width     int
+	Height    int
+	Codecs    []string
+}
+
+type Playlist struct {
+	Master         bool
+	Variants       []Variant
+	Segments       int
+	TargetDuration float64
+	Type           string
+	Ended          bool
+}
+
+func Parse(r io.Reader) (Playlist, bool) {
+	var (
+		pl      Playlist
+		pending *Variant
+		header  bool
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+		case line == "#EXTM3U":
+			header = true
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pl.Master = true
+			v := parseVariant(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			pending = &v
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			pl.TargetDuration, _ = strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64)
+		case strings.HasPrefix(line, "#EXT-X-PLAYLIST-TYPE:"):
+			pl.Type = strings.TrimPrefix(line, "#EXT-X-PLAYLIST-TYPE:")
+		case line == "#EXT-X-ENDLIST":
+			pl.Ended = true
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			pending.URI = line
+			pl.Variants = append(pl.Variants, *pending)
+			pending = nil
+		default:
+			pl.Segments++
+		}
+	}
+	return pl, header && sc.Err() == nil
+}
+
+func parseVariant(attrs string) Variant {
+	var v Variant
+	for key, val := range splitAttributes(attrs) {
+		switch key {
+		case "BANDWIDTH":
+			v.Bandwidth, _ = strconv.Atoi(val)
+		case "RESOLUTION":
+			w, h, _ := strings.Cut(val, "x")
+			v.Width, _ = strconv.Atoi(w)
+			v.Height, _ = strconv.Atoi(h)
+		case "CODECS":
+			for _, c := range strings.Split(val, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					v.Codecs = append(v.Codecs, c)
+				}
+			}
+		}
+	}
+	return v
+}
+
+func splitAttributes(s string) map[string]string {
+	out := map[string]string{}
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		var val string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				val, rest = rest[1:], ""
+			} else {
+				val, rest = rest[1:end+1], rest[end+2:]
+			}
+			rest = strings.TrimPrefix(rest, ",")
+		} else {
+			val, rest, _ = strings.Cut(rest, ",")
+		}
+		out[strings.TrimSpace(key)] = val
+		s = rest
+	}
+	return out
+}
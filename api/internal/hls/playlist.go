@@ -0,0 +1,97 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Segment struct {
+	URI      string
+	Seq      int64
+	Duration float64
+	EndedAt  time.Time
+}
+
+type MediaPlaylist struct {
+	Type        string
+	StartOffset *float64
+	Segments    []Segment
+	Ended       bool
+}
+
+func SegmentSeq(name string) (int64, bool) {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	digits, ok := strings.CutPrefix(base, "seg_")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	return n, err == nil
+}
+
+func ParseDurations(r io.Reader) map[string]float64 {
+	out := map[string]float64{}
+	sc := bufio.NewScanner(r)
+	var pending float64
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if v, ok := strings.CutPrefix(line, "#EXTINF:"); ok {
+			v, _, _ = strings.Cut(v, ",")
+			pending, _ = strconv.ParseFloat(v, 64)
+			continue
+		}
+		if line != "" && !strings.HasPrefix(line, "#") {
+			out[path.Base(line)] = pending
+		}
+	}
+	return out
+}
+
+func (p MediaPlaylist) Duration() float64 {
+	total := 0.0
+	for _, seg := range p.Segments {
+		total += seg.Duration
+	}
+	return total
+}
+
+func (p MediaPlaylist) Render() string {
+	target := 1.0
+	for _, seg := range p.Segments {
+		target = math.Max(target, seg.Duration)
+	}
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target)))
+	if len(p.Segments) > 0 {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.Segments[0].Seq)
+	}
+	if p.Type != "" {
+		fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", p.Type)
+	}
+	if p.StartOffset != nil {
+		fmt.Fprintf(&b, "#EXT-X-START:TIME-OFFSET=%s,PRECISE=YES\n", strconv.FormatFloat(*p.StartOffset, 'f', -1, 64))
+	}
+	for i, seg := range p.Segments {
+		if i == 0 || seg.Seq != p.Segments[i-1].Seq+1 {
+			if i > 0 {
+				b.WriteString("#EXT-X-DISCONTINUITY\n")
+			}
+			if !seg.EndedAt.IsZero() {
+				started := seg.EndedAt.Add(-time.Duration(seg.Duration * float64(time.Second)))
+				fmt.Fprintf(&b, "#EXT-X-PROGRAM-DATE-TIME:%s\n", started.UTC().Format("2006-01-02T15:04:05.000Z"))
+			}
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", seg.Duration, seg.URI)
+	}
+	if p.Ended {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package hls
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSegmentSeq(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want int64
+		ok   bool
+	}{
+		{"seg_00042.ts", 42, true},
+		{"720p/seg_1700000000.m4s", 1700000000, true},
+		{"seg_7", 7, true},
+		{"init.mp4", 0, false},
+		{"seg_x.ts", 0, false},
+		{"segment_1.ts", 0, false},
+	} {
+		got, ok := SegmentSeq(tc.name)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("SegmentSeq(%q) = %d, %v; want %d, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseDurations(t *testing.T) {
+	in := "#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXTINF:3.840,\nseg_1.ts\n\n#EXTINF:4,title\n720p/seg_2.ts\n#EXTINF:bad,\nseg_3.ts\n"
+	want := map[string]float64{"seg_1.ts": 3.84, "seg_2.ts": 4, "seg_3.ts": 0}
+	if got := ParseDurations(strings.NewReader(in)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDurations = %v, want %v", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 0, 10, 0, time.UTC)
+	offset := -30.5
+	for _, tc := range []struct {
+		name string
+		p    MediaPlaylist
+		want string
+	}{
+		{
+			name: "empty",
+			p:    MediaPlaylist{},
+			want: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:1\n",
+		},
+		{
+			name: "sliding window",
+			p:    MediaPlaylist{Segments: []Segment{{URI: "seg_5.ts", Seq: 5, Duration: 4}, {URI: "seg_6.ts", Seq: 6, Duration: 4.2}}},
+			want: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:5\n" +
+				"#EXTINF:4.000,\nseg_5.ts\n#EXTINF:4.200,\nseg_6.ts\n",
+		},
+		{
+			name: "event with start offset",
+			p:    MediaPlaylist{Type: "EVENT", StartOffset: &offset, Segments: []Segment{{URI: "seg_1.ts", Seq: 1, Duration: 2}}},
+			want: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:1\n#EXT-X-PLAYLIST-TYPE:EVENT\n" +
+				"#EXT-X-START:TIME-OFFSET=-30.5,PRECISE=YES\n#EXTINF:2.000,\nseg_1.ts\n",
+		},
+		{
+			name: "gap with program date time and endlist",
+			p: MediaPlaylist{Type: "VOD", Ended: true, Segments: []Segment{
+				{URI: "seg_1.ts", Seq: 1, Duration: 4, EndedAt: at},
+				{URI: "seg_2.ts", Seq: 2, Duration: 4, EndedAt: at.Add(4 * time.Second)},
+				{URI: "seg_9.ts", Seq: 9, Duration: 2.5, EndedAt: at.Add(time.Minute)},
+			}},
+			want: "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:1\n#EXT-X-PLAYLIST-TYPE:VOD\n" +
+				"#EXT-X-PROGRAM-DATE-TIME:2024-03-01T12:00:06.000Z\n#EXTINF:4.000,\nseg_1.ts\n#EXTINF:4.000,\nseg_2.ts\n" +
+				"#EXT-X-DISCONTINUITY\n#EXT-X-PROGRAM-DATE-TIME:2024-03-01T12:01:07.500Z\n#EXTINF:2.500,\nseg_9.ts\n#EXT-X-ENDLIST\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.p.Render(); got != tc.want {
+				t.Errorf("Render =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
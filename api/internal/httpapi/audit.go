@@ -0,0 +1,30 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"streamweb/api/internal/model"
+)
+
+func (s *Server) auditLog(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	writeJSON(w, 200, map[string]any{"entries": s.svc.Audit(r.URL.Query().Get("stream_id"), limit)})
+}
+
+func (s *Server) recordFailover(w http.ResponseWriter, r *http.Request) {
+	var body model.FailoverEvent
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	rt, err := s.svc.RecordFailover(r.PathValue("id"), body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rt)
+}
@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"streamweb/api/internal/service"
+)
+
+func (s *Server) listStreams(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	cq := service.CatalogQuery{
+		Status:   q.Get("status"),
+		Category: q.Get("category"),
+		Search:   q.Get("q"),
+		Sort:     q.Get("sort"),
+		Admin:    s.isAdmin(r),
+	}
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if cq.Limit, err = strconv.Atoi(v); err != nil {
+			WriteError(w, r, badRequest("invalid limit"))
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if cq.Offset, err = strconv.Atoi(v); err != nil {
+			WriteError(w, r, badRequest("invalid offset"))
+			return
+		}
+	}
+	cq.IncludeDeleted, _ = strconv.ParseBool(q.Get("include_deleted"))
+	page, err := s.svc.Catalog(cq, time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, page)
+}
+
+func (s *Server) getStream(w http.ResponseWriter, r *http.Request) {
+	item, err := s.svc.CatalogStream(r.PathValue("id"), s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(item.Version))
+	writeJSON(w, 200, item)
+}
+
+func (s *Server) deleteStream(w http.ResponseWriter, r *http.Request) {
+	st, err := s.svc.DeleteStream(r.PathValue("id"), userID(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, st)
+}
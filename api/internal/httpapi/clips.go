@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"streamweb/api/internal/service"
+)
+
+func (s *Server) streamClips(w http.ResponseWriter, r *http.Request) {
+	streamID := r.PathValue("id")
+	clips, err := s.svc.ListClips(streamID, s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"stream_id": streamID, "clips": clips})
+}
+
+func (s *Server) createClip(w http.ResponseWriter, r *http.Request) {
+	var body service.ClipRequest
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	body.CreatedBy = userID(r)
+	clip, err := s.svc.CreateClip(r.PathValue("id"), body, time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 202, clip)
+}
+
+func (s *Server) playClip(w http.ResponseWriter, r *http.Request) {
+	var body service.PlaybackRequest
+	_ = parseBody(r, &body)
+	body.StreamID, body.RecordingID, body.ClipID = "", "", r.PathValue("id")
+	body.IP, body.UserAgent = s.clientIP(r), r.UserAgent()
+	resp, err := s.svc.StartPlayback(body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) getClip(w http.ResponseWriter, r *http.Request) {
+	clip, err := s.svc.GetClip(r.PathValue("id"), s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, clip)
+}
+
+func (s *Server) deleteClip(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.svc.DeleteClip(r.Context(), id); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, map[string]string{"clip_id": id, "status": "deleted"})
+}
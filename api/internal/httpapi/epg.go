@@ -0,0 +1,46 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"streamweb/api/internal/service"
+)
+
+func (s *Server) importEPG(w http.ResponseWriter, r *http.Request) {
+	opts := service.EPGImportOptions{Actor: "user:" + userID(r)}
+	opts.DryRun, _ = strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	rep, err := s.svc.ImportXMLTV(http.MaxBytesReader(w, r.Body, maxImportBytes), opts)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rep)
+}
+
+func (s *Server) exportEPG(w http.ResponseWriter, r *http.Request) {
+	var uid string
+	if key := r.URL.Query().Get("key"); key != "" {
+		id, err := s.svc.PlaylistKeyUser(key)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		uid = id
+	} else {
+		id, ok := s.requireUser(w, r)
+		if !ok {
+			return
+		}
+		uid = id
+	}
+	body, err := s.svc.UserEPG(uid, time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(body)
+}
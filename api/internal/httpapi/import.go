@@ -0,0 +1,24 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"streamweb/api/internal/service"
+)
+
+const maxImportBytes = 8 << 20
+
+func (s *Server) importStreams(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := service.ImportOptions{ABRPreset: q.Get("abr_preset"), Actor: "user:" + userID(r)}
+	opts.DryRun, _ = strconv.ParseBool(q.Get("dry_run"))
+	opts.PointsRate, _ = strconv.Atoi(q.Get("points_rate"))
+	opts.MaxConcurrentSessions, _ = strconv.Atoi(q.Get("max_concurrent_sessions"))
+	rep, err := s.svc.ImportM3U(r.Context(), http.MaxBytesReader(w, r.Body, maxImportBytes), opts)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rep)
+}
@@ -0,0 +1,38 @@
+package httpapi
+
+import "net/http"
+
+func (s *Server) streamKey(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.StreamKey(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) rotateStreamKey(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.RotateStreamKey(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) authorizeIngest(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		StreamKey  string `json:"stream_key"`
+		RemoteAddr string `json:"remote_addr"`
+	}
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	resp, err := s.svc.AuthorizeIngest(body.StreamKey, body.RemoteAddr)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
@@ -0,0 +1,34 @@
+package httpapi
+
+import "net/http"
+
+func (s *Server) userPlaylist(w http.ResponseWriter, r *http.Request) {
+	body, err := s.svc.UserPlaylist(userID(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "audio/x-mpegurl")
+	w.Header().Set("Content-Disposition", `inline; filename="playlist.m3u"`)
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(body)
+}
+
+func (s *Server) rotatePlaylistKey(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.RotatePlaylistKey(userID(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) launch(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.Launch(r.PathValue("key"), r.PathValue("stream"), s.clientIP(r), r.UserAgent())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, resp["play_url"], http.StatusFound)
+}
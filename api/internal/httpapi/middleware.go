@@ -0,0 +1,236 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/service"
+)
+
+const maxRequestIDLength = 128
+
+type ctxKey int
+
+const (
+	ctxRequestID ctxKey = iota
+	ctxUserID
+)
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(ctxRequestID).(string)
+	return id
+}
+
+func userID(r *http.Request) string {
+	uid, _ := r.Context().Value(ctxUserID).(string)
+	return uid
+}
+
+func RequestID(gen ids.Generator) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get("X-Request-Id")
+			if id == "" || len(id) > maxRequestIDLength {
+				id = gen.New()
+			}
+			w.Header().Set("X-Request-Id", id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxRequestID, id)))
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		log.Printf("http: %s %s %d %dB %s req=%s", r.Method, r.URL.Path, sw.status, sw.bytes, time.Since(start).Round(time.Microsecond), requestID(r))
+	})
+}
+
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			log.Printf("http: panic serving %s %s req=%s: %v\n%s", r.Method, r.URL.Path, requestID(r), v, debug.Stack())
+			WriteError(w, r, service.ErrInternal)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func CORS(origins []string) Middleware {
+	anyOrigin := slices.Contains(origins, "*")
+	return func(next http.Handler) http.Handler {
+		if len(origins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || (!anyOrigin && !slices.Contains(origins, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Expose-Headers", "ETag, X-Request-Id, Allow")
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PATCH, DELETE")
+				h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, X-Request-Id")
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *Server) requireUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	uid, _, err := s.svc.ParseUserToken(token)
+	if err != nil {
+		WriteError(w, r, errUnauthorized)
+		return "", false
+	}
+	return uid, true
+}
+
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uid, role, err := s.svc.ParseUserToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		WriteError(w, r, errUnauthorized)
+		return "", false
+	}
+	if role != "admin" {
+		WriteError(w, r, errForbidden)
+		return "", false
+	}
+	return uid, true
+}
+
+func (s *Server) isAdmin(r *http.Request) bool {
+	_, role, err := s.svc.ParseUserToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	return err == nil && role == "admin"
+}
+
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !s.trustedProxy(peer.Unmap()) {
+		return host
+	}
+	if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return ip.Unmap().String()
+	}
+	return host
+}
+
+func (s *Server) trustedProxy(addr netip.Addr) bool {
+	if p := s.proxies.Load(); p != nil {
+		for _, prefix := range *p {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func withUser(next http.Handler, require func(http.ResponseWriter, *http.Request) (string, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid, ok := require(w, r)
+		if !ok {
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxUserID, uid)))
+	})
+}
+
+func (s *Server) userOnly(next http.Handler) http.Handler  { return withUser(next, s.requireUser) }
+func (s *Server) adminOnly(next http.Handler) http.Handler { return withUser(next, s.requireAdmin) }
+
+func (s *Server) workerOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		want := s.workers.Load()
+		if !ok || want == nil || *want == "" || subtle.ConstantTimeCompare([]byte(token), []byte(*want)) != 1 {
+			WriteError(w, r, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type RateLimits struct {
+	Login         int
+	PlaybackStart int
+	Launch        int
+	Window        time.Duration
+}
+
+func DefaultRateLimits() RateLimits {
+	return RateLimits{Login: 20, PlaybackStart: 30, Launch: 60, Window: time.Minute}
+}
+
+func (s *Server) rateLimit(bucket string, limit func(RateLimits) int) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := s.limits.Load()
+			if !s.allowRate(r, bucket, limit(*l), l.Window) {
+				WriteError(w, r, errRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
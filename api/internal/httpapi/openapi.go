@@ -0,0 +1,177 @@
+package httpapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/m3u"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/service"
+)
+
+//go:generate go run ../../cmd/apiclientgen -spec openapi.json -package apiclient -out ../../../player/internal/apiclient/client.go
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (s *Server) openAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPISpec)
+}
+
+type streamCreate struct {
+	ID string `json:"id"`
+	service.StreamPatch
+}
+
+var contractTypes = map[string]any{
+	"User":              model.User{},
+	"ABRProfile":        model.ABRProfile{},
+	"Stream":            model.Stream{},
+	"StreamPatch":       service.StreamPatch{},
+	"StreamCreate":      streamCreate{},
+	"Program":           service.Program{},
+	"StreamListing":     service.StreamListing{},
+	"CatalogPage":       service.CatalogPage{},
+	"StreamStateChange": model.StreamStateChange{},
+	"StreamRuntime":     model.StreamRuntime{},
+	"ProbeReport":       service.ProbeReport{},
+	"StreamProbe":       service.StreamProbe{},
+	"Recording":         model.Recording{},
+	"RecordingReport":   service.RecordingReport{},
+	"Clip":              model.Clip{},
+	"ClipRequest":       service.ClipRequest{},
+	"Schedule":          model.Schedule{},
+	"ScheduleRequest":   service.ScheduleRequest{},
+	"PlaybackRequest":   service.PlaybackRequest{},
+	"AuditEntry":        model.AuditEntry{},
+	"StreamChange":      service.StreamChange{},
+	"ImportProblem":     m3u.Problem{},
+	"ImportReport":      service.ImportReport{},
+	"EPGChannelResult":  service.EPGChannelResult{},
+	"EPGSkip":           service.EPGSkip{},
+	"EPGImportReport":   service.EPGImportReport{},
+	"FieldError":        service.FieldError{},
+	"FailoverEvent":     model.FailoverEvent{},
+}
+
+type openAPISchema struct {
+	Type       string                    `json:"type"`
+	Ref        string                    `json:"$ref"`
+	Properties map[string]*openAPISchema `json:"properties"`
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+func CheckContract(rt *Router) []string {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		return []string{"openapi.json: " + err.Error()}
+	}
+	var problems []string
+	documented, served := map[string]bool{}, map[string]bool{}
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for _, pattern := range rt.Patterns() {
+		method, path, ok := strings.Cut(strings.ReplaceAll(pattern, "...}", "}"), " ")
+		if !ok {
+			path, method = method, ""
+		}
+		found := false
+		for op := range documented {
+			if op == method+" "+path || (method == "" && strings.HasSuffix(op, " "+path)) {
+				served[op], found = true, true
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("route %q is not documented", pattern))
+		}
+	}
+	for op := range documented {
+		if !served[op] {
+			problems = append(problems, fmt.Sprintf("operation %q is documented but not routed", op))
+		}
+	}
+	for name, v := range contractTypes {
+		problems = append(problems, checkSchema(doc.Components.Schemas[name], name, reflect.TypeOf(v))...)
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func checkSchema(schema *openAPISchema, name string, t reflect.Type) []string {
+	if schema == nil {
+		return []string{fmt.Sprintf("schema %s is missing", name)}
+	}
+	var problems []string
+	fields := jsonFields(t, map[string]reflect.Type{})
+	for field, ft := range fields {
+		prop, ok := schema.Properties[field]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("schema %s lacks property %q of %s", name, field, t))
+			continue
+		}
+		if want, got := schemaType(ft), prop.Type; prop.Ref == "" && want != got {
+			problems = append(problems, fmt.Sprintf("schema %s property %q is %q, Go field is %q", name, field, got, want))
+		}
+	}
+	for prop := range schema.Properties {
+		if _, ok := fields[prop]; !ok {
+			problems = append(problems, fmt.Sprintf("schema %s property %q has no field in %s", name, prop, t))
+		}
+	}
+	return problems
+}
+
+func jsonFields(t reflect.Type, out map[string]reflect.Type) map[string]reflect.Type {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !f.IsExported():
+		case f.Anonymous && name == "":
+			jsonFields(f.Type, out)
+		case name == "":
+			out[f.Name] = f.Type
+		default:
+			out[name] = f.Type
+		}
+	}
+	return out
+}
+
+func schemaType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
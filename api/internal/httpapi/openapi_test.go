@@ -0,0 +1,31 @@
+package httpapi_test
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"streamweb/api/internal/gateway"
+	"streamweb/api/internal/httpapi"
+)
+
+func contractRouter() *httpapi.Router {
+	router := httpapi.NewRouter()
+	httpapi.NewServer(nil).Register(router)
+	gateway.New(nil, nil).Register(router)
+	return router
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	for _, p := range httpapi.CheckContract(contractRouter()) {
+		t.Error(p)
+	}
+}
+
+func TestOpenAPIContractReportsDrift(t *testing.T) {
+	router := contractRouter()
+	router.HandleFunc("GET /undocumented", func(http.ResponseWriter, *http.Request) {})
+	if problems := httpapi.CheckContract(router); !slices.Contains(problems, `route "GET /undocumented" is not documented`) {
+		t.Errorf("CheckContract = %q, want the undocumented route reported", problems)
+	}
+}
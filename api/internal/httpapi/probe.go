@@ -0,0 +1,12 @@
+package httpapi
+
+import "net/http"
+
+func (s *Server) probeStream(w http.ResponseWriter, r *http.Request) {
+	probe, err := s.svc.ProbeStream(r.Context(), r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, probe)
+}
@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"streamweb/api/internal/service"
+)
+
+var (
+	errInvalidBody  = badRequest("invalid body")
+	errUnauthorized = &service.Error{Code: "unauthorized", Message: "unauthorized"}
+	errForbidden    = &service.Error{Code: "forbidden", Message: "admin only"}
+	errNotFound     = &service.Error{Code: "not_found", Message: "not found"}
+	errMethod       = &service.Error{Code: "method_not_allowed", Message: "method not allowed"}
+	errRateLimited  = &service.Error{Code: "rate_limited", Message: "rate limit exceeded"}
+)
+
+var problemStatus = map[string]int{
+	service.ErrInvalidRequest.Code:      400,
+	service.ErrValidation.Code:          400,
+	service.ErrInvalidCredentials.Code:  401,
+	service.ErrInvalidToken.Code:        401,
+	service.ErrPlayTokenExpired.Code:    401,
+	errUnauthorized.Code:                401,
+	service.ErrInsufficientPoints.Code:  402,
+	errForbidden.Code:                   403,
+	service.ErrUserInactive.Code:        403,
+	service.ErrSessionNotActive.Code:    403,
+	service.ErrInvalidStreamKey.Code:    403,
+	service.ErrInvalidPlaylistKey.Code:  403,
+	service.ErrAssetMismatch.Code:       403,
+	errNotFound.Code:                    404,
+	service.ErrStreamNotFound.Code:      404,
+	service.ErrUserNotFound.Code:        404,
+	service.ErrSessionNotFound.Code:     404,
+	service.ErrRecordingNotFound.Code:   404,
+	service.ErrClipNotFound.Code:        404,
+	service.ErrScheduleNotFound.Code:    404,
+	service.ErrNoSegments.Code:          404,
+	service.ErrAssetNotFound.Code:       404,
+	errMethod.Code:                      405,
+	service.ErrStreamExists.Code:        409,
+	service.ErrConcurrentUpdate.Code:    409,
+	service.ErrInvalidTransition.Code:   409,
+	service.ErrStreamNotLive.Code:       409,
+	service.ErrNotPushIngest.Code:       409,
+	service.ErrDVRDisabled.Code:         409,
+	service.ErrRecordingDisabled.Code:   409,
+	service.ErrStorageUnconfigured.Code: 409,
+	service.ErrNotReady.Code:            409,
+	service.ErrSegmentBilled.Code:       409,
+	service.ErrVersionMismatch.Code:     412,
+	service.ErrIngestNotReady.Code:      422,
+	service.ErrProbeFailed.Code:         422,
+	service.ErrTooManySessions.Code:     429,
+	errRateLimited.Code:                 429,
+	service.ErrInternal.Code:            500,
+	service.ErrStorageUnavailable.Code:  502,
+}
+
+func badRequest(msg string) error {
+	return &service.Error{Code: service.ErrInvalidRequest.Code, Message: msg}
+}
+
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var e *service.Error
+	if !errors.As(err, &e) {
+		log.Printf("http: %s %s req=%s: %v", r.Method, r.URL.Path, requestID(r), err)
+		e, err = service.ErrInternal, service.ErrInternal
+	}
+	status, ok := problemStatus[e.Code]
+	if !ok {
+		status = 500
+	}
+	body := map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"status": status,
+		"code":   e.Code,
+		"detail": err.Error(),
+	}
+	if id := requestID(r); id != "" {
+		body["request_id"] = id
+	}
+	var validationErr *service.ValidationError
+	var versionErr *service.VersionMismatchError
+	var transitionErr *service.TransitionError
+	var probeErr *service.ProbeFailedError
+	var blockedErr *service.BlockedError
+	switch {
+	case errors.As(err, &validationErr):
+		body["fields"] = validationErr.Fields
+	case errors.As(err, &versionErr):
+		w.Header().Set("ETag", etag(versionErr.Current))
+		body["version"] = versionErr.Current
+	case errors.As(err, &transitionErr):
+		body["from"], body["to"], body["allowed"] = transitionErr.From, transitionErr.To, transitionErr.Allowed
+	case errors.As(err, &probeErr):
+		body["probe"] = probeErr.Probe
+	case errors.As(err, &blockedErr):
+		body["balance_points"] = blockedErr.Balance
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
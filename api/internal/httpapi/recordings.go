@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"streamweb/api/internal/service"
+)
+
+func (s *Server) streamRecordings(w http.ResponseWriter, r *http.Request) {
+	streamID := r.PathValue("id")
+	recs, err := s.svc.ListRecordings(streamID, s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"stream_id": streamID, "recordings": recs})
+}
+
+func (s *Server) playRecording(w http.ResponseWriter, r *http.Request) {
+	var body service.PlaybackRequest
+	_ = parseBody(r, &body)
+	body.StreamID, body.RecordingID, body.ClipID = "", r.PathValue("id"), ""
+	body.IP, body.UserAgent = s.clientIP(r), r.UserAgent()
+	resp, err := s.svc.StartPlayback(body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) getRecording(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.svc.GetRecording(r.PathValue("id"), s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rec)
+}
+
+func (s *Server) deleteRecording(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.svc.DeleteRecording(r.Context(), id); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, map[string]string{"recording_id": id, "status": "deleted"})
+}
+
+func (s *Server) openRecording(w http.ResponseWriter, r *http.Request) {
+	rec, err := s.svc.OpenRecording(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rec)
+}
+
+func (s *Server) finalizeRecording(w http.ResponseWriter, r *http.Request) {
+	var body service.RecordingReport
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	rec, err := s.svc.FinalizeRecording(r.PathValue("id"), body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rec)
+}
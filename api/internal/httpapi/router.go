@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type Middleware func(http.Handler) http.Handler
+
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+type Router struct {
+	routes *routes
+	mws    []Middleware
+}
+
+type routes struct {
+	mux      *http.ServeMux
+	base     http.Handler
+	mu       sync.Mutex
+	methods  []string
+	patterns []string
+}
+
+func NewRouter(mws ...Middleware) *Router {
+	rs := &routes{mux: http.NewServeMux()}
+	rs.base = Chain(http.HandlerFunc(rs.serve), mws...)
+	return &Router{routes: rs}
+}
+
+func (rt *Router) With(mws ...Middleware) *Router {
+	return &Router{routes: rt.routes, mws: append(slices.Clip(rt.mws), mws...)}
+}
+
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	rt.routes.mu.Lock()
+	rt.routes.patterns = append(rt.routes.patterns, pattern)
+	if method, _, ok := strings.Cut(pattern, " "); ok && !slices.Contains(rt.routes.methods, method) {
+		rt.routes.methods = append(rt.routes.methods, method)
+	}
+	rt.routes.mu.Unlock()
+	rt.routes.mux.Handle(pattern, Chain(h, rt.mws...))
+}
+
+func (rt *Router) Patterns() []string {
+	rt.routes.mu.Lock()
+	defer rt.routes.mu.Unlock()
+	return slices.Clone(rt.routes.patterns)
+}
+
+func (rt *Router) HandleFunc(pattern string, h http.HandlerFunc) { rt.Handle(pattern, h) }
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) { rt.routes.base.ServeHTTP(w, r) }
+
+func (rs *routes) serve(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rs.mux.Handler(r); pattern != "" {
+		rs.mux.ServeHTTP(w, r)
+		return
+	}
+	if allow := rs.allowed(r); len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		WriteError(w, r, errMethod)
+		return
+	}
+	WriteError(w, r, errNotFound)
+}
+
+func (rs *routes) allowed(r *http.Request) []string {
+	rs.mu.Lock()
+	methods := slices.Clone(rs.methods)
+	rs.mu.Unlock()
+	var allow []string
+	probe := new(http.Request)
+	for _, m := range methods {
+		*probe = *r
+		probe.Method = m
+		if _, pattern := rs.mux.Handler(probe); pattern != "" {
+			allow = append(allow, m)
+			if m == http.MethodGet {
+				allow = append(allow, http.MethodHead)
+			}
+		}
+	}
+	slices.Sort(allow)
+	return slices.Compact(allow)
+}
@@ -0,0 +1,49 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"streamweb/api/internal/service"
+)
+
+func (s *Server) streamSchedules(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.ListSchedules(r.PathValue("id"), s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) createSchedule(w http.ResponseWriter, r *http.Request) {
+	var body service.ScheduleRequest
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	body.CreatedBy = userID(r)
+	sc, err := s.svc.CreateSchedule(r.PathValue("id"), body, time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 201, sc)
+}
+
+func (s *Server) getSchedule(w http.ResponseWriter, r *http.Request) {
+	sc, err := s.svc.GetSchedule(r.PathValue("id"), s.isAdmin(r), time.Now())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, sc)
+}
+
+func (s *Server) deleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.DeleteSchedule(r.PathValue("id"), userID(r)); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, map[string]string{"status": "deleted"})
+}
@@ -3,8 +3,10 @@ package httpapi
 import (
 	"encoding/json"
 	"net/http"
+	"net/netip"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"streamweb/api/internal/model"
@@ -12,15 +14,26 @@ import (
 )
 
 type Server struct {
-	svc    *service.Service
-	rateMu sync.Mutex
-	rate   map[string][]time.Time
+	svc     *service.Service
+	rateMu  sync.Mutex
+	rate    map[string][]time.Time
+	limits  atomic.Pointer[RateLimits]
+	proxies atomic.Pointer[[]netip.Prefix]
+	workers atomic.Pointer[string]
 }
 
 func NewServer(svc *service.Service) *Server {
-	return &Server{svc: svc, rate: map[string][]time.Time{}}
+	s := &Server{svc: svc, rate: map[string][]time.Time{}}
+	s.SetRateLimits(DefaultRateLimits())
+	return s
 }
 
+func (s *Server) SetRateLimits(l RateLimits) { s.limits.Store(&l) }
+
+func (s *Server) SetTrustedProxies(p []netip.Prefix) { s.proxies.Store(&p) }
+
+func (s *Server) SetWorkerToken(token string) { s.workers.Store(&token) }
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -29,24 +42,71 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 
 func parseBody(r *http.Request, dst any) error { return json.NewDecoder(r.Body).Decode(dst) }
 
-func (s *Server) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/healthz", s.health)
-	mux.HandleFunc("/auth/login", s.login)
-	mux.HandleFunc("/auth/refresh", s.refresh)
-	mux.HandleFunc("/streams", s.createStream)
-	mux.HandleFunc("/streams/", s.streamRoutes)
-	mux.HandleFunc("/playback/start", s.playbackStart)
-	mux.HandleFunc("/playback/renew", s.playbackRenew)
-	mux.HandleFunc("/playback/heartbeat", s.playbackHeartbeat)
-	mux.HandleFunc("/playback/stop", s.playbackStop)
-	mux.HandleFunc("/playback/kick", s.playbackKick)
-	mux.HandleFunc("/monitoring/health", s.monitorHealth)
-	mux.HandleFunc("/monitoring/metrics", s.monitorMetrics)
-	mux.HandleFunc("/internal/validate-playback", s.validatePlayback)
+func (s *Server) Register(rt *Router) {
+	user, admin, worker := rt.With(s.userOnly), rt.With(s.adminOnly), rt.With(s.workerOnly)
+
+	rt.HandleFunc("GET /healthz", s.health)
+	rt.HandleFunc("GET /openapi.json", s.openAPI)
+	rt.With(s.rateLimit("login", func(l RateLimits) int { return l.Login })).HandleFunc("POST /auth/login", s.login)
+	rt.HandleFunc("POST /auth/refresh", s.refresh)
+
+	rt.HandleFunc("GET /streams", s.listStreams)
+	admin.HandleFunc("POST /streams", s.createStream)
+	admin.HandleFunc("POST /streams/import", s.importStreams)
+	rt.HandleFunc("GET /streams/{id}", s.getStream)
+	admin.HandleFunc("PATCH /streams/{id}", s.patchStream)
+	admin.HandleFunc("DELETE /streams/{id}", s.deleteStream)
+	admin.HandleFunc("GET /streams/{id}/state", s.streamState)
+	admin.HandleFunc("POST /streams/{id}/state", s.transitionStream)
+	admin.HandleFunc("GET /streams/{id}/runtime", s.streamRuntime)
+	admin.HandleFunc("GET /streams/{id}/stream-key", s.streamKey)
+	admin.HandleFunc("POST /streams/{id}/stream-key", s.rotateStreamKey)
+	admin.HandleFunc("GET /streams/{id}/probe", s.probeStream)
+	admin.HandleFunc("POST /streams/{id}/probe", s.probeStream)
+	user.HandleFunc("GET /streams/{id}/recordings", s.streamRecordings)
+	user.HandleFunc("GET /streams/{id}/clips", s.streamClips)
+	admin.HandleFunc("POST /streams/{id}/clips", s.createClip)
+	user.HandleFunc("GET /streams/{id}/schedules", s.streamSchedules)
+	admin.HandleFunc("POST /streams/{id}/schedules", s.createSchedule)
+	rt.HandleFunc("GET /abr/presets", s.abrPresets)
+
+	rt.With(s.rateLimit("playback_start", func(l RateLimits) int { return l.PlaybackStart })).HandleFunc("POST /playback/start", s.playbackStart)
+	rt.HandleFunc("POST /playback/renew", s.playbackRenew)
+	rt.HandleFunc("POST /playback/heartbeat", s.playbackHeartbeat)
+	rt.HandleFunc("POST /playback/stop", s.playbackStop)
+	rt.HandleFunc("POST /playback/kick", s.playbackKick)
+
+	user.HandleFunc("GET /recordings/{id}", s.getRecording)
+	admin.HandleFunc("DELETE /recordings/{id}", s.deleteRecording)
+	rt.HandleFunc("POST /recordings/{id}/play", s.playRecording)
+	user.HandleFunc("GET /clips/{id}", s.getClip)
+	admin.HandleFunc("DELETE /clips/{id}", s.deleteClip)
+	rt.HandleFunc("POST /clips/{id}/play", s.playClip)
+	user.HandleFunc("GET /schedules/{id}", s.getSchedule)
+	admin.HandleFunc("DELETE /schedules/{id}", s.deleteSchedule)
+	admin.HandleFunc("GET /audit", s.auditLog)
+
+	user.HandleFunc("GET /me/playlist.m3u", s.userPlaylist)
+	user.HandleFunc("POST /me/playlist/rotate", s.rotatePlaylistKey)
+	rt.With(s.rateLimit("launch", func(l RateLimits) int { return l.Launch })).HandleFunc("GET /launch/{key}/{stream}", s.launch)
+	admin.HandleFunc("POST /epg/import", s.importEPG)
+	rt.HandleFunc("GET /epg.xml", s.exportEPG)
+
+	rt.HandleFunc("GET /monitoring/health", s.monitorHealth)
+	rt.HandleFunc("GET /monitoring/metrics", s.monitorMetrics)
+
+	rt.HandleFunc("/internal/validate-playback", s.validatePlayback)
+	worker.HandleFunc("POST /internal/workers/heartbeat", s.workerHeartbeat)
+	worker.HandleFunc("GET /internal/streams/{id}", s.internalStream)
+	worker.HandleFunc("POST /internal/streams/{id}/runtime", s.reportRuntime)
+	worker.HandleFunc("POST /internal/streams/{id}/recordings", s.openRecording)
+	worker.HandleFunc("POST /internal/streams/{id}/failover", s.recordFailover)
+	worker.HandleFunc("POST /internal/recordings/{id}/finalize", s.finalizeRecording)
+	worker.HandleFunc("POST /internal/ingest/authorize", s.authorizeIngest)
 }
 
 func (s *Server) allowRate(r *http.Request, bucket string, limit int, window time.Duration) bool {
-	key := bucket + ":" + r.RemoteAddr
+	key := bucket + ":" + s.clientIP(r)
 	now := time.Now()
 	cut := now.Add(-window)
 	s.rateMu.Lock()
@@ -72,163 +132,115 @@ func (s *Server) health(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
-	if !s.allowRate(r, "login", 20, time.Minute) {
-		writeJSON(w, 429, map[string]string{"error": "rate limit"})
-		return
-	}
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct{ Email, Password string }
 	if err := parseBody(r, &body); err != nil {
-		writeJSON(w, 400, map[string]string{"error": "invalid body"})
+		WriteError(w, r, errInvalidBody)
 		return
 	}
 	resp, err := s.svc.Login(body.Email, body.Password)
 	if err != nil {
-		writeJSON(w, 401, map[string]string{"error": err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 	writeJSON(w, 200, resp)
 }
 
 func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct {
 		RefreshToken string `json:"refresh_token"`
 	}
 	if err := parseBody(r, &body); err != nil {
-		writeJSON(w, 400, map[string]string{"error": "invalid body"})
+		WriteError(w, r, errInvalidBody)
 		return
 	}
 	tok, err := s.svc.Refresh(body.RefreshToken)
 	if err != nil {
-		writeJSON(w, 401, map[string]string{"error": "invalid token"})
+		WriteError(w, r, err)
 		return
 	}
 	writeJSON(w, 200, map[string]string{"access_token": tok})
 }
 
 func (s *Server) createStream(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
+	var body streamCreate
+	if err := parseStreamBody(w, r, &body); err != nil {
 		return
 	}
-	var st model.Stream
-	if err := parseBody(r, &st); err != nil {
-		writeJSON(w, 400, map[string]string{"error": "invalid body"})
+	st, err := s.svc.CreateStream(body.ID, body.StreamPatch)
+	if err != nil {
+		WriteError(w, r, err)
 		return
 	}
-	writeJSON(w, 201, s.svc.CreateStream(st))
+	w.Header().Set("ETag", etag(st.Version))
+	writeJSON(w, 201, st)
 }
 
-func (s *Server) streamRoutes(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/streams/")
-	if strings.HasSuffix(path, "/state") {
-		id := strings.TrimSuffix(path, "/state")
-		if r.Method != http.MethodPost {
-			writeJSON(w, 405, map[string]string{"error": "method"})
-			return
-		}
-		var body struct {
-			State string `json:"state"`
-		}
-		_ = parseBody(r, &body)
-		if !s.svc.SetStreamState(id, body.State) {
-			writeJSON(w, 404, map[string]string{"error": "not found"})
-			return
-		}
-		writeJSON(w, 200, map[string]string{"stream_id": id, "state": body.State})
+func (s *Server) abrPresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, 200, s.svc.ABRPresets())
+}
+
+func (s *Server) patchStream(w http.ResponseWriter, r *http.Request) {
+	var body service.StreamPatch
+	if err := parseStreamBody(w, r, &body); err != nil {
 		return
 	}
-	if strings.HasSuffix(path, "/runtime") {
-		id := strings.TrimSuffix(path, "/runtime")
-		resp, ok := s.svc.StreamRuntime(id)
-		if !ok {
-			writeJSON(w, 404, map[string]string{"error": "not found"})
-			return
-		}
-		writeJSON(w, 200, resp)
+	st, err := s.svc.PatchStream(r.Context(), r.PathValue("id"), body, "user:"+userID(r), ifMatch(r))
+	if err != nil {
+		WriteError(w, r, err)
 		return
 	}
-	if r.Method == http.MethodPatch {
-		var body map[string]any
-		if err := parseBody(r, &body); err != nil {
-			writeJSON(w, 400, map[string]string{"error": "invalid body"})
-			return
-		}
-		st, ok := s.svc.PatchStream(path, body)
-		if !ok {
-			writeJSON(w, 404, map[string]string{"error": "not found"})
-			return
-		}
-		writeJSON(w, 200, st)
+	w.Header().Set("ETag", etag(st.Version))
+	writeJSON(w, 200, st)
+}
+
+func (s *Server) streamRuntime(w http.ResponseWriter, r *http.Request) {
+	resp, ok := s.svc.StreamRuntime(r.PathValue("id"))
+	if !ok {
+		WriteError(w, r, errNotFound)
 		return
 	}
-	writeJSON(w, 404, map[string]string{"error": "not found"})
+	writeJSON(w, 200, resp)
 }
 
 func (s *Server) playbackStart(w http.ResponseWriter, r *http.Request) {
-	if !s.allowRate(r, "playback_start", 30, time.Minute) {
-		writeJSON(w, 429, map[string]string{"error": "rate limit"})
-		return
-	}
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
-	var body struct {
-		StreamID string `json:"stream_id"`
-		Token    string `json:"token"`
-	}
+	var body service.PlaybackRequest
 	_ = parseBody(r, &body)
-	resp, code, err := s.svc.StartPlayback(body.StreamID, body.Token, r.RemoteAddr, r.UserAgent())
+	body.IP, body.UserAgent = s.clientIP(r), r.UserAgent()
+	resp, err := s.svc.StartPlayback(body)
 	if err != nil {
-		writeJSON(w, code, map[string]string{"error": err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 	writeJSON(w, 200, resp)
 }
 
 func (s *Server) playbackRenew(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct {
 		SessionID string `json:"session_id"`
 	}
 	_ = parseBody(r, &body)
-	resp, code, err := s.svc.RenewPlayback(body.SessionID)
+	resp, err := s.svc.RenewPlayback(body.SessionID)
 	if err != nil {
-		writeJSON(w, code, map[string]string{"error": err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 	writeJSON(w, 200, resp)
 }
 
 func (s *Server) playbackHeartbeat(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct {
 		SessionID string `json:"session_id"`
 	}
 	_ = parseBody(r, &body)
-	resp, code := s.svc.Heartbeat(body.SessionID)
-	writeJSON(w, code, resp)
+	resp, err := s.svc.Heartbeat(body.SessionID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
 }
 
 func (s *Server) playbackStop(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct {
 		SessionID string `json:"session_id"`
 	}
@@ -238,10 +250,6 @@ func (s *Server) playbackStop(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) playbackKick(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, 405, map[string]string{"error": "method"})
-		return
-	}
 	var body struct {
 		SessionID string `json:"session_id"`
 	}
@@ -264,10 +272,49 @@ func (s *Server) validatePlayback(w http.ResponseWriter, r *http.Request) {
 		token = r.URL.Query().Get("token")
 	}
 	sid := r.Header.Get("X-Session-Id")
-	status, message := s.svc.ValidatePlaybackToken(token, sid)
-	if status != 200 {
-		http.Error(w, message, status)
+	if err := s.svc.ValidatePlaybackToken(token, sid); err != nil {
+		WriteError(w, r, err)
 		return
 	}
 	w.WriteHeader(200)
 }
+
+func (s *Server) workerHeartbeat(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		WorkerID string                `json:"worker_id"`
+		Streams  []model.StreamRuntime `json:"streams"`
+	}
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	resp, err := s.svc.WorkerHeartbeat(body.WorkerID, body.Streams)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) internalStream(w http.ResponseWriter, r *http.Request) {
+	st, ok := s.svc.GetStream(r.PathValue("id"))
+	if !ok {
+		WriteError(w, r, errNotFound)
+		return
+	}
+	writeJSON(w, 200, st)
+}
+
+func (s *Server) reportRuntime(w http.ResponseWriter, r *http.Request) {
+	var body model.StreamRuntime
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	rt, err := s.svc.ReportRuntime(r.PathValue("id"), body)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, rt)
+}
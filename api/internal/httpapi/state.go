@@ -0,0 +1,74 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"streamweb/api/internal/service"
+)
+
+func parseStreamBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil {
+		return nil
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		WriteError(w, r, &service.ValidationError{Fields: []service.FieldError{{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}})
+		return err
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, _ = strconv.Unquote(field)
+		WriteError(w, r, &service.ValidationError{Fields: []service.FieldError{{Field: field, Message: "unknown or read-only field"}}})
+		return err
+	}
+	WriteError(w, r, errInvalidBody)
+	return err
+}
+
+func etag(version int) string { return strconv.Quote(strconv.Itoa(version)) }
+
+func ifMatch(r *http.Request) int {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(v, `"`), `"`))
+	if err != nil || n <= 0 || !strings.HasPrefix(v, `"`) {
+		return -1
+	}
+	return n
+}
+
+func (s *Server) streamState(w http.ResponseWriter, r *http.Request) {
+	resp, err := s.svc.StreamState(r.PathValue("id"))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	writeJSON(w, 200, resp)
+}
+
+func (s *Server) transitionStream(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State  string `json:"state"`
+		Reason string `json:"reason"`
+	}
+	if err := parseBody(r, &body); err != nil {
+		WriteError(w, r, errInvalidBody)
+		return
+	}
+	id := r.PathValue("id")
+	st, err := s.svc.TransitionStream(r.Context(), id, body.State, "user:"+userID(r), body.Reason, ifMatch(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(st.Version))
+	writeJSON(w, 200, map[string]string{"stream_id": id, "state": st.Status})
+}
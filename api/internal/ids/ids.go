@@ -0,0 +1,47 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789abcdefghjkmnpqrstvwxyz"
+
+type Generator interface {
+	New() string
+}
+
+type ULID struct {
+	mu  sync.Mutex
+	now func() time.Time
+	ms  uint64
+	hi  uint16
+	lo  uint64
+}
+
+func NewULID() *ULID { return &ULID{now: time.Now} }
+
+func (g *ULID) New() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ms := uint64(g.now().UnixMilli()); ms > g.ms {
+		var b [10]byte
+		_, _ = rand.Read(b[:])
+		g.ms, g.hi, g.lo = ms, binary.BigEndian.Uint16(b[:2]), binary.BigEndian.Uint64(b[2:])
+	} else if g.lo++; g.lo == 0 {
+		if g.hi++; g.hi == 0 {
+			g.ms++
+		}
+	}
+	hi := g.ms<<16 | uint64(g.hi)
+	lo := g.lo
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,177 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	amfNumber      = 0x00
+	amfBoolean     = 0x01
+	amfString      = 0x02
+	amfObject      = 0x03
+	amfNull        = 0x05
+	amfUndefined   = 0x06
+	amfECMAArray   = 0x08
+	amfObjectEnd   = 0x09
+	amfStrictArray = 0x0a
+	amfDate        = 0x0b
+	amfLongString  = 0x0c
+)
+
+var errAMFObjectEnd = errors.New("amf: object end")
+
+func decodeAMF(b []byte) ([]any, error) {
+	r := bytes.NewReader(b)
+	var out []any
+	for r.Len() > 0 {
+		v, err := readAMF(r)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func readAMF(r *bytes.Reader) (any, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch marker {
+	case amfNumber:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(bits), nil
+	case amfBoolean:
+		v, err := r.ReadByte()
+		return v != 0, err
+	case amfString:
+		return readAMFString(r, 2)
+	case amfLongString:
+		return readAMFString(r, 4)
+	case amfObject:
+		return readAMFObject(r)
+	case amfECMAArray:
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		return readAMFObject(r)
+	case amfStrictArray:
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		out := make([]any, 0, min(n, 1024))
+		for range n {
+			v, err := readAMF(r)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case amfDate:
+		_, err := r.Seek(10, io.SeekCurrent)
+		return nil, err
+	case amfNull, amfUndefined:
+		return nil, nil
+	case amfObjectEnd:
+		return nil, errAMFObjectEnd
+	}
+	return nil, fmt.Errorf("amf: unsupported marker 0x%02x", marker)
+}
+
+func readAMFString(r *bytes.Reader, width int) (string, error) {
+	var n uint32
+	if width == 2 {
+		var short uint16
+		if err := binary.Read(r, binary.BigEndian, &short); err != nil {
+			return "", err
+		}
+		n = uint32(short)
+	} else if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+	if int64(n) > int64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func readAMFObject(r *bytes.Reader) (map[string]any, error) {
+	out := map[string]any{}
+	for {
+		key, err := readAMFString(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readAMF(r)
+		if errors.Is(err, errAMFObjectEnd) && key == "" {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+}
+
+func encodeAMF(values ...any) []byte {
+	var b bytes.Buffer
+	for _, v := range values {
+		writeAMF(&b, v)
+	}
+	return b.Bytes()
+}
+
+func writeAMF(b *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(amfNull)
+	case bool:
+		b.WriteByte(amfBoolean)
+		if v {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case int:
+		writeAMF(b, float64(v))
+	case float64:
+		b.WriteByte(amfNumber)
+		_ = binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case string:
+		b.WriteByte(amfString)
+		writeAMFKey(b, v)
+	case map[string]any:
+		b.WriteByte(amfObject)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeAMFKey(b, k)
+			writeAMF(b, v[k])
+		}
+		b.Write([]byte{0, 0, amfObjectEnd})
+	default:
+		b.WriteByte(amfUndefined)
+	}
+}
+
+func writeAMFKey(b *bytes.Buffer, s string) {
+	_ = binary.Write(b, binary.BigEndian, uint16(len(s)))
+	b.WriteString(s)
+}
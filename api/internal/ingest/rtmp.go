@@ -0,0 +1,428 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	handshakeSize    = 1536
+	outChunkSize     = 4096
+	maxChunkSize     = 1 << 24
+	maxMessageSize   = 16 << 20
+	windowAckSize    = 2500000
+	readIdleTimeout  = 30 * time.Second
+	handshakeTimeout = 10 * time.Second
+
+	msgSetChunkSize  = 1
+	msgAck           = 3
+	msgUserControl   = 4
+	msgWindowAckSize = 5
+	msgPeerBandwidth = 6
+	msgAudio         = 8
+	msgVideo         = 9
+	msgCommandAMF3   = 17
+	msgDataAMF0      = 18
+	msgCommandAMF0   = 20
+
+	csidControl  = 2
+	csidCommand  = 3
+	publishMsgID = 1
+)
+
+type Publisher struct {
+	App    string
+	Key    string
+	Remote net.Addr
+	conn   net.Conn
+	once   sync.Once
+}
+
+func (p *Publisher) Close() error {
+	var err error
+	p.once.Do(func() { err = p.conn.Close() })
+	return err
+}
+
+type PublishFunc func(ctx context.Context, p *Publisher) (io.WriteCloser, error)
+
+type RTMPServer struct {
+	Addr    string
+	Publish PublishFunc
+}
+
+func (s *RTMPServer) ListenAndServe(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	log.Printf("ingest: rtmp listening on %s", ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		go s.serve(ctx, conn)
+	}
+}
+
+func (s *RTMPServer) serve(ctx context.Context, conn net.Conn) {
+	c := &rtmpConn{
+		conn:     conn,
+		r:        bufio.NewReaderSize(conn, 64<<10),
+		inChunk:  128,
+		outChunk: 128,
+		streams:  map[uint32]*chunkStream{},
+		ackEvery: windowAckSize,
+	}
+	defer conn.Close()
+	err := c.run(ctx, s.Publish)
+	if c.sink != nil {
+		c.sink.Close()
+	}
+	if c.pub != nil {
+		log.Printf("ingest: publisher %s for key %s… disconnected: %v", conn.RemoteAddr(), redact(c.pub.Key), err)
+	}
+}
+
+func redact(key string) string {
+	if len(key) > 6 {
+		return key[:6]
+	}
+	return key
+}
+
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typeID    uint8
+	streamID  uint32
+	extended  bool
+	buf       []byte
+}
+
+type rtmpConn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	inChunk  uint32
+	outChunk int
+	streams  map[uint32]*chunkStream
+	read     uint64
+	acked    uint64
+	ackEvery uint64
+	app      string
+	pub      *Publisher
+	sink     io.WriteCloser
+}
+
+func (c *rtmpConn) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += uint64(n)
+	return n, err
+}
+
+func (c *rtmpConn) run(ctx context.Context, publish PublishFunc) error {
+	_ = c.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := c.handshake(); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	_ = c.conn.SetWriteDeadline(time.Time{})
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(readIdleTimeout))
+		typeID, ts, payload, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		if c.read-c.acked >= c.ackEvery {
+			c.acked = c.read
+			if err := c.writeMessage(csidControl, msgAck, 0, 0, binary.BigEndian.AppendUint32(nil, uint32(c.read))); err != nil {
+				return err
+			}
+		}
+		switch typeID {
+		case msgSetChunkSize:
+			if len(payload) < 4 {
+				return errors.New("short set chunk size")
+			}
+			size := binary.BigEndian.Uint32(payload) & 0x7fffffff
+			if size == 0 || size > maxChunkSize {
+				return fmt.Errorf("invalid chunk size %d", size)
+			}
+			c.inChunk = size
+		case msgWindowAckSize:
+			if len(payload) >= 4 {
+				if n := binary.BigEndian.Uint32(payload); n > 0 {
+					c.ackEvery = uint64(n)
+				}
+			}
+		case msgCommandAMF3, msgCommandAMF0:
+			if typeID == msgCommandAMF3 && len(payload) > 0 {
+				payload = payload[1:]
+			}
+			if err := c.command(ctx, payload, publish); err != nil {
+				return err
+			}
+		case msgAudio, msgVideo, msgDataAMF0:
+			if c.sink == nil {
+				continue
+			}
+			if typeID == msgDataAMF0 {
+				payload = stripSetDataFrame(payload)
+			}
+			if err := writeFLVTag(c.sink, typeID, ts, payload); err != nil {
+				return fmt.Errorf("forward: %w", err)
+			}
+		}
+	}
+}
+
+func (c *rtmpConn) handshake() error {
+	c0c1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(c, c0c1); err != nil {
+		return err
+	}
+	if c0c1[0] != 3 {
+		return fmt.Errorf("unsupported version %d", c0c1[0])
+	}
+	s0s1s2 := make([]byte, 1+2*handshakeSize)
+	s0s1s2[0] = 3
+	binary.BigEndian.PutUint32(s0s1s2[1:], uint32(time.Now().Unix()))
+	_, _ = rand.Read(s0s1s2[9 : 1+handshakeSize])
+	copy(s0s1s2[1+handshakeSize:], c0c1[1:])
+	if _, err := c.conn.Write(s0s1s2); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(c, make([]byte, handshakeSize))
+	return err
+}
+
+func (c *rtmpConn) readMessage() (uint8, uint32, []byte, error) {
+	for {
+		first, err := c.readByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		format, csid := first>>6, uint32(first&0x3f)
+		switch csid {
+		case 0:
+			b, err := c.readN(1)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			csid = 64 + uint32(b[0])
+		case 1:
+			b, err := c.readN(2)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			csid = 64 + uint32(b[0]) + uint32(b[1])<<8
+		}
+		cs := c.streams[csid]
+		if cs == nil {
+			if format != 0 {
+				return 0, 0, nil, fmt.Errorf("chunk stream %d starts with format %d", csid, format)
+			}
+			cs = &chunkStream{}
+			c.streams[csid] = cs
+		}
+		var ts uint32
+		switch format {
+		case 0, 1, 2:
+			size := [...]int{11, 7, 3}[format]
+			h, err := c.readN(size)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			ts = uint24(h[0:3])
+			if format <= 1 {
+				cs.length, cs.typeID = uint24(h[3:6]), h[6]
+			}
+			if format == 0 {
+				cs.streamID = binary.LittleEndian.Uint32(h[7:11])
+			}
+			cs.extended = ts == 0xffffff
+		}
+		if cs.extended {
+			b, err := c.readN(4)
+			if err != nil {
+				return 0, 0, nil, err
+			}
+			if format != 3 {
+				ts = binary.BigEndian.Uint32(b)
+			}
+		}
+		if len(cs.buf) == 0 {
+			switch format {
+			case 0:
+				cs.timestamp, cs.delta = ts, 0
+			case 1, 2:
+				cs.delta = ts
+				cs.timestamp += ts
+			case 3:
+				cs.timestamp += cs.delta
+			}
+			if cs.length > maxMessageSize {
+				return 0, 0, nil, fmt.Errorf("message of %d bytes too large", cs.length)
+			}
+		}
+		n := min(cs.length-uint32(len(cs.buf)), c.inChunk)
+		data, err := c.readN(int(n))
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		cs.buf = append(cs.buf, data...)
+		if uint32(len(cs.buf)) < cs.length {
+			continue
+		}
+		payload := cs.buf
+		cs.buf = nil
+		return cs.typeID, cs.timestamp, payload, nil
+	}
+}
+
+func (c *rtmpConn) readByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.read++
+	}
+	return b, err
+}
+
+func (c *rtmpConn) readN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(c, buf)
+	return buf, err
+}
+
+func uint24(b []byte) uint32 { return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]) }
+
+func (c *rtmpConn) command(ctx context.Context, payload []byte, publish PublishFunc) error {
+	args, err := decodeAMF(payload)
+	if err != nil && len(args) < 2 {
+		return fmt.Errorf("decode command: %w", err)
+	}
+	if len(args) < 2 {
+		return nil
+	}
+	name, _ := args[0].(string)
+	txn, _ := args[1].(float64)
+	switch name {
+	case "connect":
+		if len(args) > 2 {
+			if obj, ok := args[2].(map[string]any); ok {
+				c.app, _ = obj["app"].(string)
+			}
+		}
+		if err := c.writeMessage(csidControl, msgWindowAckSize, 0, 0, binary.BigEndian.AppendUint32(nil, windowAckSize)); err != nil {
+			return err
+		}
+		if err := c.writeMessage(csidControl, msgPeerBandwidth, 0, 0, append(binary.BigEndian.AppendUint32(nil, windowAckSize), 2)); err != nil {
+			return err
+		}
+		if err := c.writeMessage(csidControl, msgSetChunkSize, 0, 0, binary.BigEndian.AppendUint32(nil, outChunkSize)); err != nil {
+			return err
+		}
+		c.outChunk = outChunkSize
+		return c.writeCommand(0, "_result", txn,
+			map[string]any{"fmsVer": "FMS/3,0,1,123", "capabilities": 31},
+			map[string]any{"level": "status", "code": "NetConnection.Connect.Success", "description": "Connection succeeded.", "objectEncoding": 0})
+	case "releaseStream", "FCPublish", "FCUnpublish":
+		return c.writeCommand(0, "_result", txn, nil)
+	case "createStream":
+		return c.writeCommand(0, "_result", txn, nil, publishMsgID)
+	case "publish":
+		if c.pub != nil {
+			return errors.New("already publishing")
+		}
+		key := ""
+		if len(args) > 3 {
+			key, _ = args[3].(string)
+		}
+		key, _, _ = strings.Cut(key, "?")
+		pub := &Publisher{App: c.app, Key: key, Remote: c.conn.RemoteAddr(), conn: c.conn}
+		sink, err := publish(ctx, pub)
+		if err != nil {
+			log.Printf("ingest: rejected publish from %s (app %q, key %s…): %v", pub.Remote, c.app, redact(key), err)
+			_ = c.writeCommand(publishMsgID, "onStatus", 0, nil,
+				map[string]any{"level": "error", "code": "NetStream.Publish.BadName", "description": err.Error()})
+			return fmt.Errorf("publish rejected: %w", err)
+		}
+		c.pub, c.sink = pub, sink
+		if err := writeFLVHeader(sink); err != nil {
+			return err
+		}
+		log.Printf("ingest: publisher %s accepted for key %s…", pub.Remote, redact(key))
+		return c.writeCommand(publishMsgID, "onStatus", 0, nil,
+			map[string]any{"level": "status", "code": "NetStream.Publish.Start", "description": "Publishing started."})
+	case "deleteStream", "closeStream":
+		return io.EOF
+	}
+	return nil
+}
+
+func (c *rtmpConn) writeCommand(streamID uint32, name string, txn float64, args ...any) error {
+	return c.writeMessage(csidCommand, msgCommandAMF0, streamID, 0, encodeAMF(append([]any{name, txn}, args...)...))
+}
+
+func (c *rtmpConn) writeMessage(csid uint8, typeID uint8, streamID, ts uint32, payload []byte) error {
+	chunk := c.outChunk
+	out := make([]byte, 0, 12+len(payload)+len(payload)/chunk)
+	out = append(out, csid&0x3f, byte(ts>>16), byte(ts>>8), byte(ts),
+		byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload)), typeID)
+	out = binary.LittleEndian.AppendUint32(out, streamID)
+	for i := 0; i < len(payload); i += chunk {
+		if i > 0 {
+			out = append(out, 0xc0|csid&0x3f)
+		}
+		out = append(out, payload[i:min(i+chunk, len(payload))]...)
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(handshakeTimeout))
+	_, err := c.conn.Write(out)
+	return err
+}
+
+func stripSetDataFrame(payload []byte) []byte {
+	const marker = "@setDataFrame"
+	prefix := append([]byte{amfString, 0, byte(len(marker))}, marker...)
+	if len(payload) > len(prefix) && string(payload[:len(prefix)]) == string(prefix) {
+		return payload[len(prefix):]
+	}
+	return payload
+}
+
+func writeFLVHeader(w io.Writer) error {
+	_, err := w.Write([]byte{'F', 'L', 'V', 1, 0x05, 0, 0, 0, 9, 0, 0, 0, 0})
+	return err
+}
+
+func writeFLVTag(w io.Writer, typeID uint8, ts uint32, payload []byte) error {
+	tag := make([]byte, 0, 15+len(payload))
+	tag = append(tag, typeID, byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload)),
+		byte(ts>>16), byte(ts>>8), byte(ts), byte(ts>>24), 0, 0, 0)
+	tag = append(tag, payload...)
+	tag = binary.BigEndian.AppendUint32(tag, uint32(11+len(payload)))
+	_, err := w.Write(tag)
+	return err
+}
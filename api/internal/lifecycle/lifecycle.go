@@ -0,0 +1,123 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+const stopGrace = 100 * time.Millisecond
+
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+type Manager struct {
+	hooks   []Hook
+	started int
+	failed  chan error
+}
+
+func New() *Manager { return &Manager{failed: make(chan error, 1)} }
+
+func (m *Manager) Append(h Hook) { m.hooks = append(m.hooks, h) }
+
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	m.Append(Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+			}
+			select {
+			case <-done:
+				return nil
+			case <-time.After(stopGrace):
+				return ctx.Err()
+			}
+		},
+	})
+}
+
+func (m *Manager) Serve(name string, srv *http.Server) {
+	m.Append(Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			log.Printf("%s: listening on %s", name, ln.Addr())
+			go func() {
+				if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					m.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				_ = srv.Close()
+				return err
+			}
+			return nil
+		},
+	})
+}
+
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+func (m *Manager) Failed() <-chan error { return m.failed }
+
+func (m *Manager) Start(ctx context.Context) error {
+	for _, h := range m.hooks {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				return errors.Join(fmt.Errorf("start %s: %w", h.Name, err), m.Stop(ctx))
+			}
+		}
+		m.started++
+	}
+	return nil
+}
+
+func (m *Manager) Stop(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		h := m.hooks[m.started-1]
+		if h.Stop == nil {
+			continue
+		}
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.Name, err))
+			continue
+		}
+		log.Printf("lifecycle: stopped %s", h.Name)
+	}
+	return errors.Join(errs...)
+}
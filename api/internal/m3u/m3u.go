@@ -0,0 +1,164 @@
+package m3u
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Entry struct {
+	Line     int
+	Title    string
+	Duration float64
+	Attrs    map[string]string
+	URL      string
+}
+
+func (e Entry) TvgID() string   { return e.Attrs["tvg-id"] }
+func (e Entry) TvgName() string { return e.Attrs["tvg-name"] }
+func (e Entry) Group() string   { return e.Attrs["group-title"] }
+func (e Entry) Logo() string    { return e.Attrs["tvg-logo"] }
+
+func (e Entry) Name() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.TvgName()
+}
+
+type Problem struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+func Parse(r io.Reader) ([]Entry, []Problem, error) {
+	var (
+		entries  []Entry
+		problems []Problem
+		pending  *Entry
+		lineNo   int
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\uFEFF"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTM3U"):
+		case strings.HasPrefix(line, "#EXTINF:"):
+			if pending != nil {
+				problems = append(problems, Problem{Line: pending.Line, Reason: "#EXTINF without url"})
+			}
+			e := parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+			e.Line = lineNo
+			pending = &e
+		case strings.HasPrefix(line, "#EXTGRP:"):
+			if pending != nil && pending.Attrs["group-title"] == "" {
+				pending.Attrs["group-title"] = strings.TrimSpace(strings.TrimPrefix(line, "#EXTGRP:"))
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			if pending == nil {
+				problems = append(problems, Problem{Line: lineNo, Reason: "url without #EXTINF"})
+				continue
+			}
+			pending.URL = line
+			entries = append(entries, *pending)
+			pending = nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("read m3u: %w", err)
+	}
+	if pending != nil {
+		problems = append(problems, Problem{Line: pending.Line, Reason: "#EXTINF without url"})
+	}
+	return entries, problems, nil
+}
+
+func parseExtInf(s string) Entry {
+	e := Entry{Attrs: map[string]string{}}
+	i := strings.IndexAny(s, " ,")
+	if i < 0 {
+		e.Duration, _ = strconv.ParseFloat(s, 64)
+		return e
+	}
+	e.Duration, _ = strconv.ParseFloat(s[:i], 64)
+	s = s[i:]
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return e
+		}
+		if s[0] == ',' {
+			e.Title = strings.TrimSpace(s[1:])
+			return e
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			if comma := strings.IndexByte(s, ','); comma >= 0 {
+				e.Title = strings.TrimSpace(s[comma+1:])
+			}
+			return e
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				val, s = s[1:], ""
+			} else {
+				val, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " ,")
+			if end < 0 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+		}
+		e.Attrs[key] = val
+	}
+}
+
+var (
+	attrOrder   = []string{"tvg-id", "tvg-name", "tvg-logo", "group-title"}
+	attrEscaper = strings.NewReplacer(`"`, "'", "\r", " ", "\n", " ")
+)
+
+func Write(w io.Writer, header map[string]string, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#EXTM3U")
+	for _, k := range slices.Sorted(maps.Keys(header)) {
+		fmt.Fprintf(bw, ` %s="%s"`, k, attrEscaper.Replace(header[k]))
+	}
+	bw.WriteString("\n")
+	for _, e := range entries {
+		fmt.Fprintf(bw, "#EXTINF:%s", strconv.FormatFloat(e.Duration, 'f', -1, 64))
+		keys := make([]string, 0, len(e.Attrs))
+		for _, k := range attrOrder {
+			if _, ok := e.Attrs[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+		var extra []string
+		for k := range e.Attrs {
+			if !slices.Contains(attrOrder, k) {
+				extra = append(extra, k)
+			}
+		}
+		sort.Strings(extra)
+		for _, k := range append(keys, extra...) {
+			fmt.Fprintf(bw, ` %s="%s"`, k, attrEscaper.Replace(e.Attrs[k]))
+		}
+		fmt.Fprintf(bw, ",%s\n%s\n", strings.Join(strings.Fields(e.Title), " "), e.URL)
+	}
+	return bw.Flush()
+}
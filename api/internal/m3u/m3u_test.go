@@ -0,0 +1,112 @@
+package m3u
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		in       string
+		entries  []Entry
+		problems []Problem
+	}{
+		{
+			name: "attributes and title",
+			in:   "#EXTM3U\n#EXTINF:-1 tvg-id=\"bbc1.uk\" tvg-name=\"BBC One\" tvg-logo=\"http://x/l.png\" group-title=\"UK, News\",BBC One HD\nhttp://a/1.m3u8\n",
+			entries: []Entry{{Line: 2, Title: "BBC One HD", Duration: -1, URL: "http://a/1.m3u8",
+				Attrs: map[string]string{"tvg-id": "bbc1.uk", "tvg-name": "BBC One", "tvg-logo": "http://x/l.png", "group-title": "UK, News"}}},
+		},
+		{
+			name:    "bom, crlf, blank lines and comments",
+			in:      "\uFEFF#EXTM3U\r\n\r\n# comment\r\n#EXTINF:0,One\r\n#EXTVLCOPT:http-user-agent=x\r\nhttp://a/1\r\n",
+			entries: []Entry{{Line: 4, Title: "One", Duration: 0, URL: "http://a/1", Attrs: map[string]string{}}},
+		},
+		{
+			name:    "unquoted and upper-case attributes",
+			in:      "#EXTINF:10 TVG-ID=one group-title=News,One\nhttp://a/1",
+			entries: []Entry{{Line: 1, Title: "One", Duration: 10, URL: "http://a/1", Attrs: map[string]string{"tvg-id": "one", "group-title": "News"}}},
+		},
+		{
+			name:    "extgrp fills a missing group",
+			in:      "#EXTINF:-1,One\n#EXTGRP:Sports\nhttp://a/1\n#EXTINF:-1 group-title=\"News\",Two\n#EXTGRP:Sports\nhttp://a/2",
+			entries: []Entry{{Line: 1, Title: "One", Duration: -1, URL: "http://a/1", Attrs: map[string]string{"group-title": "Sports"}}, {Line: 4, Title: "Two", Duration: -1, URL: "http://a/2", Attrs: map[string]string{"group-title": "News"}}},
+		},
+		{
+			name:    "unterminated quote",
+			in:      "#EXTINF:-1 tvg-id=\"one,One\nhttp://a/1",
+			entries: []Entry{{Line: 1, Duration: -1, URL: "http://a/1", Attrs: map[string]string{"tvg-id": "one,One"}}},
+		},
+		{
+			name:    "garbage without equals keeps the title",
+			in:      "#EXTINF:-1 junk,One\nhttp://a/1",
+			entries: []Entry{{Line: 1, Title: "One", Duration: -1, URL: "http://a/1", Attrs: map[string]string{}}},
+		},
+		{
+			name:     "orphans",
+			in:       "http://a/0\n#EXTINF:-1,One\n#EXTINF:-1,Two\nhttp://a/2\n#EXTINF:-1,Three\n",
+			entries:  []Entry{{Line: 3, Title: "Two", Duration: -1, URL: "http://a/2", Attrs: map[string]string{}}},
+			problems: []Problem{{Line: 1, Reason: "url without #EXTINF"}, {Line: 2, Reason: "#EXTINF without url"}, {Line: 5, Reason: "#EXTINF without url"}},
+		},
+		{
+			name: "empty",
+			in:   "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, problems, err := Parse(strings.NewReader(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tc.entries) {
+				t.Errorf("entries = %+v, want %+v", entries, tc.entries)
+			}
+			if !reflect.DeepEqual(problems, tc.problems) {
+				t.Errorf("problems = %+v, want %+v", problems, tc.problems)
+			}
+		})
+	}
+}
+
+func TestEntryName(t *testing.T) {
+	for _, tc := range []struct {
+		e    Entry
+		want string
+	}{
+		{Entry{Title: "Title", Attrs: map[string]string{"tvg-name": "Name"}}, "Title"},
+		{Entry{Attrs: map[string]string{"tvg-name": "Name"}}, "Name"},
+		{Entry{Attrs: map[string]string{}}, ""},
+	} {
+		if got := tc.e.Name(); got != tc.want {
+			t.Errorf("Name(%+v) = %q, want %q", tc.e, got, tc.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	entries := []Entry{
+		{Title: "  BBC \n One ", Duration: -1, URL: "http://a/1", Attrs: map[string]string{"x-extra": "1", "group-title": `say "hi"`, "tvg-id": "bbc1"}},
+		{Title: "Two", Duration: 4.5, URL: "http://a/2", Attrs: map[string]string{}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, map[string]string{"x-tvg-url": "http://g/epg.xml", "url-tvg": "http://g/epg.xml"}, entries); err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U url-tvg=\"http://g/epg.xml\" x-tvg-url=\"http://g/epg.xml\"\n" +
+		"#EXTINF:-1 tvg-id=\"bbc1\" group-title=\"say 'hi'\" x-extra=\"1\",BBC One\nhttp://a/1\n" +
+		"#EXTINF:4.5,Two\nhttp://a/2\n"
+	if buf.String() != want {
+		t.Errorf("Write =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	parsed, problems, err := Parse(&buf)
+	if err != nil || len(problems) != 0 || len(parsed) != 2 {
+		t.Fatalf("Parse(Write) = %+v, %+v, %v", parsed, problems, err)
+	}
+	if parsed[0].Title != "BBC One" || parsed[0].TvgID() != "bbc1" || parsed[0].Group() != "say 'hi'" || parsed[1].Duration != 4.5 {
+		t.Errorf("round trip = %+v", parsed)
+	}
+}
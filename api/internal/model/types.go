@@ -3,11 +3,12 @@ package model
 import "time"
 
 type User struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"-"`
-	Role     string `json:"role"`
-	Status   string `json:"status"`
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	Password    string `json:"-"`
+	Role        string `json:"role"`
+	Status      string `json:"status"`
+	PlaylistKey string `json:"-"`
 }
 
 type Wallet struct {
@@ -16,26 +17,69 @@ type Wallet struct {
 }
 
 type Stream struct {
-	ID                    string `json:"id"`
-	Name                  string `json:"name"`
-	Status                string `json:"status"`
-	IngestMode            string `json:"ingest_mode"`
-	IngestURL             string `json:"ingest_url"`
-	SegmentDurationSec    int    `json:"segment_duration_sec"`
-	PlaylistWindowMinutes int    `json:"playlist_window_minutes"`
-	PointsRate            int    `json:"points_rate"`
-	MaxConcurrentSessions int    `json:"max_concurrent_sessions"`
+	ID                    string       `json:"id"`
+	Name                  string       `json:"name"`
+	Status                string       `json:"status"`
+	IngestMode            string       `json:"ingest_mode"`
+	IngestURL             string       `json:"ingest_url"`
+	ExternalID            string       `json:"external_id,omitempty"`
+	Group                 string       `json:"group,omitempty"`
+	LogoURL               string       `json:"logo_url,omitempty"`
+	StreamKey             string       `json:"-"`
+	BackupIngestURLs      []string     `json:"backup_ingest_urls,omitempty"`
+	FailoverAfterSec      int          `json:"failover_after_sec"`
+	ABRProfiles           []ABRProfile `json:"abr_profiles"`
+	SegmentDurationSec    int          `json:"segment_duration_sec"`
+	PlaylistWindowMinutes int          `json:"playlist_window_minutes"`
+	DVRWindowMinutes      int          `json:"dvr_window_minutes"`
+	RecordingEnabled      bool         `json:"recording_enabled"`
+	RecordingPointsRate   int          `json:"recording_points_rate"`
+	PointsRate            int          `json:"points_rate"`
+	MaxConcurrentSessions int          `json:"max_concurrent_sessions"`
+	Version               int          `json:"version"`
+	CreatedAt             time.Time    `json:"created_at"`
+	DeletedAt             time.Time    `json:"deleted_at,omitzero"`
+}
+
+type ABRProfile struct {
+	Name                string `json:"name"`
+	Width               int    `json:"width"`
+	Height              int    `json:"height"`
+	VideoBitrateKbps    int    `json:"video_bitrate_kbps"`
+	AudioBitrateKbps    int    `json:"audio_bitrate_kbps"`
+	Codec               string `json:"codec"`
+	Profile             string `json:"profile"`
+	KeyframeIntervalSec int    `json:"keyframe_interval_sec"`
 }
 
 type Session struct {
-	ID         string    `json:"id"`
-	UserID     string    `json:"user_id"`
-	StreamID   string    `json:"stream_id"`
-	State      string    `json:"state"`
-	StartedAt  time.Time `json:"started_at"`
-	LastSeenAt time.Time `json:"last_seen_at"`
-	IP         string    `json:"ip"`
-	UserAgent  string    `json:"user_agent"`
+	ID                 string    `json:"id"`
+	UserID             string    `json:"user_id"`
+	StreamID           string    `json:"stream_id"`
+	State              string    `json:"state"`
+	StartedAt          time.Time `json:"started_at"`
+	LastSeenAt         time.Time `json:"last_seen_at"`
+	IP                 string    `json:"ip"`
+	UserAgent          string    `json:"user_agent"`
+	TimeshiftOffsetSec int       `json:"timeshift_offset_sec"`
+	DVRMode            string    `json:"dvr_mode,omitempty"`
+	RecordingID        string    `json:"recording_id,omitempty"`
+	ClipID             string    `json:"clip_id,omitempty"`
+	Source             string    `json:"source,omitempty"`
+	BillingMode        string    `json:"billing_mode"`
+}
+
+type Recording struct {
+	ID           string    `json:"id"`
+	StreamID     string    `json:"stream_id"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	DurationSec  float64   `json:"duration_sec"`
+	SegmentCount int       `json:"segment_count"`
+	SizeBytes    int64     `json:"size_bytes"`
+	PointsRate   int       `json:"points_rate"`
 }
 
 type LedgerEntry struct {
@@ -47,3 +91,93 @@ type LedgerEntry struct {
 	SessionID string    `json:"session_id"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type StreamRuntime struct {
+	StreamID          string    `json:"stream_id"`
+	DesiredState      string    `json:"desired_state"`
+	ActualState       string    `json:"actual_state"`
+	WorkerID          string    `json:"worker_id"`
+	LastHeartbeatAt   time.Time `json:"last_heartbeat_at"`
+	LastManifestAt    time.Time `json:"last_manifest_at"`
+	IngestBitrateKbps int       `json:"ingest_bitrate_kbps"`
+	SegmentCount      int64     `json:"segment_count"`
+	LastError         string    `json:"last_error"`
+	ActiveSource      int       `json:"active_source"`
+	FailoverCount     int       `json:"failover_count"`
+	LastFailoverAt    time.Time `json:"last_failover_at"`
+	LastFailover      string    `json:"last_failover,omitempty"`
+	ConfigGeneration  int       `json:"config_generation"`
+}
+
+type FailoverEvent struct {
+	WorkerID  string `json:"worker_id"`
+	FromIndex int    `json:"from_index"`
+	ToIndex   int    `json:"to_index"`
+	Reason    string `json:"reason"`
+}
+
+type AuditEntry struct {
+	ID       string    `json:"id"`
+	At       time.Time `json:"at"`
+	Actor    string    `json:"actor"`
+	Action   string    `json:"action"`
+	StreamID string    `json:"stream_id,omitempty"`
+	Detail   string    `json:"detail"`
+}
+
+type Clip struct {
+	ID          string    `json:"id"`
+	StreamID    string    `json:"stream_id"`
+	RecordingID string    `json:"recording_id,omitempty"`
+	Title       string    `json:"title"`
+	Format      string    `json:"format"`
+	Status      string    `json:"status"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	DurationSec float64   `json:"duration_sec"`
+	SizeBytes   int64     `json:"size_bytes"`
+	PointsRate  int       `json:"points_rate"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type Schedule struct {
+	ID              string    `json:"id"`
+	StreamID        string    `json:"stream_id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Category        string    `json:"category"`
+	StartAt         time.Time `json:"start_at"`
+	Cron            string    `json:"cron,omitempty"`
+	Timezone        string    `json:"timezone"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Enabled         bool      `json:"enabled"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastStartedAt   time.Time `json:"last_started_at"`
+	LastStoppedAt   time.Time `json:"last_stopped_at"`
+	LastError       string    `json:"last_error,omitempty"`
+}
+
+type Programme struct {
+	ID          string    `json:"id"`
+	StreamID    string    `json:"stream_id"`
+	ChannelID   string    `json:"channel_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	ImportedAt  time.Time `json:"imported_at"`
+}
+
+type StreamStateChange struct {
+	ID       string    `json:"id"`
+	StreamID string    `json:"stream_id"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Actor    string    `json:"actor"`
+	Reason   string    `json:"reason"`
+	At       time.Time `json:"at"`
+}
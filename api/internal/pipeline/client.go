@@ -0,0 +1,133 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+type statusError struct {
+	method, path string
+	status       int
+	code, detail string
+}
+
+func (e *statusError) Error() string {
+	if e.code == "" {
+		return fmt.Sprintf("%s %s: status %d", e.method, e.path, e.status)
+	}
+	return fmt.Sprintf("%s %s: status %d: %s: %s", e.method, e.path, e.status, e.code, e.detail)
+}
+
+type controlClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func newControlClient(base, token string) *controlClient {
+	return &controlClient{base: base, token: token, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *controlClient) do(ctx context.Context, method, path string, body, out any) error {
+	var rd *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	} else {
+		rd = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, rd)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		se := &statusError{method: method, path: path, status: res.StatusCode}
+		var problem struct{ Code, Detail string }
+		if json.NewDecoder(res.Body).Decode(&problem) == nil {
+			se.code, se.detail = problem.Code, problem.Detail
+		}
+		return se
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+type desiredStream struct {
+	State      string
+	Generation int
+}
+
+func (c *controlClient) heartbeat(ctx context.Context, workerID string, reports []model.StreamRuntime) (map[string]desiredStream, []string, error) {
+	var out struct {
+		Streams []struct {
+			StreamID         string `json:"stream_id"`
+			DesiredState     string `json:"desired_state"`
+			ConfigGeneration int    `json:"config_generation"`
+		} `json:"streams"`
+		Unknown []string `json:"unknown"`
+	}
+	body := map[string]any{"worker_id": workerID, "streams": reports}
+	if err := c.do(ctx, http.MethodPost, "/internal/workers/heartbeat", body, &out); err != nil {
+		return nil, nil, err
+	}
+	desired := make(map[string]desiredStream, len(out.Streams))
+	for _, st := range out.Streams {
+		desired[st.StreamID] = desiredStream{State: st.DesiredState, Generation: st.ConfigGeneration}
+	}
+	return desired, out.Unknown, nil
+}
+
+func (c *controlClient) stream(ctx context.Context, id string) (model.Stream, error) {
+	var st model.Stream
+	err := c.do(ctx, http.MethodGet, "/internal/streams/"+id, nil, &st)
+	return st, err
+}
+
+type recordingReport struct {
+	DurationSec  float64 `json:"duration_sec"`
+	SegmentCount int     `json:"segment_count"`
+	SizeBytes    int64   `json:"size_bytes"`
+	Failed       bool    `json:"failed"`
+}
+
+func (c *controlClient) openRecording(ctx context.Context, streamID string) (model.Recording, error) {
+	var rec model.Recording
+	err := c.do(ctx, http.MethodPost, "/internal/streams/"+streamID+"/recordings", nil, &rec)
+	return rec, err
+}
+
+func (c *controlClient) finalizeRecording(ctx context.Context, id string, rep recordingReport) error {
+	return c.do(ctx, http.MethodPost, "/internal/recordings/"+id+"/finalize", rep, nil)
+}
+
+func (c *controlClient) authorizeIngest(ctx context.Context, key, remote string) (string, error) {
+	var out struct {
+		StreamID string `json:"stream_id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/internal/ingest/authorize", map[string]string{"stream_key": key, "remote_addr": remote}, &out)
+	return out.StreamID, err
+}
+
+func (c *controlClient) reportFailover(ctx context.Context, streamID string, ev model.FailoverEvent) error {
+	return c.do(ctx, http.MethodPost, "/internal/streams/"+streamID+"/failover", ev, nil)
+}
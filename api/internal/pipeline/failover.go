@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	defaultFailoverAfter  = 15 * time.Second
+	failbackProbeInterval = 10 * time.Second
+	failbackHealthyProbes = 3
+	sourceWatchInterval   = time.Second
+)
+
+type sourceState struct {
+	stream     model.Stream
+	generation int
+	index      int
+	since      time.Time
+	healthy    int
+	lastProbe  time.Time
+}
+
+func ingestSources(st model.Stream) []string {
+	var out []string
+	for _, u := range append([]string{st.IngestURL}, st.BackupIngestURLs...) {
+		if u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+func failoverAfter(st model.Stream) time.Duration {
+	if st.FailoverAfterSec > 0 {
+		return time.Duration(st.FailoverAfterSec) * time.Second
+	}
+	return defaultFailoverAfter
+}
+
+func (w *Worker) watchSources(ctx context.Context) {
+	ticker := time.NewTicker(sourceWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, id := range w.cfg.StreamIDs {
+				w.checkSource(ctx, id, now)
+			}
+		}
+	}
+}
+
+func (w *Worker) checkSource(ctx context.Context, id string, now time.Time) {
+	w.mu.Lock()
+	ss := w.sources[id]
+	if ss == nil || w.desired[id].State != "running" {
+		w.mu.Unlock()
+		return
+	}
+	st, index, since := ss.stream, ss.index, ss.since
+	probe := index > 0 && now.Sub(ss.lastProbe) >= failbackProbeInterval
+	if probe {
+		ss.lastProbe = now
+	}
+	w.mu.Unlock()
+
+	sources := ingestSources(st)
+	if len(sources) < 2 {
+		return
+	}
+	lastManifest, _ := scanOutput(w.streamDir(id))
+	if lastManifest.After(since) {
+		since = lastManifest
+	}
+	if after := failoverAfter(st); now.Sub(since) > after {
+		w.switchSource(ctx, id, index, (index+1)%len(sources), fmt.Sprintf("no new segments for %s", after))
+		return
+	}
+	if !probe {
+		return
+	}
+	err := probeSource(ctx, sources[0])
+	w.mu.Lock()
+	if err != nil {
+		ss.healthy = 0
+	} else {
+		ss.healthy++
+	}
+	healthy := ss.healthy
+	w.mu.Unlock()
+	if healthy >= failbackHealthyProbes {
+		w.switchSource(ctx, id, index, 0, fmt.Sprintf("primary healthy for %d probes", healthy))
+	}
+}
+
+func (w *Worker) switchSource(ctx context.Context, id string, from, to int, reason string) {
+	w.launchMu.Lock()
+	defer w.launchMu.Unlock()
+	w.mu.Lock()
+	ss := w.sources[id]
+	if ss == nil || ss.index != from {
+		w.mu.Unlock()
+		return
+	}
+	ss.index, ss.since, ss.healthy, ss.lastProbe = to, time.Now(), 0, time.Time{}
+	st, generation := ss.stream, ss.generation
+	w.mu.Unlock()
+
+	log.Printf("worker %s: stream %s switching ingest source %d -> %d: %s", w.cfg.WorkerID, id, from, to, reason)
+	w.stop(id, false)
+	ev := model.FailoverEvent{WorkerID: w.cfg.WorkerID, FromIndex: from, ToIndex: to, Reason: reason}
+	if err := w.api.reportFailover(ctx, id, ev); err != nil {
+		log.Printf("worker %s: stream %s: report failover: %v", w.cfg.WorkerID, id, err)
+	}
+	st.IngestURL = ingestSources(st)[to]
+	if _, err := w.launch(ctx, st, nil, generation); err != nil {
+		w.setError(id, err.Error())
+	}
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	defaultSegmentSec    = 4
+	defaultWindowMinutes = 2
+)
+
+var videoEncoders = map[string]string{"h264": "libx264", "hevc": "libx265"}
+
+func hlsListSize(st model.Stream) int {
+	seg := st.SegmentDurationSec
+	if seg <= 0 {
+		seg = defaultSegmentSec
+	}
+	window := st.PlaylistWindowMinutes
+	if window <= 0 {
+		window = defaultWindowMinutes
+	}
+	if n := window * 60 / seg; n > 0 {
+		return n
+	}
+	return 1
+}
+
+func FFmpegArgs(st model.Stream, outDir string) []string {
+	seg := st.SegmentDurationSec
+	if seg <= 0 {
+		seg = defaultSegmentSec
+	}
+	args := []string{"-hide_banner", "-loglevel", "warning", "-nostats", "-progress", "pipe:1"}
+	if st.IngestMode == "push" {
+		args = append(args, "-f", "flv", "-i", "pipe:0")
+	} else {
+		args = append(args,
+			"-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5",
+			"-i", st.IngestURL,
+		)
+	}
+	hls := []string{
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(seg),
+		"-hls_list_size", strconv.Itoa(hlsListSize(st)),
+		"-hls_flags", "delete_segments+independent_segments+temp_file+program_date_time",
+		"-hls_start_number_source", "epoch",
+	}
+	if isPassthrough(st.ABRProfiles) {
+		dir := filepath.Join(outDir, "source")
+		args = append(args, "-map", "0:v:0?", "-map", "0:a:0?", "-c", "copy")
+		args = append(args, hls...)
+		return append(args, "-hls_segment_filename", filepath.Join(dir, "seg_%d.ts"), filepath.Join(dir, VariantPlaylist))
+	}
+
+	n := len(st.ABRProfiles)
+	var filter strings.Builder
+	fmt.Fprintf(&filter, "[0:v]split=%d", n)
+	for i := range st.ABRProfiles {
+		fmt.Fprintf(&filter, "[v%d]", i)
+	}
+	for i, p := range st.ABRProfiles {
+		fmt.Fprintf(&filter, ";[v%d]scale=w=%d:h=%d[v%dout]", i, p.Width, p.Height, i)
+	}
+	args = append(args, "-filter_complex", filter.String())
+
+	varMap := make([]string, 0, n)
+	audio := 0
+	for i, p := range st.ABRProfiles {
+		args = append(args,
+			"-map", fmt.Sprintf("[v%dout]", i),
+			fmt.Sprintf("-c:v:%d", i), videoEncoders[p.Codec],
+			fmt.Sprintf("-profile:v:%d", i), p.Profile,
+			fmt.Sprintf("-b:v:%d", i), fmt.Sprintf("%dk", p.VideoBitrateKbps),
+			fmt.Sprintf("-maxrate:v:%d", i), fmt.Sprintf("%dk", p.VideoBitrateKbps*107/100),
+			fmt.Sprintf("-bufsize:v:%d", i), fmt.Sprintf("%dk", p.VideoBitrateKbps*3/2),
+			fmt.Sprintf("-force_key_frames:v:%d", i), fmt.Sprintf("expr:gte(t,n_forced*%d)", p.KeyframeIntervalSec),
+		)
+		entry := fmt.Sprintf("v:%d", i)
+		if p.AudioBitrateKbps > 0 {
+			args = append(args, "-map", "0:a:0",
+				fmt.Sprintf("-c:a:%d", audio), "aac",
+				fmt.Sprintf("-b:a:%d", audio), fmt.Sprintf("%dk", p.AudioBitrateKbps),
+			)
+			entry += fmt.Sprintf(",a:%d", audio)
+			audio++
+		}
+		varMap = append(varMap, entry+",name:"+p.Name)
+	}
+	args = append(args, "-sc_threshold", "0")
+	args = append(args, hls...)
+	return append(args,
+		"-hls_segment_filename", filepath.Join(outDir, "%v", "seg_%d.ts"),
+		"-var_stream_map", strings.Join(varMap, " "),
+		filepath.Join(outDir, "%v", VariantPlaylist),
+	)
+}
@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	MasterPlaylist  = "master.m3u8"
+	VariantPlaylist = "index.m3u8"
+
+	sourceBandwidthEstimate = 4_000_000
+)
+
+var h264CodecTags = map[string]string{
+	"baseline": "avc1.42e01e",
+	"main":     "avc1.4d401f",
+	"high":     "avc1.640028",
+}
+
+var hevcCodecTags = map[string]string{
+	"main":   "hvc1.1.6.L120.90",
+	"main10": "hvc1.2.4.L120.90",
+}
+
+func isPassthrough(profiles []model.ABRProfile) bool {
+	return len(profiles) == 0 || (len(profiles) == 1 && profiles[0].Codec == "copy")
+}
+
+func renditionNames(profiles []model.ABRProfile) []string {
+	if isPassthrough(profiles) {
+		return []string{"source"}
+	}
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func codecTags(p model.ABRProfile) string {
+	var video string
+	switch p.Codec {
+	case "h264":
+		video = h264CodecTags[p.Profile]
+	case "hevc":
+		video = hevcCodecTags[p.Profile]
+	}
+	if video == "" {
+		return ""
+	}
+	if p.AudioBitrateKbps > 0 {
+		return video + ",mp4a.40.2"
+	}
+	return video
+}
+
+func BuildMasterPlaylist(profiles []model.ABRProfile) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	if isPassthrough(profiles) {
+		bandwidth := sourceBandwidthEstimate
+		if len(profiles) == 1 && profiles[0].VideoBitrateKbps > 0 {
+			bandwidth = (profiles[0].VideoBitrateKbps + profiles[0].AudioBitrateKbps) * 1000
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,NAME=\"source\"\nsource/%s\n", bandwidth, VariantPlaylist)
+		return b.String()
+	}
+	for _, p := range profiles {
+		attrs := []string{
+			"BANDWIDTH=" + strconv.Itoa((p.VideoBitrateKbps+p.AudioBitrateKbps)*1000),
+			fmt.Sprintf("RESOLUTION=%dx%d", p.Width, p.Height),
+		}
+		if tags := codecTags(p); tags != "" {
+			attrs = append(attrs, fmt.Sprintf("CODECS=\"%s\"", tags))
+		}
+		attrs = append(attrs, fmt.Sprintf("NAME=\"%s\"", p.Name))
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:%s\n%s/%s\n", strings.Join(attrs, ","), p.Name, VariantPlaylist)
+	}
+	return b.String()
+}
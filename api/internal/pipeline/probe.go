@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const probeTimeout = 5 * time.Second
+
+var defaultPorts = map[string]string{"rtmp": "1935", "rtsp": "554", "http": "80", "https": "443"}
+
+func probeSource(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	switch u.Scheme {
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, raw, nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d", res.StatusCode)
+		}
+		if strings.HasSuffix(u.Path, ".m3u8") {
+			line, _ := bufio.NewReader(res.Body).ReadString('\n')
+			if !strings.HasPrefix(strings.TrimSpace(line), "#EXTM3U") {
+				return fmt.Errorf("not an HLS playlist")
+			}
+		}
+		return nil
+	case "rtmp", "rtmps", "rtsp":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), defaultPorts[strings.TrimSuffix(u.Scheme, "s")])
+		}
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "file", "":
+		_, err := os.Stat(u.Path)
+		return err
+	}
+	return fmt.Errorf("cannot probe %s sources", u.Scheme)
+}
@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"streamweb/api/internal/hls"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+)
+
+const recordingsPrefix = "recordings"
+
+type recorder struct {
+	store     segstore.SegmentStore
+	prefix    string
+	mu        sync.Mutex
+	durations map[string]float64
+}
+
+func newRecorder(store segstore.SegmentStore, recordingID string) *recorder {
+	return &recorder{store: store, prefix: recordingsPrefix + "/" + recordingID, durations: map[string]float64{}}
+}
+
+func (r *recorder) key(rel string) string { return r.prefix + "/" + rel }
+
+func (r *recorder) setDuration(rel string, d float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.durations[rel] = d
+}
+
+func (r *recorder) duration(rel string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.durations[rel]
+}
+
+func (r *recorder) finalize(ctx context.Context, st model.Stream) (recordingReport, error) {
+	rep := recordingReport{}
+	objects, err := r.store.List(ctx, r.prefix+"/")
+	if err != nil {
+		return rep, err
+	}
+	nominal := float64(st.SegmentDurationSec)
+	if nominal <= 0 {
+		nominal = defaultSegmentSec
+	}
+	byRendition := map[string][]hls.Segment{}
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, r.prefix+"/")
+		rendition, name, ok := strings.Cut(rel, "/")
+		if !ok || path.Ext(name) != ".ts" {
+			continue
+		}
+		seq, ok := hls.SegmentSeq(name)
+		if !ok {
+			continue
+		}
+		d := r.duration(rel)
+		if d <= 0 {
+			d = nominal
+		}
+		byRendition[rendition] = append(byRendition[rendition], hls.Segment{URI: name, Seq: seq, Duration: d})
+		rep.SizeBytes += obj.Size
+	}
+	for i, rendition := range renditionNames(st.ABRProfiles) {
+		segs := byRendition[rendition]
+		sort.Slice(segs, func(i, j int) bool { return segs[i].Seq < segs[j].Seq })
+		pl := hls.MediaPlaylist{Type: "VOD", Segments: segs, Ended: true}
+		if i == 0 {
+			rep.SegmentCount, rep.DurationSec = len(segs), pl.Duration()
+		}
+		body := pl.Render()
+		if err := r.store.Put(ctx, r.key(rendition+"/"+VariantPlaylist), strings.NewReader(body), int64(len(body)), segstore.ContentType(VariantPlaylist)); err != nil {
+			return rep, err
+		}
+	}
+	master := BuildMasterPlaylist(st.ABRProfiles)
+	err = r.store.Put(ctx, r.key(MasterPlaylist), strings.NewReader(master), int64(len(master)), segstore.ContentType(MasterPlaylist))
+	return rep, err
+}
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+)
+
+func writeRendition(t *testing.T, dir, playlist string, segments ...string) {
+	t.Helper()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "720p"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range segments {
+		if err := os.WriteFile(filepath.Join(dir, "720p", name), []byte("ts"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "720p", VariantPlaylist), []byte(playlist), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorderSurvivesFailoverRestart(t *testing.T) {
+	ctx := context.Background()
+	store, err := segstore.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorker(Config{Store: store, OutputDir: t.TempDir()})
+	st := model.Stream{ID: "s1", SegmentDurationSec: 4, ABRProfiles: []model.ABRProfile{{Name: "720p"}}}
+	dir := w.streamDir(st.ID)
+
+	first := w.recorder("rec_1")
+	writeRendition(t, dir, "#EXTM3U\n#EXTINF:2.500,\nseg_100.ts\n#EXTINF:1.500,\nseg_101.ts\n", "seg_100.ts", "seg_101.ts")
+	up := newUploader(store, dir, st.ID)
+	up.recorder = first
+	if err := up.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	second := w.recorder("rec_1")
+	if second != first {
+		t.Fatal("reopening the same recording started a fresh recorder")
+	}
+	writeRendition(t, dir, "#EXTM3U\n#EXTINF:3.200,\nseg_200.ts\n", "seg_200.ts")
+	up = newUploader(store, dir, st.ID)
+	up.recorder = second
+	if err := up.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := second.finalize(ctx, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.SegmentCount != 3 || rep.DurationSec < 7.19 || rep.DurationSec > 7.21 {
+		t.Errorf("finalize = %d segments, %.3fs; want 3 segments, 7.2s", rep.SegmentCount, rep.DurationSec)
+	}
+	if w.recorder("rec_2") == first {
+		t.Error("a different recording reused the recorder")
+	}
+}
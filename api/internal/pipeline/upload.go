@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"streamweb/api/internal/hls"
+	"streamweb/api/internal/segstore"
+)
+
+const uploadInterval = time.Second
+
+type uploader struct {
+	store    segstore.SegmentStore
+	localDir string
+	streamID string
+	uploaded map[string]time.Time
+	recorder *recorder
+}
+
+func newUploader(store segstore.SegmentStore, localDir, streamID string) *uploader {
+	return &uploader{store: store, localDir: localDir, streamID: streamID, uploaded: map[string]time.Time{}}
+}
+
+func (u *uploader) sync(ctx context.Context) error {
+	segments, _ := filepath.Glob(filepath.Join(u.localDir, "*", "*.ts"))
+	variants, _ := filepath.Glob(filepath.Join(u.localDir, "*", VariantPlaylist))
+	playlists := append(variants, filepath.Join(u.localDir, MasterPlaylist))
+
+	if u.recorder != nil {
+		for _, pl := range variants {
+			rendition := filepath.Base(filepath.Dir(pl))
+			f, err := os.Open(pl)
+			if err != nil {
+				continue
+			}
+			for name, d := range hls.ParseDurations(f) {
+				u.recorder.setDuration(rendition+"/"+name, d)
+			}
+			f.Close()
+		}
+	}
+
+	seen := make(map[string]bool, len(segments)+len(playlists))
+	for _, file := range append(segments, playlists...) {
+		rel, err := filepath.Rel(u.localDir, file)
+		if err != nil {
+			continue
+		}
+		seen[rel] = true
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		last, uploaded := u.uploaded[rel]
+		if uploaded && !fi.ModTime().After(last) {
+			continue
+		}
+		key := filepath.ToSlash(rel)
+		if err := u.put(ctx, file, u.streamID+"/"+key, fi.Size()); err != nil {
+			return err
+		}
+		if u.recorder != nil && !uploaded && filepath.Ext(file) == ".ts" {
+			if err := u.put(ctx, file, u.recorder.key(key), fi.Size()); err != nil {
+				return err
+			}
+		}
+		u.uploaded[rel] = fi.ModTime()
+	}
+	for rel := range u.uploaded {
+		if !seen[rel] {
+			delete(u.uploaded, rel)
+		}
+	}
+	return nil
+}
+
+func (u *uploader) put(ctx context.Context, file, key string, size int64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return u.store.Put(ctx, key, f, size, segstore.ContentType(key))
+}
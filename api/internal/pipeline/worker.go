@@ -0,0 +1,501 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"streamweb/api/internal/ingest"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+)
+
+const stopGracePeriod = 5 * time.Second
+
+type Config struct {
+	WorkerID          string
+	APIBase           string
+	APIToken          string
+	OutputDir         string
+	FFmpegPath        string
+	StreamIDs         []string
+	HeartbeatInterval time.Duration
+	Store             segstore.SegmentStore
+	RTMPAddr          string
+}
+
+type Worker struct {
+	cfg       Config
+	api       *controlClient
+	mu        sync.Mutex
+	procs     map[string]*process
+	errs      map[string]string
+	desired   map[string]desiredStream
+	unknown   []string
+	waiting   map[string]bool
+	sources   map[string]*sourceState
+	recorders map[string]*recorder
+	launchMu  sync.Mutex
+}
+
+type process struct {
+	stream     model.Stream
+	cmd        *exec.Cmd
+	done       chan struct{}
+	bitrate    atomic.Int64
+	stderr     *lastLine
+	recorder   *recorder
+	recordID   string
+	publisher  *ingest.Publisher
+	finalize   atomic.Bool
+	final      sync.Once
+	generation int
+}
+
+func NewWorker(cfg Config) *Worker {
+	if cfg.FFmpegPath == "" {
+		cfg.FFmpegPath = "ffmpeg"
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 10 * time.Second
+	}
+	return &Worker{cfg: cfg, api: newControlClient(cfg.APIBase, cfg.APIToken), procs: map[string]*process{}, errs: map[string]string{}, desired: map[string]desiredStream{}, waiting: map[string]bool{}, sources: map[string]*sourceState{}, recorders: map[string]*recorder{}}
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	defer w.stopAll()
+	if w.cfg.RTMPAddr != "" {
+		srv := &ingest.RTMPServer{Addr: w.cfg.RTMPAddr, Publish: w.publish}
+		go func() {
+			if err := srv.ListenAndServe(ctx); err != nil {
+				log.Printf("worker %s: rtmp ingest: %v", w.cfg.WorkerID, err)
+			}
+		}()
+	}
+	go w.watchSources(ctx)
+	for {
+		w.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) tick(ctx context.Context) {
+	reports := make([]model.StreamRuntime, 0, len(w.cfg.StreamIDs))
+	for _, id := range w.cfg.StreamIDs {
+		reports = append(reports, w.report(id))
+	}
+	desired, unknown, err := w.api.heartbeat(ctx, w.cfg.WorkerID, reports)
+	if err != nil {
+		log.Printf("worker %s: heartbeat failed: %v", w.cfg.WorkerID, err)
+		return
+	}
+	if !slices.Equal(unknown, w.unknown) && len(unknown) > 0 {
+		log.Printf("worker %s: control plane does not know streams %s, keeping them stopped", w.cfg.WorkerID, strings.Join(unknown, ", "))
+	}
+	w.unknown = unknown
+	w.mu.Lock()
+	w.desired = desired
+	w.mu.Unlock()
+	for _, id := range w.cfg.StreamIDs {
+		if desired[id].State == "running" {
+			if w.running(id) && w.generation(id) != desired[id].Generation {
+				log.Printf("worker %s: config of stream %s changed (generation %d), restarting", w.cfg.WorkerID, id, desired[id].Generation)
+				w.mu.Lock()
+				delete(w.sources, id)
+				w.mu.Unlock()
+				w.stop(id, true)
+			}
+			if !w.running(id) {
+				w.start(ctx, id)
+			} else {
+				w.checkPublisher(ctx, id)
+			}
+			continue
+		}
+		w.mu.Lock()
+		delete(w.waiting, id)
+		delete(w.sources, id)
+		w.mu.Unlock()
+		w.stop(id, true)
+	}
+}
+
+func (w *Worker) streamDir(id string) string { return filepath.Join(w.cfg.OutputDir, id) }
+
+func (w *Worker) running(id string) bool {
+	w.mu.Lock()
+	p := w.procs[id]
+	w.mu.Unlock()
+	if p == nil {
+		return false
+	}
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (w *Worker) generation(id string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if p := w.procs[id]; p != nil {
+		return p.generation
+	}
+	return 0
+}
+
+func (w *Worker) report(id string) model.StreamRuntime {
+	rt := model.StreamRuntime{StreamID: id, ActualState: "stopped"}
+	w.mu.Lock()
+	p := w.procs[id]
+	rt.LastError = w.errs[id]
+	waiting := w.waiting[id]
+	if ss := w.sources[id]; ss != nil {
+		rt.ActiveSource = ss.index
+	}
+	w.mu.Unlock()
+	if p != nil && w.running(id) {
+		rt.ActualState = "running"
+		rt.IngestBitrateKbps = int(p.bitrate.Load())
+	} else if rt.LastError != "" {
+		rt.ActualState = "failed"
+	} else if waiting {
+		rt.ActualState = "waiting"
+	}
+	rt.LastManifestAt, rt.SegmentCount = scanOutput(w.streamDir(id))
+	return rt
+}
+
+func scanOutput(dir string) (time.Time, int64) {
+	var lastManifest time.Time
+	playlists, _ := filepath.Glob(filepath.Join(dir, "*", VariantPlaylist))
+	for _, pl := range playlists {
+		if fi, err := os.Stat(pl); err == nil && fi.ModTime().After(lastManifest) {
+			lastManifest = fi.ModTime().UTC()
+		}
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "*", "*.ts"))
+	return lastManifest, int64(len(segs))
+}
+
+func (w *Worker) start(ctx context.Context, id string) {
+	w.launchMu.Lock()
+	defer w.launchMu.Unlock()
+	if w.running(id) {
+		return
+	}
+	w.mu.Lock()
+	generation := w.desired[id].Generation
+	w.mu.Unlock()
+	st, err := w.api.stream(ctx, id)
+	if err != nil {
+		w.setError(id, fmt.Sprintf("fetch config: %v", err))
+		return
+	}
+	if st.IngestMode == "push" {
+		w.mu.Lock()
+		w.waiting[id] = true
+		w.mu.Unlock()
+		return
+	}
+	sources := ingestSources(st)
+	if len(sources) == 0 {
+		w.setError(id, "ingest_url empty")
+		return
+	}
+	w.mu.Lock()
+	ss := w.sources[id]
+	if ss == nil {
+		ss = &sourceState{since: time.Now()}
+		w.sources[id] = ss
+	}
+	if ss.index >= len(sources) {
+		ss.index = 0
+	}
+	ss.stream, ss.generation = st, generation
+	st.IngestURL = sources[ss.index]
+	w.mu.Unlock()
+	if _, err := w.launch(ctx, st, nil, generation); err != nil {
+		w.setError(id, err.Error())
+	}
+}
+
+func (w *Worker) publish(ctx context.Context, pub *ingest.Publisher) (io.WriteCloser, error) {
+	id, err := w.api.authorizeIngest(ctx, pub.Key, pub.Remote.String())
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(w.cfg.StreamIDs, id) {
+		return nil, fmt.Errorf("stream %s is not served by this worker", id)
+	}
+	w.launchMu.Lock()
+	defer w.launchMu.Unlock()
+	w.mu.Lock()
+	desired := w.desired[id]
+	w.mu.Unlock()
+	if desired.State != "running" {
+		return nil, fmt.Errorf("stream %s is not running", id)
+	}
+	if w.running(id) {
+		return nil, fmt.Errorf("stream %s already has a publisher", id)
+	}
+	st, err := w.api.stream(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetch config: %w", err)
+	}
+	if st.IngestMode != "push" {
+		return nil, fmt.Errorf("stream %s does not accept push ingest", id)
+	}
+	stdin, err := w.launch(ctx, st, pub, desired.Generation)
+	if err != nil {
+		w.setError(id, err.Error())
+		return nil, err
+	}
+	return stdin, nil
+}
+
+func (w *Worker) checkPublisher(ctx context.Context, id string) {
+	w.mu.Lock()
+	p := w.procs[id]
+	w.mu.Unlock()
+	if p == nil || p.publisher == nil {
+		return
+	}
+	current, err := w.api.authorizeIngest(ctx, p.publisher.Key, p.publisher.Remote.String())
+	var se *statusError
+	if (errors.As(err, &se) && se.code == "invalid_stream_key") || (err == nil && current != id) {
+		log.Printf("worker %s: stream key for %s no longer valid, dropping publisher %s", w.cfg.WorkerID, id, p.publisher.Remote)
+		w.stop(id, true)
+	}
+}
+
+func (w *Worker) launch(ctx context.Context, st model.Stream, pub *ingest.Publisher, generation int) (io.WriteCloser, error) {
+	id := st.ID
+	dir := w.streamDir(id)
+	for _, name := range renditionNames(st.ABRProfiles) {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeFileAtomic(filepath.Join(dir, MasterPlaylist), []byte(BuildMasterPlaylist(st.ABRProfiles))); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(w.cfg.FFmpegPath, FFmpegArgs(st, dir)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stdin io.WriteCloser
+	if pub != nil {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return nil, err
+		}
+	}
+	p := &process{stream: st, cmd: cmd, done: make(chan struct{}), stderr: &lastLine{}, publisher: pub, generation: generation}
+	p.finalize.Store(pub != nil)
+	cmd.Stderr = p.stderr
+	if st.RecordingEnabled && w.cfg.Store != nil {
+		rec, err := w.api.openRecording(ctx, id)
+		if err != nil {
+			w.setError(id, fmt.Sprintf("open recording: %v", err))
+		} else {
+			p.recorder, p.recordID = w.recorder(rec.ID), rec.ID
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start ffmpeg: %v", err)
+	}
+	w.mu.Lock()
+	w.procs[id] = p
+	delete(w.errs, id)
+	delete(w.waiting, id)
+	w.mu.Unlock()
+	log.Printf("worker %s: started stream %s (pid %d, %d renditions)", w.cfg.WorkerID, id, cmd.Process.Pid, len(renditionNames(st.ABRProfiles)))
+	go p.readProgress(stdout)
+	if w.cfg.Store != nil {
+		go w.syncLoop(id, p)
+	}
+	go func() {
+		err := cmd.Wait()
+		if err != nil {
+			msg := err.Error()
+			if line := p.stderr.String(); line != "" {
+				msg += ": " + line
+			}
+			w.setError(id, msg)
+		}
+		if pub != nil {
+			pub.Close()
+		}
+		close(p.done)
+	}()
+	return stdin, nil
+}
+
+func (w *Worker) syncLoop(id string, p *process) {
+	up := newUploader(w.cfg.Store, w.streamDir(id), id)
+	up.recorder = p.recorder
+	ticker := time.NewTicker(uploadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			if err := up.sync(context.Background()); err != nil {
+				w.setError(id, "upload: "+err.Error())
+			}
+			if p.recorder != nil && p.finalize.Load() {
+				w.finalizeRecording(id, p)
+			}
+			return
+		case <-ticker.C:
+			if err := up.sync(context.Background()); err != nil {
+				w.setError(id, "upload: "+err.Error())
+			}
+		}
+	}
+}
+
+func (w *Worker) recorder(recordingID string) *recorder {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r := w.recorders[recordingID]
+	if r == nil {
+		r = newRecorder(w.cfg.Store, recordingID)
+		w.recorders[recordingID] = r
+	}
+	return r
+}
+
+func (w *Worker) finalizeRecording(id string, p *process) {
+	p.final.Do(func() { w.doFinalizeRecording(id, p) })
+}
+
+func (w *Worker) doFinalizeRecording(id string, p *process) {
+	ctx := context.Background()
+	rep, err := p.recorder.finalize(ctx, p.stream)
+	if err != nil {
+		w.setError(id, "finalize recording: "+err.Error())
+		rep.Failed = true
+	}
+	if err := w.api.finalizeRecording(ctx, p.recordID, rep); err != nil {
+		w.setError(id, "finalize recording: "+err.Error())
+		return
+	}
+	w.mu.Lock()
+	delete(w.recorders, p.recordID)
+	w.mu.Unlock()
+	log.Printf("worker %s: finalized recording %s for stream %s (%d segments, %.0fs)", w.cfg.WorkerID, p.recordID, id, rep.SegmentCount, rep.DurationSec)
+}
+
+func (w *Worker) stop(id string, finalize bool) {
+	w.mu.Lock()
+	p := w.procs[id]
+	delete(w.procs, id)
+	w.mu.Unlock()
+	if p == nil {
+		return
+	}
+	p.finalize.Store(finalize)
+	select {
+	case <-p.done:
+		if finalize && p.recorder != nil {
+			go w.finalizeRecording(id, p)
+		}
+		return
+	default:
+	}
+	if p.publisher != nil {
+		p.publisher.Close()
+	}
+	_ = syscall.Kill(-p.cmd.Process.Pid, syscall.SIGINT)
+	select {
+	case <-p.done:
+	case <-time.After(stopGracePeriod):
+		_ = syscall.Kill(-p.cmd.Process.Pid, syscall.SIGKILL)
+		<-p.done
+	}
+	w.mu.Lock()
+	delete(w.errs, id)
+	w.mu.Unlock()
+	log.Printf("worker %s: stopped stream %s", w.cfg.WorkerID, id)
+}
+
+func (w *Worker) stopAll() {
+	for _, id := range w.cfg.StreamIDs {
+		w.stop(id, false)
+	}
+}
+
+func (w *Worker) setError(id, msg string) {
+	log.Printf("worker %s: stream %s: %s", w.cfg.WorkerID, id, msg)
+	w.mu.Lock()
+	w.errs[id] = msg
+	w.mu.Unlock()
+}
+
+func (p *process) readProgress(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		v, ok := strings.CutPrefix(sc.Text(), "bitrate=")
+		if !ok {
+			continue
+		}
+		kbps, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(v, "kbits/s")), 64)
+		if err == nil {
+			p.bitrate.Store(int64(kbps))
+		}
+	}
+}
+
+type lastLine struct {
+	mu   sync.Mutex
+	line string
+}
+
+func (l *lastLine) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			l.line = line
+		}
+	}
+	return len(b), nil
+}
+
+func (l *lastLine) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.line
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,50 @@
+package segstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFSStore(t *testing.T) {
+	s, err := NewFSStore(filepath.Join(t.TempDir(), "segments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
+
+func TestFSStoreLayout(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewFSStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.Put(ctx, "s1/720p/seg_00001.ts", strings.NewReader("data"), 4, ""); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(root, "s1", "720p", "seg_00001.ts"))
+	if err != nil || string(b) != "data" {
+		t.Fatalf("file on disk = %q, %v", b, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "s1", "720p", ".put-123"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.List(ctx, "s1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Key != "s1/720p/seg_00001.ts" {
+		t.Errorf("List(s1/) = %+v, want only the finished segment", list)
+	}
+	if _, err := s.List(ctx, "s1/../../etc/"); err == nil {
+		t.Error("List with .. in the prefix succeeded")
+	}
+	if _, err := s.Stat(ctx, "s1/720p"); err != ErrNotFound {
+		t.Errorf("Stat(directory) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,334 @@
+package segstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+type fakeS3 struct {
+	t        *testing.T
+	bucket   string
+	region   string
+	pageSize int
+
+	mu       sync.Mutex
+	objects  map[string]fakeObject
+	requests []string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, bucket: "streams", region: "eu-test-1", pageSize: 3, objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func newTestS3Store(t *testing.T, srv *httptest.Server, secret string) *S3Store {
+	s, err := NewS3Store(S3Config{Endpoint: srv.URL, Region: "eu-test-1", Bucket: "streams", AccessKey: testAccessKey, SecretKey: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RawQuery)
+	if msg := f.verify(r); msg != "" {
+		f.t.Logf("fake s3: %s %s: %s", r.Method, r.URL, msg)
+		writeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", msg)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket", "no such bucket")
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query())
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody", "body does not match Content-Length")
+			return
+		}
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now().UTC().Truncate(time.Second)}
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, q url.Values) {
+	if q.Get("list-type") != "2" {
+		writeS3Error(w, http.StatusBadRequest, "InvalidArgument", "list-type=2 required")
+		return
+	}
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, q.Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	start := 0
+	if token := q.Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(strings.TrimPrefix(token, "page-"))
+	}
+	type content struct {
+		Key          string
+		Size         int
+		LastModified string
+	}
+	var res struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+		Contents              []content
+	}
+	end := min(start+f.pageSize, len(keys))
+	for _, key := range keys[start:end] {
+		obj := f.objects[key]
+		res.Contents = append(res.Contents, content{Key: key, Size: len(obj.data), LastModified: obj.modTime.Format(time.RFC3339)})
+	}
+	if end < len(keys) {
+		res.IsTruncated, res.NextContinuationToken = true, "page-"+strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(res)
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, msg)
+}
+
+func (f *fakeS3) verify(r *http.Request) string {
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return "missing AWS4-HMAC-SHA256 authorization"
+	}
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ", ") {
+		k, v, _ := strings.Cut(part, "=")
+		fields[k] = v
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	date, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || time.Since(date).Abs() > 15*time.Minute {
+		return "bad X-Amz-Date " + amzDate
+	}
+	scope := amzDate[:8] + "/" + f.region + "/s3/aws4_request"
+	if fields["Credential"] != testAccessKey+"/"+scope {
+		return "bad credential scope " + fields["Credential"]
+	}
+	if r.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		return "bad X-Amz-Content-Sha256"
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	for _, required := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !slices.Contains(signed, required) {
+			return "unsigned header " + required
+		}
+	}
+	if r.Header.Get("Content-Type") != "" && !slices.Contains(signed, "content-type") {
+		return "unsigned header content-type"
+	}
+	var headers strings.Builder
+	for _, name := range signed {
+		v := r.Header.Get(name)
+		if name == "host" {
+			v = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(v) + "\n")
+	}
+	query := r.URL.Query()
+	var params []string
+	for k, vs := range query {
+		for _, v := range vs {
+			params = append(params, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	slices.Sort(params)
+	canonical := strings.Join([]string{r.Method, r.URL.EscapedPath(), strings.Join(params, "&"), headers.String(), fields["SignedHeaders"], "UNSIGNED-PAYLOAD"}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{amzDate[:8], f.region, "s3", "aws4_request"} {
+		key = mac(key, part)
+	}
+	if want := hex.EncodeToString(mac(key, toSign)); !hmac.Equal([]byte(fields["Signature"]), []byte(want)) {
+		return "signature mismatch for canonical request:\n" + canonical
+	}
+	return ""
+}
+
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func TestS3Store(t *testing.T) {
+	_, srv := newFakeS3(t)
+	testStore(t, newTestS3Store(t, srv, testSecretKey))
+}
+
+func TestS3StoreListPaginates(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv, testSecretKey)
+	ctx := context.Background()
+	var want []string
+	for i := range 8 {
+		key := fmt.Sprintf("s1/720p/seg_%05d.ts", i)
+		want = append(want, key)
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.requests = nil
+	list, err := s.List(ctx, "s1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range list {
+		got = append(got, o.Key)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("List = %q, want %q", got, want)
+	}
+	var pages int
+	for _, req := range f.requests {
+		if strings.HasPrefix(req, "GET ") && strings.Contains(req, "list-type=2") {
+			pages++
+		}
+	}
+	if pages != 3 {
+		t.Errorf("List made %d list requests, want 3 pages of 3", pages)
+	}
+	if !slices.ContainsFunc(f.requests, func(req string) bool { return strings.Contains(req, "continuation-token=page-6") }) {
+		t.Errorf("List did not follow the continuation token: %q", f.requests)
+	}
+}
+
+func TestS3StoreContentType(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv, testSecretKey)
+	ctx := context.Background()
+	if err := s.Put(ctx, "s1/720p/index.m3u8", strings.NewReader("#EXTM3U\n"), 8, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "clips/c1/clip.bin", strings.NewReader("x"), 1, "video/mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if ct := f.objects["s1/720p/index.m3u8"].contentType; ct != "application/vnd.apple.mpegurl" {
+		t.Errorf("playlist stored as %q", ct)
+	}
+	if ct := f.objects["clips/c1/clip.bin"].contentType; ct != "video/mp4" {
+		t.Errorf("explicit content type stored as %q", ct)
+	}
+}
+
+func TestS3StoreNotFound(t *testing.T) {
+	_, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv, testSecretKey)
+	ctx := context.Background()
+	if _, _, err := s.Get(ctx, "s1/missing.ts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Stat(ctx, "s1/missing.ts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreSignatureRejected(t *testing.T) {
+	_, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv, "not-the-secret")
+	err := s.Put(context.Background(), "s1/720p/seg.ts", strings.NewReader("x"), 1, "")
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Put with a wrong secret = %v, want a signature error", err)
+	}
+	if !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("error %q lacks the status and S3 error code", err)
+	}
+}
+
+func TestS3StoreSignedHeaders(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(context.Background())
+	}))
+	defer srv.Close()
+	s := newTestS3Store(t, srv, testSecretKey)
+	if err := s.Put(context.Background(), "rec/r 1/seg.ts", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.EscapedPath() != "/streams/rec/r%201/seg.ts" {
+		t.Errorf("path = %q, want the key percent-encoded under the bucket", got.URL.EscapedPath())
+	}
+	if got.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		t.Errorf("X-Amz-Content-Sha256 = %q", got.Header.Get("X-Amz-Content-Sha256"))
+	}
+	if _, err := time.Parse("20060102T150405Z", got.Header.Get("X-Amz-Date")); err != nil {
+		t.Errorf("X-Amz-Date = %q", got.Header.Get("X-Amz-Date"))
+	}
+	auth := got.Header.Get("Authorization")
+	for _, want := range []string{
+		"AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/",
+		"/eu-test-1/s3/aws4_request, ",
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, ",
+		"Signature=",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Errorf("Authorization %q lacks %q", auth, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package segstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("segment not found")
+	ErrInvalidKey = errors.New("invalid segment key")
+)
+
+type ObjectInfo struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+type SegmentStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+		}
+	}
+	return path.Clean(key), nil
+}
+
+func ContentType(key string) string {
+	switch path.Ext(key) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s", ".mp4":
+		return "video/mp4"
+	case ".aac":
+		return "audio/aac"
+	case ".vtt":
+		return "text/vtt"
+	}
+	return "application/octet-stream"
+}
+
+type Config struct {
+	Backend string
+	Dir     string
+	S3      S3Config
+}
+
+func Open(cfg Config) (SegmentStore, error) {
+	switch cfg.Backend {
+	case "", "fs":
+		if cfg.Dir == "" {
+			cfg.Dir = "./data/streams"
+		}
+		return NewFSStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+func FromEnv() (SegmentStore, error) {
+	return Open(Config{
+		Backend: os.Getenv("STREAMWEB_STORAGE"),
+		Dir:     os.Getenv("STREAMWEB_STORAGE_DIR"),
+		S3: S3Config{
+			Endpoint:  os.Getenv("STREAMWEB_S3_ENDPOINT"),
+			Region:    os.Getenv("STREAMWEB_S3_REGION"),
+			Bucket:    os.Getenv("STREAMWEB_S3_BUCKET"),
+			AccessKey: os.Getenv("STREAMWEB_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("STREAMWEB_S3_SECRET_KEY"),
+		},
+	})
+}
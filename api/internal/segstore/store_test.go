@@ -0,0 +1,173 @@
+package segstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testStore(t *testing.T, s SegmentStore) {
+	t.Helper()
+	ctx := context.Background()
+	objects := map[string]string{
+		"s1/720p/index.m3u8":     "#EXTM3U\n",
+		"s1/720p/seg_00001.ts":   "segment one",
+		"s1/720p/seg_00002.ts":   strings.Repeat("x", 64<<10),
+		"s1/720p/empty.ts":       "",
+		"s1/720p/sub/nested.ts":  "nested",
+		"s1/480p/seg_00001.ts":   "low",
+		"s10/720p/seg_00001.ts":  "prefix sibling",
+		"rec/r 1/seg+00001 é.ts": "needs escaping",
+	}
+	for key, body := range objects {
+		if err := s.Put(ctx, key, strings.NewReader(body), int64(len(body)), ""); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	if err := s.Put(ctx, "s1/unknown-size.ts", strings.NewReader("streamed"), -1, ""); err != nil {
+		t.Fatalf("Put with unknown size: %v", err)
+	}
+	objects["s1/unknown-size.ts"] = "streamed"
+
+	for key, want := range objects {
+		got, info := get(t, s, key)
+		if got != want {
+			t.Errorf("Get(%q) = %q, want %q", key, got, want)
+		}
+		if info.Key != key || info.Size != int64(len(want)) || info.ModTime.IsZero() {
+			t.Errorf("Get(%q) info = %+v", key, info)
+		}
+		st, err := s.Stat(ctx, key)
+		if err != nil {
+			t.Fatalf("Stat(%q): %v", key, err)
+		}
+		if st.Key != key || st.Size != int64(len(want)) || st.ModTime.IsZero() {
+			t.Errorf("Stat(%q) = %+v", key, st)
+		}
+	}
+
+	list, err := s.List(ctx, "s1/720p/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var keys []string
+	for _, o := range list {
+		keys = append(keys, o.Key)
+		if o.Size != int64(len(objects[o.Key])) {
+			t.Errorf("List size of %q = %d, want %d", o.Key, o.Size, len(objects[o.Key]))
+		}
+	}
+	var want []string
+	for key := range objects {
+		if strings.HasPrefix(key, "s1/720p/") {
+			want = append(want, key)
+		}
+	}
+	slices.Sort(keys)
+	slices.Sort(want)
+	if !slices.Equal(keys, want) {
+		t.Errorf("List(s1/720p/) = %q, want %q", keys, want)
+	}
+	if list, err := s.List(ctx, "s1/"); err != nil || slices.ContainsFunc(list, func(o ObjectInfo) bool { return strings.HasPrefix(o.Key, "s10/") }) {
+		t.Errorf("List(s1/) = %v, %v; must not include s10/", list, err)
+	}
+	if list, err := s.List(ctx, "missing/"); err != nil || len(list) != 0 {
+		t.Errorf("List(missing/) = %v, %v; want empty", list, err)
+	}
+
+	if err := s.Put(ctx, "s1/720p/seg_00001.ts", bytes.NewReader([]byte("replaced")), 8, ""); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if got, _ := get(t, s, "s1/720p/seg_00001.ts"); got != "replaced" {
+		t.Errorf("Get after overwrite = %q", got)
+	}
+
+	if err := s.Delete(ctx, "s1/720p/seg_00001.ts"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "s1/720p/seg_00001.ts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Stat(ctx, "s1/720p/seg_00001.ts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "s1/720p/seg_00001.ts"); err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+	if _, err := s.Stat(ctx, "never/written.ts"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat(missing) = %v, want ErrNotFound", err)
+	}
+
+	for _, key := range []string{"", "/abs.ts", "a//b.ts", "a/../b.ts", "./a.ts", "a/.."} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, ""); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := s.Stat(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Stat(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func get(t *testing.T, s SegmentStore, key string) (string, ObjectInfo) {
+	t.Helper()
+	rc, info, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Get(%q) read: %v", key, err)
+	}
+	return string(b), info
+}
+
+func TestContentType(t *testing.T) {
+	for key, want := range map[string]string{
+		"s/720p/index.m3u8": "application/vnd.apple.mpegurl",
+		"s/720p/seg.ts":     "video/mp2t",
+		"s/720p/seg.m4s":    "video/mp4",
+		"clips/c/clip.mp4":  "video/mp4",
+		"s/audio/seg.aac":   "audio/aac",
+		"s/subs/en.vtt":     "text/vtt",
+		"s/thumb.jpg":       "application/octet-stream",
+	} {
+		if got := ContentType(key); got != want {
+			t.Errorf("ContentType(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(Config{Backend: "fs", Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*FSStore); !ok {
+		t.Errorf("Open(fs) = %T, want *FSStore", s)
+	}
+	s, err = Open(Config{Backend: "s3", S3: S3Config{Endpoint: "http://127.0.0.1:9000", Bucket: "streams"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*S3Store); !ok {
+		t.Errorf("Open(s3) = %T, want *S3Store", s)
+	}
+	if _, err := Open(Config{Backend: "s3"}); err == nil {
+		t.Error("Open(s3) without endpoint and bucket succeeded")
+	}
+	if _, err := Open(Config{Backend: "gcs"}); err == nil {
+		t.Error("Open(gcs) succeeded")
+	}
+}
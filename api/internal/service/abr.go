@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"streamweb/api/internal/model"
+)
+
+const defaultABRPreset = "source-only"
+
+var abrPresets = map[string][]model.ABRProfile{
+	"source-only": {
+		{Name: "source", Codec: "copy"},
+	},
+	"sd": {
+		{Name: "480p", Width: 854, Height: 480, VideoBitrateKbps: 1400, AudioBitrateKbps: 128, Codec: "h264", Profile: "main", KeyframeIntervalSec: 2},
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96, Codec: "h264", Profile: "main", KeyframeIntervalSec: 2},
+		{Name: "240p", Width: 426, Height: 240, VideoBitrateKbps: 400, AudioBitrateKbps: 64, Codec: "h264", Profile: "baseline", KeyframeIntervalSec: 2},
+	},
+	"hd": {
+		{Name: "1080p", Width: 1920, Height: 1080, VideoBitrateKbps: 5000, AudioBitrateKbps: 192, Codec: "h264", Profile: "high", KeyframeIntervalSec: 2},
+		{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2800, AudioBitrateKbps: 128, Codec: "h264", Profile: "high", KeyframeIntervalSec: 2},
+		{Name: "480p", Width: 854, Height: 480, VideoBitrateKbps: 1400, AudioBitrateKbps: 128, Codec: "h264", Profile: "main", KeyframeIntervalSec: 2},
+		{Name: "360p", Width: 640, Height: 360, VideoBitrateKbps: 800, AudioBitrateKbps: 96, Codec: "h264", Profile: "main", KeyframeIntervalSec: 2},
+	},
+}
+
+var codecProfiles = map[string][]string{
+	"h264": {"baseline", "main", "high"},
+	"hevc": {"main", "main10"},
+}
+
+var renditionName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+var defaultCodecProfile = map[string]string{"h264": "high", "hevc": "main"}
+
+func (s *Service) ABRPresets() map[string][]model.ABRProfile {
+	out := make(map[string][]model.ABRProfile, len(abrPresets))
+	for name, ladder := range abrPresets {
+		out[name] = append([]model.ABRProfile(nil), ladder...)
+	}
+	return out
+}
+
+func resolveABR(preset string, profiles []model.ABRProfile) ([]model.ABRProfile, error) {
+	if preset == "" && len(profiles) == 0 {
+		preset = defaultABRPreset
+	}
+	if preset != "" {
+		if len(profiles) > 0 {
+			return nil, fmt.Errorf("abr_preset and abr_profiles are mutually exclusive")
+		}
+		ladder, ok := abrPresets[preset]
+		if !ok {
+			names := make([]string, 0, len(abrPresets))
+			for name := range abrPresets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown abr_preset %q (available: %v)", preset, names)
+		}
+		return append([]model.ABRProfile(nil), ladder...), nil
+	}
+	if err := validateABRProfiles(profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func validateABRProfiles(profiles []model.ABRProfile) error {
+	seen := map[string]bool{}
+	for i := range profiles {
+		p := &profiles[i]
+		if p.Codec == "copy" {
+			if len(profiles) != 1 {
+				return fmt.Errorf("abr_profiles[%d]: codec copy must be the only rendition", i)
+			}
+			if p.Name == "" {
+				p.Name = "source"
+			}
+			return nil
+		}
+		allowed, ok := codecProfiles[p.Codec]
+		if !ok {
+			return fmt.Errorf("abr_profiles[%d]: codec must be one of h264, hevc, copy", i)
+		}
+		if p.Profile == "" {
+			p.Profile = defaultCodecProfile[p.Codec]
+		}
+		if !slices.Contains(allowed, p.Profile) {
+			return fmt.Errorf("abr_profiles[%d]: profile %q not valid for %s", i, p.Profile, p.Codec)
+		}
+		if p.Width <= 0 || p.Height <= 0 || p.Width%2 != 0 || p.Height%2 != 0 {
+			return fmt.Errorf("abr_profiles[%d]: width and height must be positive even numbers", i)
+		}
+		if p.VideoBitrateKbps <= 0 {
+			return fmt.Errorf("abr_profiles[%d]: video_bitrate_kbps must be positive", i)
+		}
+		if p.AudioBitrateKbps < 0 {
+			return fmt.Errorf("abr_profiles[%d]: audio_bitrate_kbps must not be negative", i)
+		}
+		if p.KeyframeIntervalSec == 0 {
+			p.KeyframeIntervalSec = 2
+		}
+		if p.KeyframeIntervalSec < 1 || p.KeyframeIntervalSec > 10 {
+			return fmt.Errorf("abr_profiles[%d]: keyframe_interval_sec must be between 1 and 10", i)
+		}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("%dp", p.Height)
+		}
+		if !renditionName.MatchString(p.Name) {
+			return fmt.Errorf("abr_profiles[%d]: name must match %s", i, renditionName)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("abr_profiles[%d]: duplicate rendition name %q", i, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
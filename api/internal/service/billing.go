@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	BillingModeHeartbeat = "heartbeat"
+	BillingModeSegments  = "segments"
+	BillingInterval      = 10 * time.Second
+	segmentIdleTimeout   = 30 * time.Second
+)
+
+type segmentActivity struct {
+	mu      sync.Mutex
+	last    map[string]time.Time
+	pending map[string]bool
+	charges atomic.Int64
+	expired atomic.Int64
+}
+
+func validateBillingMode(mode string) error {
+	if mode != BillingModeHeartbeat && mode != BillingModeSegments {
+		return fmt.Errorf("billing_mode must be heartbeat or segments")
+	}
+	return nil
+}
+
+func (s *Service) billingMode(requested, source string) (string, error) {
+	if source == SessionSourceLauncher {
+		return BillingModeSegments, nil
+	}
+	mode := firstNonEmpty(requested, s.options().DefaultBillingMode, BillingModeHeartbeat)
+	return mode, validateBillingMode(mode)
+}
+
+func (s *Service) RecordSegmentFetch(sessionID string, at time.Time) {
+	ss, ok := s.repo.GetSession(sessionID)
+	if !ok || ss.BillingMode != BillingModeSegments {
+		return
+	}
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	s.fetches.last[sessionID] = at
+	s.fetches.pending[sessionID] = true
+}
+
+func (s *Service) RunSegmentBilling(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.BillSegmentSessions(time.Now())
+	}
+}
+
+func (s *Service) BillSegmentSessions(now time.Time) {
+	active := map[string]bool{}
+	for _, ss := range s.repo.ListActiveSessions() {
+		if ss.BillingMode != BillingModeSegments {
+			continue
+		}
+		active[ss.ID] = true
+		s.fetches.mu.Lock()
+		last, seen := s.fetches.last[ss.ID]
+		fetched := s.fetches.pending[ss.ID]
+		delete(s.fetches.pending, ss.ID)
+		s.fetches.mu.Unlock()
+		if fetched {
+			s.fetches.charges.Add(1)
+			if _, err := s.charge(ss, "segment_deduction"); err != nil {
+				log.Printf("billing: session %s blocked after segment charge: %v", ss.ID, err)
+			}
+			continue
+		}
+		if !seen {
+			last = ss.StartedAt
+		}
+		if now.Sub(last) >= segmentIdleTimeout {
+			s.repo.UpdateSessionState(ss.ID, "expired")
+			s.fetches.expired.Add(1)
+			delete(active, ss.ID)
+		}
+	}
+	s.fetches.mu.Lock()
+	defer s.fetches.mu.Unlock()
+	for id := range s.fetches.last {
+		if !active[id] {
+			delete(s.fetches.last, id)
+			delete(s.fetches.pending, id)
+		}
+	}
+}
+
+type BlockedError struct {
+	Balance int64
+}
+
+func (e *BlockedError) Error() string { return "insufficient points, session blocked" }
+func (e *BlockedError) Unwrap() error { return ErrInsufficientPoints }
+
+func (s *Service) charge(ss model.Session, reason string) (map[string]any, error) {
+	rate, ok := s.sessionPointsRate(ss)
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	remaining, err := s.repo.DeductPoints(ss.UserID, ss.StreamID, ss.ID, reason, int64(rate))
+	if err != nil || remaining <= 0 {
+		s.repo.UpdateSessionState(ss.ID, "blocked")
+		return nil, &BlockedError{Balance: remaining}
+	}
+	s.repo.TouchSession(ss.ID)
+	return map[string]any{"state": "active", "balance_points": remaining}, nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/store"
+)
+
+const (
+	defaultCatalogLimit = 50
+	maxCatalogLimit     = 200
+	scheduledLookahead  = 7 * 24 * time.Hour
+)
+
+type CatalogQuery struct {
+	Status         string
+	Category       string
+	Search         string
+	Sort           string
+	Limit          int
+	Offset         int
+	IncludeDeleted bool
+	Admin          bool
+}
+
+type CatalogPage struct {
+	Streams []StreamListing `json:"streams"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+func (s *Service) Catalog(q CatalogQuery, now time.Time) (CatalogPage, error) {
+	if q.Limit == 0 {
+		q.Limit = defaultCatalogLimit
+	}
+	if q.Limit < 0 || q.Limit > maxCatalogLimit || q.Offset < 0 {
+		return CatalogPage{}, invalidf("limit must be between 1 and %d and offset must not be negative", maxCatalogLimit)
+	}
+	sq := store.StreamQuery{Category: q.Category, Search: strings.TrimSpace(q.Search), Limit: q.Limit, Offset: q.Offset}
+	sq.Sort, sq.Desc = strings.TrimPrefix(q.Sort, "-"), strings.HasPrefix(q.Sort, "-")
+	if sq.Sort == "" {
+		sq.Sort = "name"
+	}
+	if !slices.Contains(store.StreamSortFields, sq.Sort) {
+		return CatalogPage{}, invalidf("sort must be one of %s, optionally prefixed with -", strings.Join(store.StreamSortFields, ", "))
+	}
+	for _, st := range strings.Split(q.Status, ",") {
+		if st = strings.TrimSpace(st); st != "" {
+			sq.Statuses = append(sq.Statuses, st)
+		}
+	}
+	if q.Admin {
+		sq.IncludeDeleted = q.IncludeDeleted
+	} else {
+		sq.Visible = &store.StreamVisibility{Statuses: []string{"live"}, IDs: s.scheduledStreamIDs(now)}
+	}
+	streams, total := s.repo.QueryStreams(sq)
+	page := CatalogPage{Streams: make([]StreamListing, 0, len(streams)), Total: total, Limit: q.Limit, Offset: q.Offset}
+	for _, st := range streams {
+		page.Streams = append(page.Streams, s.listing(st, q.Admin, now))
+	}
+	return page, nil
+}
+
+func (s *Service) CatalogStream(id string, admin bool, now time.Time) (StreamListing, error) {
+	st, ok := s.catalogStream(id, admin, now)
+	if !ok {
+		return StreamListing{}, ErrStreamNotFound
+	}
+	return s.listing(st, admin, now), nil
+}
+
+func (s *Service) catalogStream(id string, admin bool, now time.Time) (model.Stream, bool) {
+	st, ok := s.repo.GetStream(id)
+	if !ok || (!admin && (!st.DeletedAt.IsZero() || !s.visible(st, now))) {
+		return model.Stream{}, false
+	}
+	return st, true
+}
+
+func (s *Service) DeleteStream(id, actor string) (model.Stream, error) {
+	cur, ok := s.repo.GetStream(id)
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Stream{}, ErrStreamNotFound
+	}
+	st, _ := s.repo.UpdateStream(id, func(st *model.Stream) {
+		st.Status, st.DeletedAt = StateDisabled, time.Now().UTC()
+	})
+	if cur.Status != StateDisabled {
+		s.recordTransition(id, cur.Status, StateDisabled, "user:"+actor, "stream deleted")
+	}
+	for _, sc := range s.repo.ListSchedules(id) {
+		s.repo.UpdateSchedule(sc.ID, func(sc *model.Schedule) { sc.Enabled = false })
+	}
+	for _, ss := range s.repo.ListActiveSessions() {
+		if ss.StreamID == id {
+			s.repo.UpdateSessionState(ss.ID, "stopped")
+		}
+	}
+	s.audit("user:"+actor, "stream.delete", id, fmt.Sprintf("was %s", cur.Status))
+	return st, nil
+}
+
+func (s *Service) scheduledStreamIDs(now time.Time) []string {
+	var ids []string
+	for _, sc := range s.repo.ListSchedules("") {
+		if sc.Enabled && !slices.Contains(ids, sc.StreamID) && len(occurrences(sc, now, now.Add(scheduledLookahead))) > 0 {
+			ids = append(ids, sc.StreamID)
+		}
+	}
+	return ids
+}
+
+func (s *Service) visible(st model.Stream, now time.Time) bool {
+	return st.Status == "live" || slices.Contains(s.scheduledStreamIDs(now), st.ID)
+}
+
+func (s *Service) listing(st model.Stream, admin bool, now time.Time) StreamListing {
+	if !admin {
+		st.IngestURL, st.BackupIngestURLs = "", nil
+	}
+	item := StreamListing{Stream: st}
+	for _, p := range s.Programs(st.ID, now, now.Add(programListingAhead)) {
+		if !p.StartAt.After(now) {
+			item.NowPlaying = &p
+		} else if item.UpNext == nil {
+			item.UpNext = &p
+		}
+	}
+	return item
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+func TestStreamChildrenFollowCatalogVisibility(t *testing.T) {
+	svc := newTestService()
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	streams := map[string]model.Stream{
+		"live":      {ID: "live", Name: "Live", Status: StateLive},
+		"scheduled": {ID: "scheduled", Name: "Scheduled", Status: StatePaused},
+		"paused":    {ID: "paused", Name: "Paused", Status: StatePaused},
+		"deleted":   {ID: "deleted", Name: "Deleted", Status: StateLive, DeletedAt: now.Add(-time.Hour)},
+	}
+	recs, clips, schedules := map[string]string{}, map[string]string{}, map[string]string{}
+	for id, st := range streams {
+		if _, err := svc.repo.CreateStream(st); err != nil {
+			t.Fatal(err)
+		}
+		recs[id] = svc.repo.CreateRecording(model.Recording{StreamID: id, Status: "completed"}).ID
+		clips[id] = svc.repo.CreateClip(model.Clip{StreamID: id, Status: "ready"}).ID
+		sc := model.Schedule{StreamID: id, Title: "Later", StartAt: now.Add(30 * 24 * time.Hour), DurationMinutes: 30, Timezone: "UTC", Enabled: true}
+		if id == "scheduled" {
+			sc.StartAt = now.Add(time.Hour)
+		}
+		schedules[id] = svc.repo.CreateSchedule(sc).ID
+	}
+
+	for _, tc := range []struct {
+		stream string
+		admin  bool
+		want   bool
+	}{
+		{"live", false, true},
+		{"scheduled", false, true},
+		{"paused", false, false},
+		{"deleted", false, false},
+		{"missing", false, false},
+		{"paused", true, true},
+		{"deleted", true, true},
+		{"missing", true, false},
+	} {
+		check := func(what string, err, notFound error) {
+			t.Helper()
+			if tc.want && err != nil {
+				t.Errorf("%s of %s (admin=%v): %v", what, tc.stream, tc.admin, err)
+			}
+			if !tc.want && !errors.Is(err, notFound) {
+				t.Errorf("%s of %s (admin=%v) = %v, want %v", what, tc.stream, tc.admin, err, notFound)
+			}
+		}
+		_, err := svc.ListRecordings(tc.stream, tc.admin, now)
+		check("ListRecordings", err, ErrStreamNotFound)
+		_, err = svc.ListClips(tc.stream, tc.admin, now)
+		check("ListClips", err, ErrStreamNotFound)
+		_, err = svc.ListSchedules(tc.stream, tc.admin, now)
+		check("ListSchedules", err, ErrStreamNotFound)
+		if tc.stream == "missing" {
+			continue
+		}
+		_, err = svc.GetRecording(recs[tc.stream], tc.admin, now)
+		check("GetRecording", err, ErrRecordingNotFound)
+		_, err = svc.GetClip(clips[tc.stream], tc.admin, now)
+		check("GetClip", err, ErrClipNotFound)
+		_, err = svc.GetSchedule(schedules[tc.stream], tc.admin, now)
+		check("GetSchedule", err, ErrScheduleNotFound)
+	}
+}
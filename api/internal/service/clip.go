@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"streamweb/api/internal/hls"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+)
+
+const (
+	ClipsPrefix      = "clips"
+	maxClipDuration  = 30 * time.Minute
+	clipJobTimeout   = 10 * time.Minute
+	clipMP4Name      = "clip.mp4"
+	clipMasterName   = "master.m3u8"
+	clipVariantName  = "index.m3u8"
+	defaultFFmpegBin = "ffmpeg"
+)
+
+type ClipRequest struct {
+	RecordingID string    `json:"recording_id"`
+	Title       string    `json:"title"`
+	Format      string    `json:"format"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	CreatedBy   string    `json:"-"`
+}
+
+func clipPrefix(id string) string { return ClipsPrefix + "/" + id }
+
+type clipJobs struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func (j *clipJobs) add(streamID string, n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.active[streamID] += n; j.active[streamID] <= 0 {
+		delete(j.active, streamID)
+	}
+}
+
+func (j *clipJobs) busy(streamID string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.active[streamID] > 0
+}
+
+func (s *Service) CreateClip(streamID string, req ClipRequest, now time.Time) (model.Clip, error) {
+	st, ok := s.repo.GetStream(streamID)
+	if !ok {
+		return model.Clip{}, ErrStreamNotFound
+	}
+	if s.segments == nil {
+		return model.Clip{}, ErrStorageUnconfigured
+	}
+	if req.Format == "" {
+		req.Format = "hls"
+	}
+	if req.Format != "hls" && req.Format != "mp4" {
+		return model.Clip{}, invalidf("format must be hls or mp4")
+	}
+	if req.StartAt.IsZero() || req.EndAt.IsZero() || !req.EndAt.After(req.StartAt) {
+		return model.Clip{}, invalidf("start_at and end_at are required and end_at must be after start_at")
+	}
+	if req.EndAt.Sub(req.StartAt) > maxClipDuration {
+		return model.Clip{}, invalidf("clip must not exceed %s", maxClipDuration)
+	}
+
+	source, rate := st.ID, st.RecordingPointsRate
+	if rate == 0 {
+		rate = st.PointsRate
+	}
+	if req.RecordingID != "" {
+		rec, ok := s.repo.GetRecording(req.RecordingID)
+		if !ok || rec.StreamID != st.ID {
+			return model.Clip{}, ErrRecordingNotFound
+		}
+		if rec.Status != "ready" {
+			return model.Clip{}, ErrNotReady.withf("recording not ready")
+		}
+		if req.StartAt.Before(rec.StartedAt) || req.EndAt.After(rec.EndedAt) {
+			return model.Clip{}, invalidf("clip must lie within the recording")
+		}
+		source, rate = recordingPrefix(rec.ID), rec.PointsRate
+	} else {
+		if st.DVRWindowMinutes <= 0 {
+			return model.Clip{}, ErrDVRDisabled
+		}
+		if req.StartAt.Before(now.Add(-time.Duration(st.DVRWindowMinutes)*time.Minute)) || req.EndAt.After(now) {
+			return model.Clip{}, invalidf("clip must lie within the dvr window of %d minutes", st.DVRWindowMinutes)
+		}
+	}
+	if req.Title == "" {
+		req.Title = fmt.Sprintf("%s clip %s", st.Name, req.StartAt.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+
+	clip := s.repo.CreateClip(model.Clip{
+		StreamID:    st.ID,
+		RecordingID: req.RecordingID,
+		Title:       req.Title,
+		Format:      req.Format,
+		Status:      "processing",
+		StartAt:     req.StartAt.UTC(),
+		EndAt:       req.EndAt.UTC(),
+		PointsRate:  rate,
+		CreatedBy:   req.CreatedBy,
+	})
+	if source == st.ID {
+		s.clipJobs.add(st.ID, 1)
+	}
+	go func() {
+		if source == st.ID {
+			defer s.clipJobs.add(st.ID, -1)
+		}
+		s.buildClip(clip, st, source)
+	}()
+	return clip, nil
+}
+
+func (s *Service) buildClip(clip model.Clip, st model.Stream, source string) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipJobTimeout)
+	defer cancel()
+	var (
+		duration float64
+		size     int64
+		err      error
+	)
+	switch {
+	case len(st.ABRProfiles) == 0:
+		err = fmt.Errorf("stream has no renditions")
+	case clip.Format == "mp4":
+		duration, size, err = s.remuxClip(ctx, clip, st, source)
+	default:
+		duration, size, err = s.assembleClip(ctx, clip, st, source)
+	}
+	s.repo.UpdateClip(clip.ID, func(c *model.Clip) {
+		c.DurationSec, c.SizeBytes, c.Status = duration, size, "ready"
+		if err != nil {
+			c.Status, c.Error = "failed", err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("clip %s: %v", clip.ID, err)
+		s.deletePrefix(ctx, clipPrefix(clip.ID)+"/")
+	}
+}
+
+func (s *Service) clipSegments(ctx context.Context, clip model.Clip, st model.Stream, source, rendition string) ([]hls.Segment, error) {
+	all, err := s.storedSegments(ctx, source+"/"+rendition+"/", nominalSegmentSec(st))
+	if err != nil {
+		return nil, err
+	}
+	var segs []hls.Segment
+	for _, seg := range all {
+		started := seg.EndedAt.Add(-time.Duration(seg.Duration * float64(time.Second)))
+		if seg.EndedAt.After(clip.StartAt) && started.Before(clip.EndAt) {
+			segs = append(segs, seg)
+		}
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("no %s segments between %s and %s", rendition, clip.StartAt.Format(time.RFC3339), clip.EndAt.Format(time.RFC3339))
+	}
+	return segs, nil
+}
+
+func (s *Service) assembleClip(ctx context.Context, clip model.Clip, st model.Stream, source string) (float64, int64, error) {
+	var (
+		duration float64
+		size     int64
+	)
+	dst := clipPrefix(clip.ID) + "/"
+	for i, profile := range st.ABRProfiles {
+		segs, err := s.clipSegments(ctx, clip, st, source, profile.Name)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, seg := range segs {
+			n, err := s.copyObject(ctx, source+"/"+profile.Name+"/"+seg.URI, dst+profile.Name+"/"+seg.URI)
+			if err != nil {
+				return 0, 0, err
+			}
+			size += n
+		}
+		pl := hls.MediaPlaylist{Type: "VOD", Segments: segs, Ended: true}
+		if err := s.putString(ctx, dst+profile.Name+"/"+clipVariantName, pl.Render()); err != nil {
+			return 0, 0, err
+		}
+		if i == 0 {
+			duration = pl.Duration()
+		}
+	}
+	if _, err := s.copyObject(ctx, source+"/"+clipMasterName, dst+clipMasterName); err != nil {
+		return 0, 0, err
+	}
+	return duration, size, nil
+}
+
+func (s *Service) remuxClip(ctx context.Context, clip model.Clip, st model.Stream, source string) (float64, int64, error) {
+	rendition := st.ABRProfiles[0].Name
+	segs, err := s.clipSegments(ctx, clip, st, source, rendition)
+	if err != nil {
+		return 0, 0, err
+	}
+	dir, err := os.MkdirTemp("", "clip-"+clip.ID+"-")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	inputs := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		local := filepath.Join(dir, seg.URI)
+		if err := s.download(ctx, source+"/"+rendition+"/"+seg.URI, local); err != nil {
+			return 0, 0, err
+		}
+		inputs = append(inputs, local)
+	}
+	out := filepath.Join(dir, clipMP4Name)
+	bin := s.options().FFmpegPath
+	if bin == "" {
+		bin = defaultFFmpegBin
+	}
+	cmd := exec.CommandContext(ctx, bin, "-hide_banner", "-loglevel", "error", "-y",
+		"-i", "concat:"+strings.Join(inputs, "|"),
+		"-c", "copy", "-bsf:a", "aac_adtstoasc", "-movflags", "+faststart", out)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return 0, 0, fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(msg)))
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	key := clipPrefix(clip.ID) + "/" + clipMP4Name
+	if err := s.segments.Put(ctx, key, f, info.Size(), segstore.ContentType(key)); err != nil {
+		return 0, 0, err
+	}
+	return hls.MediaPlaylist{Segments: segs}.Duration(), info.Size(), nil
+}
+
+func (s *Service) copyObject(ctx context.Context, from, to string) (int64, error) {
+	body, info, err := s.segments.Get(ctx, from)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", from, err)
+	}
+	defer body.Close()
+	if err := s.segments.Put(ctx, to, body, info.Size, segstore.ContentType(to)); err != nil {
+		return 0, fmt.Errorf("write %s: %w", to, err)
+	}
+	return info.Size, nil
+}
+
+func (s *Service) putString(ctx context.Context, key, body string) error {
+	return s.segments.Put(ctx, key, strings.NewReader(body), int64(len(body)), segstore.ContentType(key))
+}
+
+func (s *Service) download(ctx context.Context, key, local string) error {
+	body, _, err := s.segments.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", key, err)
+	}
+	defer body.Close()
+	f, err := os.Create(local)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *Service) deletePrefix(ctx context.Context, prefix string) error {
+	objects, err := s.segments.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := s.segments.Delete(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) ListClips(streamID string, admin bool, now time.Time) ([]model.Clip, error) {
+	if _, ok := s.catalogStream(streamID, admin, now); !ok {
+		return nil, ErrStreamNotFound
+	}
+	return s.repo.ListClips(streamID), nil
+}
+
+func (s *Service) GetClip(id string, admin bool, now time.Time) (model.Clip, error) {
+	clip, ok := s.repo.GetClip(id)
+	if !ok {
+		return model.Clip{}, ErrClipNotFound
+	}
+	if _, ok := s.catalogStream(clip.StreamID, admin, now); !ok {
+		return model.Clip{}, ErrClipNotFound
+	}
+	return clip, nil
+}
+
+func (s *Service) DeleteClip(ctx context.Context, id string) error {
+	clip, ok := s.repo.GetClip(id)
+	if !ok {
+		return ErrClipNotFound
+	}
+	if clip.Status == "processing" {
+		return ErrNotReady.withf("clip still processing")
+	}
+	if s.segments != nil {
+		if err := s.deletePrefix(ctx, clipPrefix(id)+"/"); err != nil {
+			return storageError(err)
+		}
+	}
+	s.repo.DeleteClip(id)
+	return nil
+}
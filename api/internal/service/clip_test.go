@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+	"streamweb/api/internal/store"
+)
+
+func newSegmentService(t *testing.T) *Service {
+	t.Helper()
+	segs, err := segstore.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(store.NewMemoryStore(ids.NewULID()), segs, ids.NewULID(), nil)
+}
+
+func TestBuildClipWithoutRenditionsFails(t *testing.T) {
+	for _, format := range []string{"hls", "mp4"} {
+		svc := newSegmentService(t)
+		st := model.Stream{ID: "bare", Name: "Bare", Status: StateLive, DVRWindowMinutes: 10}
+		if err := svc.putString(context.Background(), "bare/master.m3u8", "#EXTM3U\n"); err != nil {
+			t.Fatal(err)
+		}
+		clip := svc.repo.CreateClip(model.Clip{StreamID: st.ID, Format: format, Status: "processing"})
+		svc.buildClip(clip, st, st.ID)
+		got, _ := svc.repo.GetClip(clip.ID)
+		if got.Status != "failed" || got.Error != "stream has no renditions" {
+			t.Errorf("%s clip = %s (%q), want failed: stream has no renditions", format, got.Status, got.Error)
+		}
+		if objs, _ := svc.segments.List(context.Background(), clipPrefix(clip.ID)+"/"); len(objs) != 0 {
+			t.Errorf("%s clip left %d objects", format, len(objs))
+		}
+	}
+}
+
+func TestRetentionSkipsStreamsWithClipJobs(t *testing.T) {
+	svc := newSegmentService(t)
+	ctx := context.Background()
+	for _, id := range []string{"busy", "idle"} {
+		if _, err := svc.repo.CreateStream(model.Stream{ID: id, Name: id, Status: StateLive, PlaylistWindowMinutes: 1, SegmentDurationSec: 4}); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.putString(ctx, id+"/720p/seg_00001.ts", strings.Repeat("x", 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	later := time.Now().Add(time.Hour)
+	svc.clipJobs.add("busy", 1)
+	svc.clipJobs.add("busy", 1)
+	svc.clipJobs.add("busy", -1)
+	rep, err := svc.EnforceRetention(ctx, RetentionPolicy{}, later)
+	if err != nil || rep.DeletedObjects != 1 {
+		t.Fatalf("retention with a clip job = %+v, %v; want only the idle segment deleted", rep, err)
+	}
+	if _, err := svc.segments.Stat(ctx, "busy/720p/seg_00001.ts"); err != nil {
+		t.Errorf("segment of the clipped stream: %v", err)
+	}
+
+	svc.clipJobs.add("busy", -1)
+	if rep, err := svc.EnforceRetention(ctx, RetentionPolicy{}, later); err != nil || rep.DeletedObjects != 1 {
+		t.Errorf("retention after the clip job = %+v, %v; want the segment deleted", rep, err)
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/hls"
+	"streamweb/api/internal/model"
+)
+
+const maxDVRWindowMinutes = 24 * 60
+
+func dvrMode(st model.Stream, offsetSec int, mode string) (string, error) {
+	if offsetSec < 0 {
+		return "", invalidf("start_offset_sec must not be negative")
+	}
+	if mode == "" && offsetSec == 0 {
+		return "", nil
+	}
+	if st.DVRWindowMinutes <= 0 {
+		return "", ErrDVRDisabled
+	}
+	if offsetSec > st.DVRWindowMinutes*60 {
+		return "", invalidf("start_offset_sec exceeds dvr window of %d minutes", st.DVRWindowMinutes)
+	}
+	switch mode {
+	case "":
+		return "event", nil
+	case "event", "sliding":
+		return mode, nil
+	}
+	return "", invalidf("dvr_mode must be event or sliding")
+}
+
+func (s *Service) storedSegments(ctx context.Context, prefix string, nominal float64) ([]hls.Segment, error) {
+	objects, err := s.segments.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	durations := map[string]float64{}
+	if body, _, err := s.segments.Get(ctx, prefix+"index.m3u8"); err == nil {
+		durations = hls.ParseDurations(io.LimitReader(body, 4<<20))
+		body.Close()
+	}
+	segs := []hls.Segment{}
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, prefix)
+		if strings.Contains(name, "/") || path.Ext(name) != ".ts" {
+			continue
+		}
+		seq, ok := hls.SegmentSeq(name)
+		if !ok {
+			continue
+		}
+		d, ok := durations[name]
+		if !ok || d <= 0 {
+			d = nominal
+		}
+		segs = append(segs, hls.Segment{URI: name, Seq: seq, Duration: d, EndedAt: obj.ModTime})
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].Seq < segs[j].Seq })
+	return segs, nil
+}
+
+func nominalSegmentSec(st model.Stream) float64 {
+	if st.SegmentDurationSec > 0 {
+		return float64(st.SegmentDurationSec)
+	}
+	return defaultSegmentDuration.Seconds()
+}
+
+func (s *Service) DVRPlaylist(ctx context.Context, sessionID, rendition, mode string, now time.Time) (string, error) {
+	ss, ok := s.repo.GetSession(sessionID)
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	st, ok := s.repo.GetStream(ss.StreamID)
+	if !ok {
+		return "", ErrStreamNotFound
+	}
+	if st.DVRWindowMinutes <= 0 || s.segments == nil {
+		return "", ErrDVRDisabled
+	}
+	if mode != "event" && mode != "sliding" {
+		return "", invalidf("dvr must be event or sliding")
+	}
+	all, err := s.storedSegments(ctx, st.ID+"/"+rendition+"/", nominalSegmentSec(st))
+	if err != nil {
+		return "", storageError(err)
+	}
+
+	windowStart := now.Add(-time.Duration(st.DVRWindowMinutes) * time.Minute)
+	from := windowStart
+	pl := hls.MediaPlaylist{}
+	if mode == "event" {
+		from = ss.StartedAt.Add(-time.Duration(ss.TimeshiftOffsetSec) * time.Second)
+		if from.Before(windowStart) {
+			from = windowStart
+		}
+		zero := 0.0
+		pl.Type, pl.StartOffset = "EVENT", &zero
+	} else if ss.TimeshiftOffsetSec > 0 {
+		offset := -float64(ss.TimeshiftOffsetSec)
+		pl.StartOffset = &offset
+	}
+	for _, seg := range all {
+		if !seg.EndedAt.Before(from) {
+			pl.Segments = append(pl.Segments, seg)
+		}
+	}
+	if len(pl.Segments) == 0 {
+		return "", ErrNoSegments
+	}
+	return pl.Render(), nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
+	"streamweb/api/internal/store"
+)
+
+func TestDVRMode(t *testing.T) {
+	dvr := model.Stream{DVRWindowMinutes: 10}
+	for _, tc := range []struct {
+		st     model.Stream
+		offset int
+		mode   string
+		want   string
+		err    error
+	}{
+		{st: model.Stream{}, want: ""},
+		{st: dvr, want: ""},
+		{st: dvr, offset: 60, want: "event"},
+		{st: dvr, mode: "sliding", want: "sliding"},
+		{st: dvr, offset: 600, mode: "event", want: "event"},
+		{st: dvr, offset: 601, err: ErrInvalidRequest},
+		{st: dvr, offset: -1, err: ErrInvalidRequest},
+		{st: dvr, mode: "rewind", err: ErrInvalidRequest},
+		{st: model.Stream{}, offset: 30, err: ErrDVRDisabled},
+		{st: model.Stream{}, mode: "event", err: ErrDVRDisabled},
+	} {
+		got, err := dvrMode(tc.st, tc.offset, tc.mode)
+		if got != tc.want || !errors.Is(err, tc.err) {
+			t.Errorf("dvrMode(window=%d, %d, %q) = %q, %v; want %q, %v", tc.st.DVRWindowMinutes, tc.offset, tc.mode, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func playlistURIs(pl string) []string {
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(pl), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func TestDVRPlaylistWindows(t *testing.T) {
+	root := t.TempDir()
+	segs, err := segstore.NewFSStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := New(store.NewMemoryStore(ids.NewULID()), segs, ids.NewULID(), nil)
+	ctx := context.Background()
+	if _, err := svc.repo.CreateStream(model.Stream{ID: "dvr", Name: "DVR", Status: StateLive, SegmentDurationSec: 4, DVRWindowMinutes: 7}); err != nil {
+		t.Fatal(err)
+	}
+	session := func(offset int) model.Session {
+		return svc.repo.CreateSession(model.Session{StreamID: "dvr", TimeshiftOffsetSec: offset, DVRMode: "event"})
+	}
+	base := session(0).StartedAt
+	ended := map[string]time.Duration{
+		"seg_1.ts": -12 * time.Minute,
+		"seg_2.ts": -6 * time.Minute,
+		"seg_3.ts": -time.Minute,
+		"seg_4.ts": time.Minute,
+		"seg_6.ts": 2 * time.Minute,
+	}
+	for name, d := range ended {
+		if err := svc.putString(ctx, "dvr/720p/"+name, "ts"); err != nil {
+			t.Fatal(err)
+		}
+		at := base.Add(d)
+		if err := os.Chtimes(filepath.Join(root, "dvr", "720p", name), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.putString(ctx, "dvr/720p/index.m3u8", "#EXTM3U\n#EXTINF:3.500,\nseg_4.ts\n"); err != nil {
+		t.Fatal(err)
+	}
+	now := base.Add(3 * time.Minute)
+
+	for _, tc := range []struct {
+		name   string
+		offset int
+		mode   string
+		uris   []string
+		has    []string
+	}{
+		{name: "sliding", mode: "sliding", uris: []string{"seg_3.ts", "seg_4.ts", "seg_6.ts"}, has: []string{"#EXT-X-MEDIA-SEQUENCE:3\n", "#EXTINF:3.500,\nseg_4.ts", "#EXT-X-DISCONTINUITY\n"}},
+		{name: "sliding with offset", offset: 120, mode: "sliding", uris: []string{"seg_3.ts", "seg_4.ts", "seg_6.ts"}, has: []string{"#EXT-X-START:TIME-OFFSET=-120,PRECISE=YES\n"}},
+		{name: "event from session start", mode: "event", uris: []string{"seg_4.ts", "seg_6.ts"}, has: []string{"#EXT-X-PLAYLIST-TYPE:EVENT\n", "#EXT-X-START:TIME-OFFSET=0,PRECISE=YES\n"}},
+		{name: "event with offset", offset: 120, mode: "event", uris: []string{"seg_3.ts", "seg_4.ts", "seg_6.ts"}},
+		{name: "event clamped to the window", offset: 400, mode: "event", uris: []string{"seg_3.ts", "seg_4.ts", "seg_6.ts"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ss := session(tc.offset)
+			pl, err := svc.DVRPlaylist(ctx, ss.ID, "720p", tc.mode, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := playlistURIs(pl); !reflect.DeepEqual(got, tc.uris) {
+				t.Errorf("segments = %v, want %v\n%s", got, tc.uris, pl)
+			}
+			for _, s := range tc.has {
+				if !strings.Contains(pl, s) {
+					t.Errorf("playlist lacks %q\n%s", s, pl)
+				}
+			}
+			if tc.mode == "sliding" && strings.Contains(pl, "#EXT-X-PLAYLIST-TYPE") {
+				t.Errorf("sliding playlist has a type\n%s", pl)
+			}
+		})
+	}
+
+	ss := session(0)
+	for _, tc := range []struct {
+		session, rendition, mode string
+		want                     error
+	}{
+		{"missing", "720p", "event", ErrSessionNotFound},
+		{ss.ID, "720p", "rewind", ErrInvalidRequest},
+		{ss.ID, "1080p", "event", ErrNoSegments},
+	} {
+		if _, err := svc.DVRPlaylist(ctx, tc.session, tc.rendition, tc.mode, now); !errors.Is(err, tc.want) {
+			t.Errorf("DVRPlaylist(%s, %s, %s) = %v, want %v", tc.session, tc.rendition, tc.mode, err, tc.want)
+		}
+	}
+	plain := svc.repo.CreateSession(model.Session{StreamID: "stream-1"})
+	if _, err := svc.DVRPlaylist(ctx, plain.ID, "source", "event", now); !errors.Is(err, ErrDVRDisabled) {
+		t.Errorf("stream without dvr = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/xmltv"
+)
+
+const (
+	epgExportPast  = 6 * time.Hour
+	epgExportAhead = 7 * 24 * time.Hour
+)
+
+type EPGImportOptions struct {
+	DryRun bool
+	Actor  string
+}
+
+type EPGChannelResult struct {
+	ChannelID  string `json:"channel_id"`
+	StreamID   string `json:"stream_id"`
+	Programmes int    `json:"programmes"`
+	Replaced   int    `json:"replaced"`
+}
+
+type EPGSkip struct {
+	ChannelID string `json:"channel_id"`
+	Start     string `json:"start"`
+	Reason    string `json:"reason"`
+}
+
+type EPGImportReport struct {
+	DryRun     bool               `json:"dry_run"`
+	Programmes int                `json:"programmes"`
+	Channels   []EPGChannelResult `json:"channels"`
+	Unmatched  []string           `json:"unmatched_channels"`
+	Skipped    []EPGSkip          `json:"skipped"`
+}
+
+func (s *Service) ImportXMLTV(r io.Reader, opts EPGImportOptions) (EPGImportReport, error) {
+	tv, err := xmltv.Parse(r)
+	if err != nil {
+		return EPGImportReport{}, invalid(err)
+	}
+	if len(tv.Programmes) == 0 {
+		return EPGImportReport{}, invalidf("no programmes found in guide")
+	}
+	streams := map[string]string{}
+	for _, st := range s.repo.ListStreams() {
+		if !st.DeletedAt.IsZero() {
+			continue
+		}
+		if _, taken := streams[channelID(st)]; !taken || st.ExternalID != "" {
+			streams[channelID(st)] = st.ID
+		}
+	}
+
+	rep := EPGImportReport{DryRun: opts.DryRun, Channels: []EPGChannelResult{}, Unmatched: []string{}, Skipped: []EPGSkip{}}
+	byChannel, order := map[string][]xmltv.Programme{}, []string{}
+	for _, p := range tv.Programmes {
+		if _, ok := byChannel[p.Channel]; !ok {
+			order = append(order, p.Channel)
+		}
+		byChannel[p.Channel] = append(byChannel[p.Channel], p)
+	}
+	for _, ch := range tv.Channels {
+		if _, ok := byChannel[ch.ID]; !ok {
+			order = append(order, ch.ID)
+		}
+	}
+	for _, channel := range order {
+		streamID, ok := streams[channel]
+		if !ok {
+			rep.Unmatched = append(rep.Unmatched, channel)
+			continue
+		}
+		ps, skipped := epgProgrammes(channel, byChannel[channel])
+		rep.Skipped = append(rep.Skipped, skipped...)
+		if len(ps) == 0 {
+			continue
+		}
+		res := EPGChannelResult{ChannelID: channel, StreamID: streamID, Programmes: len(ps)}
+		if !opts.DryRun {
+			res.Replaced = s.repo.ReplaceProgrammes(streamID, ps[0].StartAt, ps[len(ps)-1].EndAt, ps)
+		}
+		rep.Programmes += len(ps)
+		rep.Channels = append(rep.Channels, res)
+	}
+	if !opts.DryRun && rep.Programmes > 0 {
+		s.audit(opts.Actor, "epg.import", "", fmt.Sprintf("%d programmes on %d channels, %d unmatched channels, %d skipped", rep.Programmes, len(rep.Channels), len(rep.Unmatched), len(rep.Skipped)))
+	}
+	return rep, nil
+}
+
+func epgProgrammes(channel string, in []xmltv.Programme) ([]model.Programme, []EPGSkip) {
+	type parsed struct {
+		src         xmltv.Programme
+		start, stop time.Time
+	}
+	var (
+		items   []parsed
+		skipped []EPGSkip
+	)
+	for _, p := range in {
+		start, err := xmltv.ParseTime(p.Start)
+		if err != nil {
+			skipped = append(skipped, EPGSkip{ChannelID: channel, Start: p.Start, Reason: err.Error()})
+			continue
+		}
+		var stop time.Time
+		if p.Stop != "" {
+			if stop, err = xmltv.ParseTime(p.Stop); err != nil {
+				skipped = append(skipped, EPGSkip{ChannelID: channel, Start: p.Start, Reason: err.Error()})
+				continue
+			}
+		}
+		items = append(items, parsed{p, start, stop})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].start.Before(items[j].start) })
+	out := []model.Programme{}
+	for i, it := range items {
+		if it.stop.IsZero() && i+1 < len(items) {
+			it.stop = items[i+1].start
+		}
+		switch {
+		case it.src.Title() == "":
+			skipped = append(skipped, EPGSkip{ChannelID: channel, Start: it.src.Start, Reason: "programme has no title"})
+		case it.stop.IsZero():
+			skipped = append(skipped, EPGSkip{ChannelID: channel, Start: it.src.Start, Reason: "programme has no stop time"})
+		case !it.stop.After(it.start):
+			skipped = append(skipped, EPGSkip{ChannelID: channel, Start: it.src.Start, Reason: "stop is not after start"})
+		default:
+			out = append(out, model.Programme{ChannelID: channel, Title: it.src.Title(), Description: it.src.Description(), Category: it.src.Category(), StartAt: it.start, EndAt: it.stop})
+		}
+	}
+	return out, skipped
+}
+
+func (s *Service) UserEPG(uid string, now time.Time) ([]byte, error) {
+	if _, ok := s.repo.GetUser(uid); !ok {
+		return nil, ErrUserNotFound
+	}
+	tv := xmltv.TV{GeneratorName: "streamweb", Channels: []xmltv.Channel{}, Programmes: []xmltv.Programme{}}
+	from, to := now.Add(-epgExportPast), now.Add(epgExportAhead)
+	for _, st := range s.userStreams() {
+		ch := xmltv.Channel{ID: channelID(st), DisplayNames: xmltv.Texts(firstNonEmpty(st.Name, st.ID))}
+		if st.LogoURL != "" {
+			ch.Icon = &xmltv.Icon{Src: st.LogoURL}
+		}
+		tv.Channels = append(tv.Channels, ch)
+		for _, p := range s.Programs(st.ID, from, to) {
+			tv.Programmes = append(tv.Programmes, xmltv.Programme{
+				Start:      xmltv.FormatTime(p.StartAt),
+				Stop:       xmltv.FormatTime(p.EndAt),
+				Channel:    ch.ID,
+				Titles:     xmltv.Texts(p.Title),
+				Descs:      xmltv.Texts(p.Description),
+				Categories: xmltv.Texts(p.Category),
+			})
+		}
+	}
+	var buf bytes.Buffer
+	if err := xmltv.Write(&buf, tv); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
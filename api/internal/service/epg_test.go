@@ -0,0 +1,78 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/xmltv"
+)
+
+func TestEPGProgrammes(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 1, h, m, 0, 0, time.UTC) }
+	prog := func(start, stop, title string) xmltv.Programme {
+		return xmltv.Programme{Start: start, Stop: stop, Channel: "one", Titles: xmltv.Texts(title)}
+	}
+	for _, tc := range []struct {
+		name    string
+		in      []xmltv.Programme
+		want    []model.Programme
+		skipped []EPGSkip
+	}{
+		{
+			name: "sorted with offsets",
+			in:   []xmltv.Programme{prog("20240301130000 +0100", "20240301140000 +0100", "Two"), prog("20240301110000 +0000", "20240301113000 +0000", "One")},
+			want: []model.Programme{
+				{ChannelID: "one", Title: "One", StartAt: at(11, 0), EndAt: at(11, 30)},
+				{ChannelID: "one", Title: "Two", StartAt: at(12, 0), EndAt: at(13, 0)},
+			},
+		},
+		{
+			name:    "missing stop taken from the next programme",
+			in:      []xmltv.Programme{prog("20240301120000 +0000", "", "One"), prog("20240301124500 +0000", "", "Last")},
+			want:    []model.Programme{{ChannelID: "one", Title: "One", StartAt: at(12, 0), EndAt: at(12, 45)}},
+			skipped: []EPGSkip{{ChannelID: "one", Start: "20240301124500 +0000", Reason: "programme has no stop time"}},
+		},
+		{
+			name:    "no title",
+			in:      []xmltv.Programme{prog("20240301120000 +0000", "20240301130000 +0000", " ")},
+			want:    []model.Programme{},
+			skipped: []EPGSkip{{ChannelID: "one", Start: "20240301120000 +0000", Reason: "programme has no title"}},
+		},
+		{
+			name: "stop not after start",
+			in:   []xmltv.Programme{prog("20240301120000 +0000", "20240301120000 +0000", "Zero"), prog("20240301130000 +0000", "20240301120000 +0000", "Back")},
+			want: []model.Programme{},
+			skipped: []EPGSkip{
+				{ChannelID: "one", Start: "20240301120000 +0000", Reason: "stop is not after start"},
+				{ChannelID: "one", Start: "20240301130000 +0000", Reason: "stop is not after start"},
+			},
+		},
+		{
+			name: "bad times",
+			in:   []xmltv.Programme{prog("tomorrow", "20240301130000 +0000", "A"), prog("20240301120000 +0000", "soon", "B")},
+			want: []model.Programme{},
+			skipped: []EPGSkip{
+				{ChannelID: "one", Start: "tomorrow", Reason: `invalid xmltv time "tomorrow"`},
+				{ChannelID: "one", Start: "20240301120000 +0000", Reason: `invalid xmltv time "soon"`},
+			},
+		},
+		{
+			name: "description and category",
+			in: []xmltv.Programme{{Start: "20240301120000", Stop: "20240301123000", Channel: "one", Titles: xmltv.Texts("News"),
+				Descs: xmltv.Texts("Headlines"), Categories: []xmltv.Text{{Value: ""}, {Value: "Info"}}}},
+			want: []model.Programme{{ChannelID: "one", Title: "News", Description: "Headlines", Category: "Info", StartAt: at(12, 0), EndAt: at(12, 30)}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, skipped := epgProgrammes("one", tc.in)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("programmes = %+v, want %+v", got, tc.want)
+			}
+			if !reflect.DeepEqual(skipped, tc.skipped) {
+				t.Errorf("skipped = %+v, want %+v", skipped, tc.skipped)
+			}
+		})
+	}
+}
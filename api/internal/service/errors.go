@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"streamweb/api/internal/segstore"
+)
+
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) withf(format string, args ...any) *Error {
+	return &Error{Code: e.Code, Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	ErrInvalidRequest      = &Error{"invalid_request", "invalid request"}
+	ErrValidation          = &Error{"validation_failed", "validation failed"}
+	ErrInvalidCredentials  = &Error{"invalid_credentials", "invalid credentials"}
+	ErrInvalidToken        = &Error{"invalid_token", "invalid token"}
+	ErrPlayTokenExpired    = &Error{"play_token_expired", "play token expired"}
+	ErrInsufficientPoints  = &Error{"insufficient_points", "insufficient points"}
+	ErrUserInactive        = &Error{"user_inactive", "user not active"}
+	ErrSessionNotActive    = &Error{"session_not_active", "session not active"}
+	ErrInvalidStreamKey    = &Error{"invalid_stream_key", "invalid stream key"}
+	ErrInvalidPlaylistKey  = &Error{"invalid_playlist_key", "invalid playlist key"}
+	ErrAssetMismatch       = &Error{"asset_mismatch", "asset does not belong to session"}
+	ErrStreamNotFound      = &Error{"stream_not_found", "stream not found"}
+	ErrUserNotFound        = &Error{"user_not_found", "user not found"}
+	ErrSessionNotFound     = &Error{"session_not_found", "session not found"}
+	ErrRecordingNotFound   = &Error{"recording_not_found", "recording not found"}
+	ErrClipNotFound        = &Error{"clip_not_found", "clip not found"}
+	ErrScheduleNotFound    = &Error{"schedule_not_found", "schedule not found"}
+	ErrNoSegments          = &Error{"no_segments", "no segments in dvr window"}
+	ErrAssetNotFound       = &Error{"asset_not_found", "asset not found"}
+	ErrStreamExists        = &Error{"stream_exists", "stream already exists"}
+	ErrConcurrentUpdate    = &Error{"concurrent_update", "stream was modified concurrently, retry"}
+	ErrInvalidTransition   = &Error{"invalid_transition", "invalid state transition"}
+	ErrStreamNotLive       = &Error{"stream_not_live", "stream not live"}
+	ErrNotPushIngest       = &Error{"not_push_ingest", "stream does not use push ingest"}
+	ErrDVRDisabled         = &Error{"dvr_disabled", "dvr not enabled for stream"}
+	ErrRecordingDisabled   = &Error{"recording_disabled", "recording not enabled for stream"}
+	ErrStorageUnconfigured = &Error{"storage_not_configured", "segment storage not configured"}
+	ErrNotReady            = &Error{"not_ready", "not ready"}
+	ErrSegmentBilled       = &Error{"segment_billed", "session is billed from segment fetches"}
+	ErrVersionMismatch     = &Error{"version_mismatch", "stream has been modified"}
+	ErrIngestNotReady      = &Error{"ingest_not_ready", "ingest not ready"}
+	ErrProbeFailed         = &Error{"probe_failed", "ingest probe failed"}
+	ErrTooManySessions     = &Error{"too_many_sessions", "too many concurrent sessions"}
+	ErrStorageUnavailable  = &Error{"storage_unavailable", "segment storage unavailable"}
+	ErrInternal            = &Error{"internal", "internal error"}
+)
+
+func invalidf(format string, args ...any) error { return ErrInvalidRequest.withf(format, args...) }
+
+func invalid(err error) error { return ErrInvalidRequest.withf("%s", err) }
+
+func storageError(err error) error {
+	if errors.Is(err, segstore.ErrNotFound) || errors.Is(err, segstore.ErrInvalidKey) {
+		return ErrAssetNotFound
+	}
+	log.Printf("storage: %v", err)
+	return ErrStorageUnavailable
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	maxBackupIngestURLs = 8
+	maxFailoverAfterSec = 600
+)
+
+func (s *Service) audit(actor, action, streamID, detail string) {
+	s.repo.AppendAudit(model.AuditEntry{Actor: actor, Action: action, StreamID: streamID, Detail: detail})
+}
+
+func (s *Service) Audit(streamID string, limit int) []model.AuditEntry {
+	return s.repo.ListAudit(streamID, limit)
+}
+
+func (s *Service) RecordFailover(streamID string, ev model.FailoverEvent) (model.StreamRuntime, error) {
+	st, ok := s.repo.GetStream(streamID)
+	if !ok {
+		return model.StreamRuntime{}, ErrStreamNotFound
+	}
+	sources := 1 + len(st.BackupIngestURLs)
+	if ev.WorkerID == "" {
+		return model.StreamRuntime{}, invalidf("worker_id required")
+	}
+	if ev.FromIndex < 0 || ev.FromIndex >= sources || ev.ToIndex < 0 || ev.ToIndex >= sources {
+		return model.StreamRuntime{}, invalidf("source index out of range")
+	}
+	action := "ingest.failover"
+	if ev.ToIndex == 0 {
+		action = "ingest.failback"
+	}
+	detail := fmt.Sprintf("source %d -> %d: %s", ev.FromIndex, ev.ToIndex, ev.Reason)
+	rt := s.repo.UpdateRuntime(streamID, func(rt *model.StreamRuntime) {
+		rt.ActiveSource = ev.ToIndex
+		rt.FailoverCount++
+		rt.LastFailoverAt = time.Now().UTC()
+		rt.LastFailover = detail
+	})
+	s.audit("worker:"+ev.WorkerID, action, streamID, detail)
+	return rt, nil
+}
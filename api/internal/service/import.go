@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"streamweb/api/internal/m3u"
+	"streamweb/api/internal/model"
+)
+
+const (
+	defaultImportPointsRate  = 5
+	defaultImportMaxSessions = 2
+)
+
+type ImportOptions struct {
+	DryRun                bool
+	PointsRate            int
+	MaxConcurrentSessions int
+	ABRPreset             string
+	Actor                 string
+}
+
+type StreamChange struct {
+	StreamID string               `json:"stream_id"`
+	Name     string               `json:"name"`
+	Line     int                  `json:"line"`
+	Changes  map[string][2]string `json:"changes,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool           `json:"dry_run"`
+	Created   []StreamChange `json:"created"`
+	Updated   []StreamChange `json:"updated"`
+	Unchanged []StreamChange `json:"unchanged"`
+	Skipped   []m3u.Problem  `json:"skipped"`
+}
+
+func (s *Service) ImportM3U(ctx context.Context, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	entries, problems, err := m3u.Parse(r)
+	if err != nil {
+		return ImportReport{}, invalid(err)
+	}
+	if len(entries) == 0 {
+		return ImportReport{}, invalidf("no channels found in playlist")
+	}
+	if opts.PointsRate == 0 {
+		opts.PointsRate = defaultImportPointsRate
+	}
+	if opts.MaxConcurrentSessions == 0 {
+		opts.MaxConcurrentSessions = defaultImportMaxSessions
+	}
+	if opts.PointsRate < 0 || opts.MaxConcurrentSessions < 0 {
+		return ImportReport{}, invalidf("points_rate and max_concurrent_sessions must not be negative")
+	}
+	if _, err := resolveABR(opts.ABRPreset, nil); err != nil {
+		return ImportReport{}, invalid(err)
+	}
+
+	rep := ImportReport{DryRun: opts.DryRun, Created: []StreamChange{}, Updated: []StreamChange{}, Unchanged: []StreamChange{}, Skipped: problems}
+	if rep.Skipped == nil {
+		rep.Skipped = []m3u.Problem{}
+	}
+	byExternal, byURL, used := map[string]model.Stream{}, map[string]model.Stream{}, map[string]bool{}
+	for _, st := range s.repo.ListStreams() {
+		used[st.ID] = true
+		if st.ExternalID != "" {
+			byExternal[st.ExternalID] = st
+		}
+		if st.IngestURL != "" {
+			byURL[st.IngestURL] = st
+		}
+	}
+	seen := map[string]int{}
+	for _, e := range entries {
+		if msg := ingestURLProblem(e.URL); msg != "" {
+			rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: fmt.Sprintf("invalid url %q: %s", e.URL, msg)})
+			continue
+		}
+		name := e.Name()
+		if name == "" {
+			name = e.TvgID()
+		}
+		if name == "" {
+			rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: "channel has no name"})
+			continue
+		}
+		key := "url:" + e.URL
+		if e.TvgID() != "" {
+			key = "tvg:" + e.TvgID()
+		}
+		if first, dup := seen[key]; dup {
+			rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: fmt.Sprintf("duplicate of line %d", first)})
+			continue
+		}
+		seen[key] = e.Line
+
+		existing, found := byExternal[e.TvgID()]
+		if !found || e.TvgID() == "" {
+			existing, found = byURL[e.URL]
+			found = found && (existing.ExternalID == "" || e.TvgID() == "")
+		}
+		if found && !existing.DeletedAt.IsZero() {
+			rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: fmt.Sprintf("matches deleted stream %s", existing.ID)})
+			continue
+		}
+		if found {
+			changes := importChanges(existing, name, e)
+			change := StreamChange{StreamID: existing.ID, Name: name, Line: e.Line, Changes: changes}
+			if len(changes) == 0 {
+				rep.Unchanged = append(rep.Unchanged, change)
+				continue
+			}
+			patch := importPatch(name, e)
+			candidate := existing
+			patch.apply(&candidate)
+			if reason := importProblem(candidate, patch.fields()); reason != "" {
+				rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: reason})
+				continue
+			}
+			if !opts.DryRun {
+				if _, err := s.PatchStream(ctx, existing.ID, patch, opts.Actor, existing.Version); err != nil {
+					rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: err.Error()})
+					continue
+				}
+			}
+			rep.Updated = append(rep.Updated, change)
+			continue
+		}
+
+		st := defaultStream(uniqueStreamID(firstNonEmpty(e.TvgID(), name), used))
+		st.PointsRate, st.MaxConcurrentSessions = opts.PointsRate, opts.MaxConcurrentSessions
+		importPatch(name, e).apply(&st)
+		candidate := st
+		candidate.ABRProfiles, _ = resolveABR(opts.ABRPreset, nil)
+		if reason := importProblem(candidate, nil); reason != "" {
+			rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: reason})
+			continue
+		}
+		used[st.ID] = true
+		change := StreamChange{StreamID: st.ID, Name: name, Line: e.Line, Changes: importChanges(model.Stream{}, name, e)}
+		if !opts.DryRun {
+			if _, err := s.createStream(st, opts.ABRPreset); err != nil {
+				rep.Skipped = append(rep.Skipped, m3u.Problem{Line: e.Line, Reason: err.Error()})
+				continue
+			}
+		}
+		rep.Created = append(rep.Created, change)
+	}
+	sort.Slice(rep.Skipped, func(i, j int) bool { return rep.Skipped[i].Line < rep.Skipped[j].Line })
+	if !opts.DryRun && len(rep.Created)+len(rep.Updated) > 0 {
+		s.audit(opts.Actor, "streams.import", "", fmt.Sprintf("created %d, updated %d, unchanged %d, skipped %d", len(rep.Created), len(rep.Updated), len(rep.Unchanged), len(rep.Skipped)))
+	}
+	return rep, nil
+}
+
+func importProblem(st model.Stream, only map[string]bool) string {
+	v := &validator{only: only}
+	v.stream(st)
+	msgs := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func importPatch(name string, e m3u.Entry) StreamPatch {
+	p := StreamPatch{Name: &name, IngestURL: &e.URL}
+	if v := e.TvgID(); v != "" {
+		p.ExternalID = &v
+	}
+	if v := e.Group(); v != "" {
+		p.Group = &v
+	}
+	if v := e.Logo(); v != "" {
+		p.LogoURL = &v
+	}
+	return p
+}
+
+func importChanges(before model.Stream, name string, e m3u.Entry) map[string][2]string {
+	after := before
+	importPatch(name, e).apply(&after)
+	changes := map[string][2]string{}
+	diff := func(field, a, b string) {
+		if a != b {
+			changes[field] = [2]string{a, b}
+		}
+	}
+	diff("name", before.Name, after.Name)
+	diff("ingest_url", before.IngestURL, after.IngestURL)
+	diff("external_id", before.ExternalID, after.ExternalID)
+	diff("group", before.Group, after.Group)
+	diff("logo_url", before.LogoURL, after.LogoURL)
+	return changes
+}
+
+func uniqueStreamID(base string, used map[string]bool) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(base) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimRight(b.String(), "-")
+	if len(slug) > maxStreamIDLength {
+		slug = strings.TrimRight(slug[:maxStreamIDLength], "-")
+	}
+	if slug == "" {
+		slug = "channel"
+	}
+	id := slug
+	for n := 2; used[id] || reservedStreamIDs[id]; n++ {
+		suffix := "-" + strconv.Itoa(n)
+		id = strings.TrimRight(slug[:min(len(slug), maxStreamIDLength-len(suffix))], "-") + suffix
+	}
+	return id
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
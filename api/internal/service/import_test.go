@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"streamweb/api/internal/m3u"
+)
+
+func TestImportM3UValidatesInDryRun(t *testing.T) {
+	playlist := strings.Join([]string{
+		"#EXTM3U",
+		`#EXTINF:-1 tvg-id="one" group-title="News",One`,
+		"http://example.com/one.m3u8",
+		`#EXTINF:-1 tvg-id="two" tvg-logo="ftp://example.com/two.png",Two`,
+		"http://example.com/two.m3u8",
+		`#EXTINF:-1 tvg-id="three",` + strings.Repeat("n", maxStreamNameLength+1),
+		"http://example.com/three.m3u8",
+		`#EXTINF:-1 tvg-id="four" group-title="` + strings.Repeat("g", maxGroupLength+1) + `" tvg-logo="ftp://example.com/four.png",Four`,
+		"http://example.com/four.m3u8",
+		`#EXTINF:-1 tvg-id="one" tvg-logo="mailto:x",One`,
+		"http://example.com/one.m3u8",
+	}, "\n")
+	wantSkipped := []m3u.Problem{
+		{Line: 4, Reason: "logo_url: must be an http or https url"},
+		{Line: 6, Reason: "name: must be at most 200 characters"},
+		{Line: 8, Reason: "group: must be at most 100 characters; logo_url: must be an http or https url"},
+		{Line: 10, Reason: "duplicate of line 2"},
+	}
+
+	var reports []ImportReport
+	for _, dryRun := range []bool{true, false} {
+		svc := newTestService()
+		before := len(svc.repo.ListStreams())
+		rep, err := svc.ImportM3U(context.Background(), strings.NewReader(playlist), ImportOptions{DryRun: dryRun, Actor: "u_admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rep.Skipped, wantSkipped) {
+			t.Errorf("dry_run=%v skipped = %+v, want %+v", dryRun, rep.Skipped, wantSkipped)
+		}
+		if len(rep.Created) != 1 || rep.Created[0].StreamID != "one" {
+			t.Errorf("dry_run=%v created = %+v, want only one", dryRun, rep.Created)
+		}
+		if n := len(svc.repo.ListStreams()) - before; (dryRun && n != 0) || (!dryRun && n != 1) {
+			t.Errorf("dry_run=%v stored %d new streams", dryRun, n)
+		}
+		rep.DryRun = false
+		reports = append(reports, rep)
+	}
+	if !reflect.DeepEqual(reports[0], reports[1]) {
+		t.Errorf("dry run report %+v differs from import report %+v", reports[0], reports[1])
+	}
+
+	svc := newTestService()
+	if _, err := svc.ImportM3U(context.Background(), strings.NewReader("#EXTM3U\n#EXTINF:-1 tvg-id=\"one\",One\nhttp://example.com/one.m3u8"), ImportOptions{Actor: "u_admin"}); err != nil {
+		t.Fatal(err)
+	}
+	update := "#EXTM3U\n#EXTINF:-1 tvg-id=\"one\" tvg-logo=\"ftp://example.com/one.png\",One\nhttp://example.com/one.m3u8"
+	for _, dryRun := range []bool{true, false} {
+		rep, err := svc.ImportM3U(context.Background(), strings.NewReader(update), ImportOptions{DryRun: dryRun, Actor: "u_admin"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rep.Updated) != 0 || len(rep.Skipped) != 1 || rep.Skipped[0].Reason != "logo_url: must be an http or https url" {
+			t.Errorf("dry_run=%v update report = %+v", dryRun, rep)
+		}
+	}
+}
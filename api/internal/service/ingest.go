@@ -0,0 +1,63 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	IngestModeURL  = "url"
+	IngestModePush = "push"
+)
+
+func newStreamKey() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "sk_" + hex.EncodeToString(b)
+}
+
+func (s *Service) streamKeyResponse(st model.Stream) map[string]string {
+	publish := strings.TrimSuffix(s.options().RTMPPublishURL, "/") + "/" + st.StreamKey
+	return map[string]string{"stream_id": st.ID, "stream_key": st.StreamKey, "publish_url": publish}
+}
+
+func (s *Service) StreamKey(id string) (map[string]string, error) {
+	st, ok := s.repo.GetStream(id)
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	if st.IngestMode != IngestModePush {
+		return nil, ErrNotPushIngest
+	}
+	return s.streamKeyResponse(st), nil
+}
+
+func (s *Service) RotateStreamKey(id string) (map[string]string, error) {
+	st, ok := s.repo.GetStream(id)
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	if st.IngestMode != IngestModePush {
+		return nil, ErrNotPushIngest
+	}
+	st, _ = s.repo.UpdateStream(id, func(st *model.Stream) { st.StreamKey = newStreamKey() })
+	log.Printf("ingest: stream key rotated for %s", id)
+	return s.streamKeyResponse(st), nil
+}
+
+func (s *Service) AuthorizeIngest(key, remote string) (map[string]string, error) {
+	st, ok := s.repo.FindStreamByKey(key)
+	if !ok || st.IngestMode != IngestModePush {
+		log.Printf("ingest: rejected invalid stream key from %s", remote)
+		return nil, ErrInvalidStreamKey
+	}
+	if !isOnAir(st.Status) {
+		log.Printf("ingest: rejected publish to %s from %s: stream is %s", st.ID, remote, st.Status)
+		return nil, ErrStreamNotLive
+	}
+	return map[string]string{"stream_id": st.ID}, nil
+}
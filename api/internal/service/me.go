@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+
+	"streamweb/api/internal/m3u"
+	"streamweb/api/internal/model"
+)
+
+const SessionSourceLauncher = "launcher"
+
+func newPlaylistKey() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "pk_" + hex.EncodeToString(b)
+}
+
+func (s *Service) playlistKey(uid string) (string, error) {
+	u, ok := s.repo.GetUser(uid)
+	if !ok {
+		return "", ErrUserNotFound
+	}
+	if u.PlaylistKey != "" {
+		return u.PlaylistKey, nil
+	}
+	u, _ = s.repo.UpdateUser(uid, func(u *model.User) {
+		if u.PlaylistKey == "" {
+			u.PlaylistKey = newPlaylistKey()
+		}
+	})
+	return u.PlaylistKey, nil
+}
+
+func (s *Service) RotatePlaylistKey(uid string) (map[string]string, error) {
+	if _, ok := s.repo.GetUser(uid); !ok {
+		return nil, ErrUserNotFound
+	}
+	u, _ := s.repo.UpdateUser(uid, func(u *model.User) { u.PlaylistKey = newPlaylistKey() })
+	for _, ss := range s.repo.ListUserSessions(uid) {
+		if ss.Source == SessionSourceLauncher && ss.State == "active" {
+			s.repo.UpdateSessionState(ss.ID, "stopped")
+		}
+	}
+	log.Printf("playlist: launcher key rotated for %s", uid)
+	return map[string]string{"user_id": uid, "launch_base_url": s.options().PublicBaseURL + "/launch/" + u.PlaylistKey + "/"}, nil
+}
+
+func (s *Service) PlaylistKeyUser(key string) (string, error) {
+	u, ok := s.repo.FindUserByPlaylistKey(key)
+	if !ok {
+		return "", ErrInvalidPlaylistKey
+	}
+	if u.Status != "active" {
+		return "", ErrUserInactive
+	}
+	return u.ID, nil
+}
+
+func channelID(st model.Stream) string { return firstNonEmpty(st.ExternalID, st.ID) }
+
+func (s *Service) userStreams() []model.Stream {
+	var live []model.Stream
+	for _, st := range s.repo.ListStreams() {
+		if st.Status == "live" {
+			live = append(live, st)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].Group != live[j].Group {
+			return live[i].Group < live[j].Group
+		}
+		return live[i].Name < live[j].Name
+	})
+	return live
+}
+
+func (s *Service) UserPlaylist(uid string) ([]byte, error) {
+	key, err := s.playlistKey(uid)
+	if err != nil {
+		return nil, err
+	}
+	base := s.options().PublicBaseURL
+	live := s.userStreams()
+	entries := make([]m3u.Entry, 0, len(live))
+	for _, st := range live {
+		attrs := map[string]string{"tvg-id": channelID(st), "tvg-name": st.Name}
+		if st.LogoURL != "" {
+			attrs["tvg-logo"] = st.LogoURL
+		}
+		if st.Group != "" {
+			attrs["group-title"] = st.Group
+		}
+		entries = append(entries, m3u.Entry{
+			Duration: -1,
+			Title:    firstNonEmpty(st.Name, st.ID),
+			Attrs:    attrs,
+			URL:      fmt.Sprintf("%s/launch/%s/%s", base, key, st.ID),
+		})
+	}
+	var buf bytes.Buffer
+	header := map[string]string{"x-tvg-url": base + "/epg.xml?key=" + key}
+	if err := m3u.Write(&buf, header, entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Service) Launch(key, streamID, ip, userAgent string) (map[string]string, error) {
+	uid, err := s.PlaylistKeyUser(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, ss := range s.repo.ListUserSessions(uid) {
+		if ss.Source != SessionSourceLauncher || ss.State != "active" || ss.IP != ip || ss.UserAgent != userAgent {
+			continue
+		}
+		if ss.StreamID == streamID {
+			s.repo.TouchSession(ss.ID)
+			return s.playbackGrant(ss), nil
+		}
+		s.repo.UpdateSessionState(ss.ID, "stopped")
+	}
+	return s.startSession(uid, PlaybackRequest{StreamID: streamID, IP: ip, UserAgent: userAgent}, SessionSourceLauncher)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+type Options struct {
+	PublicBaseURL      string
+	PlayTokenTTL       time.Duration
+	LauncherTokenTTL   time.Duration
+	FFmpegPath         string
+	FFprobePath        string
+	DefaultBillingMode string
+	RTMPPublishURL     string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PublicBaseURL:      "http://localhost:8088",
+		PlayTokenTTL:       90 * time.Second,
+		LauncherTokenTTL:   6 * time.Hour,
+		DefaultBillingMode: BillingModeHeartbeat,
+		RTMPPublishURL:     "rtmp://localhost:1935/live/",
+	}
+}
+
+func (s *Service) Configure(o Options) error {
+	if o.DefaultBillingMode == "" {
+		o.DefaultBillingMode = BillingModeHeartbeat
+	}
+	if o.RTMPPublishURL == "" {
+		o.RTMPPublishURL = DefaultOptions().RTMPPublishURL
+	}
+	if err := validateBillingMode(o.DefaultBillingMode); err != nil {
+		return err
+	}
+	if o.PlayTokenTTL <= 0 || o.LauncherTokenTTL <= 0 {
+		return errors.New("token ttls must be positive")
+	}
+	s.opts.Store(&o)
+	return nil
+}
+
+func (s *Service) options() Options { return *s.opts.Load() }
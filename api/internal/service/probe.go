@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/hls"
+	"streamweb/api/internal/model"
+)
+
+const (
+	probeTimeout      = 10 * time.Second
+	probeMaxPlaylist  = 1 << 20
+	defaultFFprobeBin = "ffprobe"
+)
+
+var packagableCodecs = map[string]bool{"h264": true, "hevc": true}
+
+var hlsCodecNames = map[string]string{"avc1": "h264", "avc3": "h264", "hvc1": "hevc", "hev1": "hevc", "mp4a": "aac", "ac-3": "ac3", "ec-3": "eac3", "opus": "opus"}
+
+type ProbeReport struct {
+	URL         string   `json:"url"`
+	Method      string   `json:"method"`
+	OK          bool     `json:"ok"`
+	Live        bool     `json:"live"`
+	VideoCodec  string   `json:"video_codec,omitempty"`
+	AudioCodec  string   `json:"audio_codec,omitempty"`
+	Width       int      `json:"width,omitempty"`
+	Height      int      `json:"height,omitempty"`
+	BitrateKbps int      `json:"bitrate_kbps,omitempty"`
+	Variants    int      `json:"variants,omitempty"`
+	Problems    []string `json:"problems"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+type StreamProbe struct {
+	StreamID string        `json:"stream_id"`
+	OK       bool          `json:"ok"`
+	ProbedAt time.Time     `json:"probed_at"`
+	Sources  []ProbeReport `json:"sources"`
+}
+
+type ProbeFailedError struct {
+	Probe StreamProbe
+}
+
+func (e *ProbeFailedError) Error() string { return "ingest probe failed" }
+func (e *ProbeFailedError) Unwrap() error { return ErrProbeFailed }
+
+func (s *Service) ProbeStream(ctx context.Context, id string) (StreamProbe, error) {
+	st, ok := s.repo.GetStream(id)
+	if !ok {
+		return StreamProbe{}, ErrStreamNotFound
+	}
+	return s.probe(ctx, st), nil
+}
+
+func (s *Service) probe(ctx context.Context, st model.Stream) StreamProbe {
+	out := StreamProbe{StreamID: st.ID, ProbedAt: time.Now().UTC(), Sources: []ProbeReport{}}
+	if st.IngestMode == IngestModePush {
+		out.OK = true
+		out.Sources = append(out.Sources, ProbeReport{Method: "push", OK: true, Problems: []string{},
+			Warnings: []string{"push ingest is checked when the encoder publishes"}})
+		return out
+	}
+	sources := append([]string{st.IngestURL}, st.BackupIngestURLs...)
+	for _, src := range sources {
+		if src == "" {
+			continue
+		}
+		rep := s.probeSource(ctx, src)
+		checkProbe(&rep, st)
+		out.OK = out.OK || rep.OK
+		out.Sources = append(out.Sources, rep)
+	}
+	if len(out.Sources) == 0 {
+		out.Sources = append(out.Sources, ProbeReport{Method: "none", Problems: []string{"ingest_url is empty"}})
+	}
+	return out
+}
+
+func (s *Service) probeSource(ctx context.Context, src string) ProbeReport {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	u, err := url.Parse(src)
+	if err != nil {
+		return ProbeReport{URL: src, Method: "none", Problems: []string{"invalid url: " + err.Error()}}
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && strings.HasSuffix(u.Path, ".m3u8") {
+		return probeM3U8(ctx, src)
+	}
+	return s.probeFFprobe(ctx, src)
+}
+
+func fetchPlaylist(ctx context.Context, src string) (hls.Playlist, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return hls.Playlist{}, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return hls.Playlist{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return hls.Playlist{}, fmt.Errorf("GET %s: status %d", src, res.StatusCode)
+	}
+	pl, ok := hls.Parse(io.LimitReader(res.Body, probeMaxPlaylist))
+	if !ok {
+		return hls.Playlist{}, fmt.Errorf("GET %s: not an HLS playlist", src)
+	}
+	return pl, nil
+}
+
+func probeM3U8(ctx context.Context, src string) ProbeReport {
+	rep := ProbeReport{URL: src, Method: "m3u8", Problems: []string{}}
+	pl, err := fetchPlaylist(ctx, src)
+	if err != nil {
+		rep.Problems = append(rep.Problems, err.Error())
+		return rep
+	}
+	if pl.Master {
+		rep.Variants = len(pl.Variants)
+		if len(pl.Variants) == 0 {
+			rep.Problems = append(rep.Problems, "master playlist has no variants")
+			return rep
+		}
+		best := pl.Variants[0]
+		for _, v := range pl.Variants[1:] {
+			if v.Bandwidth > best.Bandwidth {
+				best = v
+			}
+		}
+		rep.Width, rep.Height, rep.BitrateKbps = best.Width, best.Height, best.Bandwidth/1000
+		for _, c := range best.Codecs {
+			name := hlsCodecNames[strings.SplitN(c, ".", 2)[0]]
+			switch name {
+			case "h264", "hevc":
+				rep.VideoCodec = name
+			case "":
+			default:
+				rep.AudioCodec = name
+			}
+		}
+		if len(best.Codecs) == 0 {
+			rep.Warnings = append(rep.Warnings, "variant does not advertise CODECS")
+		}
+		base, _ := url.Parse(src)
+		ref, err := url.Parse(best.URI)
+		if err != nil {
+			rep.Problems = append(rep.Problems, "invalid variant uri: "+best.URI)
+			return rep
+		}
+		if pl, err = fetchPlaylist(ctx, base.ResolveReference(ref).String()); err != nil {
+			rep.Problems = append(rep.Problems, err.Error())
+			return rep
+		}
+	} else {
+		rep.Warnings = append(rep.Warnings, "media playlist without master: codecs and resolution unknown")
+	}
+	if pl.Segments == 0 {
+		rep.Problems = append(rep.Problems, "media playlist has no segments")
+	}
+	rep.Live = !pl.Ended && pl.Type != "VOD"
+	return rep
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		BitRate   string `json:"bit_rate"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		BitRate  string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+func (s *Service) probeFFprobe(ctx context.Context, src string) ProbeReport {
+	rep := ProbeReport{URL: src, Method: "ffprobe", Problems: []string{}}
+	bin := s.options().FFprobePath
+	if bin == "" {
+		bin = defaultFFprobeBin
+	}
+	cmd := exec.CommandContext(ctx, bin, "-v", "error", "-show_streams", "-show_format", "-of", "json", src)
+	raw, err := cmd.Output()
+	if err != nil {
+		msg := err.Error()
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			msg = strings.TrimSpace(string(ee.Stderr))
+		}
+		rep.Problems = append(rep.Problems, "ffprobe: "+msg)
+		return rep
+	}
+	var out ffprobeOutput
+	if err := json.Unmarshal(raw, &out); err != nil {
+		rep.Problems = append(rep.Problems, "ffprobe: "+err.Error())
+		return rep
+	}
+	bitrate, _ := strconv.Atoi(out.Format.BitRate)
+	streamsBitrate := 0
+	for _, st := range out.Streams {
+		switch st.CodecType {
+		case "video":
+			if rep.VideoCodec == "" {
+				rep.VideoCodec, rep.Width, rep.Height = st.CodecName, st.Width, st.Height
+			}
+		case "audio":
+			if rep.AudioCodec == "" {
+				rep.AudioCodec = st.CodecName
+			}
+		}
+		b, _ := strconv.Atoi(st.BitRate)
+		streamsBitrate += b
+	}
+	if bitrate == 0 {
+		bitrate = streamsBitrate
+	}
+	rep.BitrateKbps = bitrate / 1000
+	if rep.VideoCodec == "" {
+		rep.Problems = append(rep.Problems, "no video stream")
+	}
+	duration, _ := strconv.ParseFloat(out.Format.Duration, 64)
+	rep.Live = duration == 0
+	return rep
+}
+
+func checkProbe(rep *ProbeReport, st model.Stream) {
+	if rep.Method != "none" && len(rep.Problems) == 0 && !rep.Live {
+		rep.Problems = append(rep.Problems, "source is not live (finite duration or ended playlist)")
+	}
+	passthrough := len(st.ABRProfiles) == 1 && st.ABRProfiles[0].Codec == "copy"
+	if passthrough && rep.VideoCodec != "" && !packagableCodecs[rep.VideoCodec] {
+		rep.Problems = append(rep.Problems, fmt.Sprintf("video codec %s cannot be packaged without transcoding; use an h264 or hevc ladder", rep.VideoCodec))
+	}
+	if !passthrough && rep.Height > 0 {
+		for _, p := range st.ABRProfiles {
+			if p.Height > rep.Height {
+				rep.Warnings = append(rep.Warnings, fmt.Sprintf("rendition %s (%dp) upscales the %dp source", p.Name, p.Height, rep.Height))
+			}
+		}
+	}
+	if len(rep.Problems) == 0 && rep.BitrateKbps == 0 {
+		rep.Warnings = append(rep.Warnings, "source bitrate unknown")
+	}
+	rep.OK = len(rep.Problems) == 0
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+const RecordingsPrefix = "recordings"
+
+type RecordingReport struct {
+	DurationSec  float64 `json:"duration_sec"`
+	SegmentCount int     `json:"segment_count"`
+	SizeBytes    int64   `json:"size_bytes"`
+	Failed       bool    `json:"failed"`
+}
+
+func recordingPrefix(id string) string { return RecordingsPrefix + "/" + id }
+
+func assetPrefix(ss model.Session) string {
+	if ss.ClipID != "" {
+		return clipPrefix(ss.ClipID)
+	}
+	if ss.RecordingID != "" {
+		return recordingPrefix(ss.RecordingID)
+	}
+	return ss.StreamID
+}
+
+func (s *Service) OpenRecording(streamID string) (model.Recording, error) {
+	st, ok := s.repo.GetStream(streamID)
+	if !ok {
+		return model.Recording{}, ErrStreamNotFound
+	}
+	if !st.RecordingEnabled {
+		return model.Recording{}, ErrRecordingDisabled
+	}
+	for _, rec := range s.repo.ListRecordings(streamID) {
+		if rec.Status == "recording" {
+			return rec, nil
+		}
+	}
+	rate := st.RecordingPointsRate
+	if rate == 0 {
+		rate = st.PointsRate
+	}
+	now := time.Now().UTC()
+	rec := s.repo.CreateRecording(model.Recording{
+		StreamID:   st.ID,
+		Title:      fmt.Sprintf("%s %s", st.Name, now.Format("2006-01-02 15:04 MST")),
+		Status:     "recording",
+		StartedAt:  now,
+		PointsRate: rate,
+	})
+	return rec, nil
+}
+
+func (s *Service) FinalizeRecording(id string, rep RecordingReport) (model.Recording, error) {
+	rec, ok := s.repo.UpdateRecording(id, func(rec *model.Recording) {
+		rec.EndedAt = time.Now().UTC()
+		rec.DurationSec = rep.DurationSec
+		rec.SegmentCount = rep.SegmentCount
+		rec.SizeBytes = rep.SizeBytes
+		rec.Status = "ready"
+		if rep.Failed || rep.SegmentCount == 0 {
+			rec.Status = "failed"
+		}
+	})
+	if !ok {
+		return model.Recording{}, ErrRecordingNotFound
+	}
+	return rec, nil
+}
+
+func (s *Service) ListRecordings(streamID string, admin bool, now time.Time) ([]model.Recording, error) {
+	if _, ok := s.catalogStream(streamID, admin, now); !ok {
+		return nil, ErrStreamNotFound
+	}
+	return s.repo.ListRecordings(streamID), nil
+}
+
+func (s *Service) GetRecording(id string, admin bool, now time.Time) (model.Recording, error) {
+	rec, ok := s.repo.GetRecording(id)
+	if !ok {
+		return model.Recording{}, ErrRecordingNotFound
+	}
+	if _, ok := s.catalogStream(rec.StreamID, admin, now); !ok {
+		return model.Recording{}, ErrRecordingNotFound
+	}
+	return rec, nil
+}
+
+func (s *Service) DeleteRecording(ctx context.Context, id string) error {
+	if _, ok := s.repo.GetRecording(id); !ok {
+		return ErrRecordingNotFound
+	}
+	if s.segments != nil {
+		if err := s.deletePrefix(ctx, recordingPrefix(id)+"/"); err != nil {
+			return storageError(err)
+		}
+	}
+	s.repo.DeleteRecording(id)
+	return nil
+}
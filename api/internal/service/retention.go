@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"log"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+type RetentionPolicy struct {
+	DVRMargin   time.Duration
+	OrphanGrace time.Duration
+}
+
+type RetentionReport struct {
+	Streams        int   `json:"streams"`
+	DeletedObjects int   `json:"deleted_objects"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+}
+
+type retentionStats struct {
+	runs           atomic.Int64
+	deletedObjects atomic.Int64
+	reclaimedBytes atomic.Int64
+}
+
+func retentionWindow(st model.Stream) time.Duration {
+	window := time.Duration(st.PlaylistWindowMinutes) * time.Minute
+	if window <= 0 {
+		window = 2 * time.Minute
+	}
+	if dvr := time.Duration(st.DVRWindowMinutes) * time.Minute; dvr > window {
+		window = dvr
+	}
+	seg := time.Duration(st.SegmentDurationSec) * time.Second
+	if seg <= 0 {
+		seg = defaultSegmentDuration
+	}
+	return window + seg
+}
+
+func (s *Service) RunRetention(ctx context.Context, interval time.Duration, policy RetentionPolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		rep, err := s.EnforceRetention(ctx, policy, time.Now())
+		if err != nil {
+			log.Printf("retention: %v", err)
+			continue
+		}
+		if rep.DeletedObjects > 0 {
+			log.Printf("retention: deleted %d objects across %d streams, reclaimed %d bytes", rep.DeletedObjects, rep.Streams, rep.ReclaimedBytes)
+		}
+	}
+}
+
+func (s *Service) EnforceRetention(ctx context.Context, policy RetentionPolicy, now time.Time) (rep RetentionReport, err error) {
+	if s.segments == nil {
+		return rep, nil
+	}
+	objects, err := s.segments.List(ctx, "")
+	if err != nil {
+		return rep, err
+	}
+	streams := map[string]model.Stream{}
+	for _, st := range s.repo.ListStreams() {
+		streams[st.ID] = st
+	}
+	touched := map[string]bool{}
+	defer func() {
+		rep.Streams = len(touched)
+		s.retention.runs.Add(1)
+		s.retention.deletedObjects.Add(int64(rep.DeletedObjects))
+		s.retention.reclaimedBytes.Add(rep.ReclaimedBytes)
+	}()
+	for _, obj := range objects {
+		id, _, ok := strings.Cut(obj.Key, "/")
+		if !ok || id == RecordingsPrefix || id == ClipsPrefix {
+			continue
+		}
+		st, known := streams[id]
+		if known && s.clipJobs.busy(id) {
+			continue
+		}
+		isPlaylist := path.Ext(obj.Key) == ".m3u8"
+		var keep time.Duration
+		switch {
+		case !known:
+			keep = policy.OrphanGrace
+		case st.Status == "live":
+			if isPlaylist {
+				continue
+			}
+			keep = retentionWindow(st) + policy.DVRMargin
+		default:
+			keep = max(retentionWindow(st)+policy.DVRMargin, policy.OrphanGrace)
+		}
+		if !obj.ModTime.Before(now.Add(-keep)) {
+			continue
+		}
+		if err := s.segments.Delete(ctx, obj.Key); err != nil {
+			return rep, err
+		}
+		touched[id] = true
+		rep.DeletedObjects++
+		rep.ReclaimedBytes += obj.Size
+	}
+	return rep, nil
+}
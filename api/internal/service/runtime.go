@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	workerHeartbeatTimeout  = 30 * time.Second
+	manifestStaleSegments   = 3
+	defaultSegmentDuration  = 4 * time.Second
+	minManifestStaleTimeout = 10 * time.Second
+)
+
+func desiredState(st model.Stream) string {
+	if isOnAir(st.Status) {
+		return "running"
+	}
+	return "stopped"
+}
+
+func manifestStaleAfter(st model.Stream) time.Duration {
+	seg := time.Duration(st.SegmentDurationSec) * time.Second
+	if seg <= 0 {
+		seg = defaultSegmentDuration
+	}
+	if d := seg * manifestStaleSegments; d > minManifestStaleTimeout {
+		return d
+	}
+	return minManifestStaleTimeout
+}
+
+func (s *Service) restartStream(id, actor string, changed []string) {
+	rt := s.repo.UpdateRuntime(id, func(rt *model.StreamRuntime) { rt.ConfigGeneration++ })
+	s.audit(actor, "stream.restart", id, fmt.Sprintf("config generation %d: %s changed", rt.ConfigGeneration, strings.Join(changed, ", ")))
+}
+
+func (s *Service) WorkerHeartbeat(workerID string, reports []model.StreamRuntime) (map[string]any, error) {
+	if workerID == "" {
+		return nil, invalidf("worker_id required")
+	}
+	desired, unknown := make([]map[string]any, 0, len(reports)), []string{}
+	for _, rep := range reports {
+		rep.WorkerID = workerID
+		rt, err := s.ReportRuntime(rep.StreamID, rep)
+		if errors.Is(err, ErrStreamNotFound) {
+			unknown = append(unknown, rep.StreamID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		desired = append(desired, map[string]any{"stream_id": rt.StreamID, "desired_state": rt.DesiredState, "config_generation": rt.ConfigGeneration})
+	}
+	return map[string]any{"worker_id": workerID, "streams": desired, "unknown": unknown}, nil
+}
+
+func (s *Service) ReportRuntime(streamID string, rep model.StreamRuntime) (model.StreamRuntime, error) {
+	if rep.WorkerID == "" {
+		return model.StreamRuntime{}, invalidf("worker_id required")
+	}
+	st, ok := s.repo.GetStream(streamID)
+	if !ok || !st.DeletedAt.IsZero() {
+		return model.StreamRuntime{}, ErrStreamNotFound
+	}
+	now := time.Now().UTC()
+	rt := s.repo.UpdateRuntime(streamID, func(rt *model.StreamRuntime) {
+		rt.DesiredState = desiredState(st)
+		rt.WorkerID = rep.WorkerID
+		rt.LastHeartbeatAt = now
+		if rep.ActualState != "" {
+			rt.ActualState = rep.ActualState
+		}
+		if !rep.LastManifestAt.IsZero() {
+			rt.LastManifestAt = rep.LastManifestAt.UTC()
+		}
+		rt.IngestBitrateKbps = rep.IngestBitrateKbps
+		rt.SegmentCount = rep.SegmentCount
+		rt.LastError = rep.LastError
+		rt.ActiveSource = rep.ActiveSource
+	})
+	switch {
+	case st.Status == StateStarting && rt.ActualState == "running":
+		s.commitTransition(st, StateLive, "worker:"+rep.WorkerID, "worker reported running", 0)
+	case st.Status == StateStopping && rt.ActualState != "running":
+		s.commitTransition(st, StatePaused, "worker:"+rep.WorkerID, "worker reported "+rt.ActualState, 0)
+	}
+	return rt, nil
+}
+
+func (s *Service) StreamRuntime(id string) (map[string]any, bool) {
+	st, ok := s.repo.GetStream(id)
+	if !ok {
+		return nil, false
+	}
+	rt, ok := s.repo.GetRuntime(id)
+	if !ok {
+		rt = model.StreamRuntime{StreamID: id}
+	}
+	rt.DesiredState = desiredState(st)
+	reasons := degradedReasons(st, rt, time.Now())
+	var lastManifest any
+	if !rt.LastManifestAt.IsZero() {
+		lastManifest = rt.LastManifestAt
+	}
+	return map[string]any{
+		"stream":           st,
+		"runtime":          rt,
+		"current_viewers":  s.repo.ActiveViewerCount(id),
+		"last_manifest_at": lastManifest,
+		"degraded":         len(reasons) > 0,
+		"degraded_reasons": reasons,
+	}, true
+}
+
+func degradedReasons(st model.Stream, rt model.StreamRuntime, now time.Time) []string {
+	reasons := []string{}
+	if st.Status != "live" {
+		return reasons
+	}
+	if rt.LastHeartbeatAt.IsZero() {
+		reasons = append(reasons, "no worker heartbeat")
+	} else if now.Sub(rt.LastHeartbeatAt) > workerHeartbeatTimeout {
+		reasons = append(reasons, "worker heartbeat stale")
+	}
+	if st.IngestMode == IngestModePush && rt.ActualState == "waiting" {
+		reasons = append(reasons, "no publisher connected")
+	}
+	if rt.LastManifestAt.IsZero() {
+		reasons = append(reasons, "no manifest written")
+	} else if now.Sub(rt.LastManifestAt) > manifestStaleAfter(st) {
+		reasons = append(reasons, "manifest stale")
+	}
+	return reasons
+}
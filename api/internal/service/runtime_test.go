@@ -0,0 +1,50 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+func TestWorkerHeartbeatSkipsUnknownStreams(t *testing.T) {
+	svc := newTestService()
+	for _, st := range []model.Stream{
+		{ID: "live", Name: "Live", Status: StateLive},
+		{ID: "gone", Name: "Gone", Status: StateDisabled, DeletedAt: time.Now().UTC()},
+	} {
+		if _, err := svc.repo.CreateStream(st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := svc.WorkerHeartbeat("w1", []model.StreamRuntime{
+		{StreamID: "missing", ActualState: "running"},
+		{StreamID: "live", ActualState: "running", SegmentCount: 7},
+		{StreamID: "gone", ActualState: "running"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{{"stream_id": "live", "desired_state": "running", "config_generation": 0}}
+	if !reflect.DeepEqual(resp["streams"], want) {
+		t.Errorf("streams = %v, want %v", resp["streams"], want)
+	}
+	if got := resp["unknown"]; !reflect.DeepEqual(got, []string{"missing", "gone"}) {
+		t.Errorf("unknown = %v, want [missing gone]", got)
+	}
+	if rt, ok := svc.repo.GetRuntime("live"); !ok || rt.WorkerID != "w1" || rt.SegmentCount != 7 {
+		t.Errorf("runtime of live = %+v, %v", rt, ok)
+	}
+	if _, ok := svc.repo.GetRuntime("gone"); ok {
+		t.Error("heartbeat wrote a runtime for a deleted stream")
+	}
+
+	resp, err = svc.WorkerHeartbeat("w1", nil)
+	if err != nil || len(resp["streams"].([]map[string]any)) != 0 || len(resp["unknown"].([]string)) != 0 {
+		t.Errorf("empty heartbeat = %v, %v", resp, err)
+	}
+	if _, err := svc.WorkerHeartbeat("", nil); err == nil {
+		t.Error("heartbeat without worker_id succeeded")
+	}
+}
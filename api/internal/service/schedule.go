@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"streamweb/api/internal/cron"
+	"streamweb/api/internal/model"
+)
+
+const (
+	maxScheduleMinutes  = 7 * 24 * 60
+	maxOccurrences      = 1000
+	scheduleActor       = "scheduler"
+	programListingAhead = 24 * time.Hour
+)
+
+type ScheduleRequest struct {
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Category        string    `json:"category"`
+	StartAt         time.Time `json:"start_at"`
+	Cron            string    `json:"cron"`
+	Timezone        string    `json:"timezone"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Enabled         *bool     `json:"enabled"`
+	CreatedBy       string    `json:"-"`
+}
+
+type Program struct {
+	ScheduleID  string    `json:"schedule_id,omitempty"`
+	StreamID    string    `json:"stream_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+}
+
+type StreamListing struct {
+	model.Stream
+	NowPlaying *Program `json:"now_playing,omitempty"`
+	UpNext     *Program `json:"up_next,omitempty"`
+}
+
+func (s *Service) CreateSchedule(streamID string, req ScheduleRequest, now time.Time) (model.Schedule, error) {
+	if st, ok := s.repo.GetStream(streamID); !ok || !st.DeletedAt.IsZero() {
+		return model.Schedule{}, ErrStreamNotFound
+	}
+	if req.Title == "" {
+		return model.Schedule{}, invalidf("title is required")
+	}
+	if req.DurationMinutes <= 0 || req.DurationMinutes > maxScheduleMinutes {
+		return model.Schedule{}, invalidf("duration_minutes must be between 1 and %d", maxScheduleMinutes)
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return model.Schedule{}, invalidf("unknown timezone %q", req.Timezone)
+	}
+	if (req.Cron == "") == req.StartAt.IsZero() {
+		return model.Schedule{}, invalidf("exactly one of start_at (one-off) or cron (recurring) is required")
+	}
+	if req.Cron != "" {
+		if _, err := cron.Parse(req.Cron); err != nil {
+			return model.Schedule{}, invalid(err)
+		}
+	} else if !req.StartAt.Add(time.Duration(req.DurationMinutes) * time.Minute).After(now) {
+		return model.Schedule{}, invalidf("schedule ends in the past")
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	sc := s.repo.CreateSchedule(model.Schedule{
+		StreamID:        streamID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Category:        req.Category,
+		StartAt:         req.StartAt.UTC(),
+		Cron:            req.Cron,
+		Timezone:        req.Timezone,
+		DurationMinutes: req.DurationMinutes,
+		Enabled:         enabled,
+		CreatedBy:       req.CreatedBy,
+	})
+	s.audit("user:"+req.CreatedBy, "schedule.create", streamID, fmt.Sprintf("%s: %s", sc.ID, sc.Title))
+	return sc, nil
+}
+
+func (s *Service) ListSchedules(streamID string, admin bool, now time.Time) (map[string]any, error) {
+	if _, ok := s.catalogStream(streamID, admin, now); !ok {
+		return nil, ErrStreamNotFound
+	}
+	return map[string]any{
+		"stream_id": streamID,
+		"schedules": s.repo.ListSchedules(streamID),
+		"programs":  s.Programs(streamID, now, now.Add(programListingAhead)),
+	}, nil
+}
+
+func (s *Service) GetSchedule(id string, admin bool, now time.Time) (model.Schedule, error) {
+	sc, ok := s.repo.GetSchedule(id)
+	if !ok {
+		return model.Schedule{}, ErrScheduleNotFound
+	}
+	if _, ok := s.catalogStream(sc.StreamID, admin, now); !ok {
+		return model.Schedule{}, ErrScheduleNotFound
+	}
+	return sc, nil
+}
+
+func (s *Service) DeleteSchedule(id, actor string) error {
+	sc, ok := s.repo.GetSchedule(id)
+	if !ok || !s.repo.DeleteSchedule(id) {
+		return ErrScheduleNotFound
+	}
+	s.audit("user:"+actor, "schedule.delete", sc.StreamID, fmt.Sprintf("%s: %s", sc.ID, sc.Title))
+	return nil
+}
+
+func occurrences(sc model.Schedule, from, to time.Time) []Program {
+	d := time.Duration(sc.DurationMinutes) * time.Minute
+	program := func(start time.Time) Program {
+		return Program{ScheduleID: sc.ID, StreamID: sc.StreamID, Title: sc.Title, Description: sc.Description, Category: sc.Category, StartAt: start.UTC(), EndAt: start.Add(d).UTC()}
+	}
+	if sc.Cron == "" {
+		if sc.StartAt.Before(to) && sc.StartAt.Add(d).After(from) {
+			return []Program{program(sc.StartAt)}
+		}
+		return nil
+	}
+	expr, err := cron.Parse(sc.Cron)
+	if err != nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(sc.Timezone)
+	if err != nil {
+		return nil
+	}
+	var out []Program
+	t := from.Add(-d).In(loc)
+	for len(out) < maxOccurrences {
+		if t = expr.Next(t); t.IsZero() || !t.Before(to) {
+			break
+		}
+		if t.Add(d).After(from) {
+			out = append(out, program(t))
+		}
+	}
+	return out
+}
+
+func (s *Service) Programs(streamID string, from, to time.Time) []Program {
+	out := []Program{}
+	for _, sc := range s.repo.ListSchedules(streamID) {
+		if sc.Enabled {
+			out = append(out, occurrences(sc, from, to)...)
+		}
+	}
+	for _, p := range s.repo.ListProgrammes(streamID, from, to) {
+		out = append(out, Program{StreamID: p.StreamID, Title: p.Title, Description: p.Description, Category: p.Category, StartAt: p.StartAt, EndAt: p.EndAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartAt.Before(out[j].StartAt) })
+	return out
+}
+
+func (s *Service) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.RunSchedules(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) RunSchedules(ctx context.Context, now time.Time) {
+	type start struct {
+		sc      model.Schedule
+		program Program
+	}
+	var (
+		starts []start
+		stops  []model.Schedule
+		onAir  = map[string]bool{}
+	)
+	for _, sc := range s.repo.ListSchedules("") {
+		if !sc.Enabled {
+			continue
+		}
+		if cur := occurrences(sc, now, now.Add(time.Nanosecond)); len(cur) > 0 {
+			p := cur[len(cur)-1]
+			onAir[sc.StreamID] = true
+			if p.StartAt.After(sc.LastStartedAt) {
+				starts = append(starts, start{sc, p})
+			}
+			continue
+		}
+		if !sc.LastStartedAt.IsZero() && sc.LastStoppedAt.Before(sc.LastStartedAt) {
+			stops = append(stops, sc)
+		}
+	}
+	for _, sc := range stops {
+		s.repo.UpdateSchedule(sc.ID, func(x *model.Schedule) { x.LastStoppedAt = now.UTC() })
+		st, ok := s.repo.GetStream(sc.StreamID)
+		if !ok || onAir[sc.StreamID] || !isOnAir(st.Status) {
+			continue
+		}
+		if _, err := s.TransitionStream(ctx, sc.StreamID, StatePaused, scheduleActor, fmt.Sprintf("schedule %s ended: %s", sc.ID, sc.Title), 0); err != nil {
+			s.scheduleFailed(sc, err)
+			continue
+		}
+		s.audit(scheduleActor, "schedule.stop", sc.StreamID, fmt.Sprintf("%s: %s", sc.ID, sc.Title))
+	}
+	for _, x := range starts {
+		if _, err := s.TransitionStream(ctx, x.sc.StreamID, StateLive, scheduleActor, fmt.Sprintf("schedule %s started: %s", x.sc.ID, x.sc.Title), 0); err != nil {
+			s.scheduleFailed(x.sc, err)
+			continue
+		}
+		s.repo.UpdateSchedule(x.sc.ID, func(sc *model.Schedule) {
+			sc.LastStartedAt, sc.LastError = x.program.StartAt, ""
+		})
+		s.audit(scheduleActor, "schedule.start", x.sc.StreamID, fmt.Sprintf("%s: %s until %s", x.sc.ID, x.sc.Title, x.program.EndAt.Format(time.RFC3339)))
+	}
+}
+
+func (s *Service) scheduleFailed(sc model.Schedule, err error) {
+	if sc.LastError != err.Error() {
+		log.Printf("scheduler: %s on %s: %v", sc.ID, sc.StreamID, err)
+	}
+	s.repo.UpdateSchedule(sc.ID, func(x *model.Schedule) { x.LastError = err.Error() })
+}
@@ -1,149 +1,339 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"streamweb/api/internal/auth"
+	"streamweb/api/internal/ids"
 	"streamweb/api/internal/model"
+	"streamweb/api/internal/segstore"
 	"streamweb/api/internal/store"
 )
 
+var reservedStreamIDs = map[string]bool{RecordingsPrefix: true, ClipsPrefix: true, "import": true}
+
 type Service struct {
-	repo store.Repository
+	repo      store.Repository
+	segments  segstore.SegmentStore
+	retention retentionStats
+	opts      atomic.Pointer[Options]
+	fetches   segmentActivity
+	clipJobs  clipJobs
+	ids       ids.Generator
+	tokens    *auth.Signer
 }
 
-func New(repo store.Repository) *Service { return &Service{repo: repo} }
+func New(repo store.Repository, segments segstore.SegmentStore, gen ids.Generator, tokens *auth.Signer) *Service {
+	svc := &Service{repo: repo, segments: segments, ids: gen, tokens: tokens}
+	svc.fetches.last, svc.fetches.pending = map[string]time.Time{}, map[string]bool{}
+	svc.clipJobs.active = map[string]int{}
+	opts := DefaultOptions()
+	svc.opts.Store(&opts)
+	return svc
+}
 
 func (s *Service) Login(email, password string) (map[string]any, error) {
 	u, ok := s.repo.FindUserByEmail(email)
 	if !ok || u.Password != password {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
-	tok := auth.TokenForUser(u.ID, u.Role)
+	tok := s.tokens.UserToken(u.ID, u.Role)
 	return map[string]any{"access_token": tok, "refresh_token": tok, "user": u}, nil
 }
 
 func (s *Service) Refresh(refreshToken string) (string, error) {
-	uid, role, err := auth.ParseUserToken(refreshToken)
+	uid, role, err := s.tokens.ParseUserToken(refreshToken)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	return s.tokens.UserToken(uid, role), nil
+}
+
+func (s *Service) ParseUserToken(token string) (string, string, error) {
+	uid, role, err := s.tokens.ParseUserToken(token)
 	if err != nil {
-		return "", err
+		return "", "", ErrInvalidToken
 	}
-	return auth.TokenForUser(uid, role), nil
+	return uid, role, nil
 }
 
-func (s *Service) CreateStream(st model.Stream) model.Stream {
+func defaultStream(id string) model.Stream {
+	return model.Stream{
+		ID:                    id,
+		Status:                StateDraft,
+		IngestMode:            IngestModeURL,
+		SegmentDurationSec:    defaultSegmentDurationSec,
+		PlaylistWindowMinutes: defaultPlaylistWindowMinutes,
+		MaxConcurrentSessions: defaultMaxConcurrentSessions,
+	}
+}
+
+func (s *Service) CreateStream(id string, p StreamPatch) (model.Stream, error) {
+	st := defaultStream(id)
+	p.apply(&st)
+	set(&st.ABRProfiles, p.ABRProfiles)
+	var preset string
+	set(&preset, p.ABRPreset)
+	return s.createStream(st, preset)
+}
+
+func (s *Service) createStream(st model.Stream, abrPreset string) (model.Stream, error) {
 	if st.ID == "" {
-		st.ID = fmt.Sprintf("stream-%d", time.Now().Unix())
+		st.ID = "stream-" + s.ids.New()
+	}
+	v := &validator{}
+	if err := validateInitialState(st.Status); err != nil {
+		v.add("status", "%s", err.Error())
+	}
+	st.StreamKey = ""
+	if st.IngestMode == IngestModePush {
+		st.StreamKey = newStreamKey()
+	}
+	profiles, err := resolveABR(abrPreset, st.ABRProfiles)
+	if err != nil {
+		v.abr(abrField(abrPreset), err)
 	}
-	if st.Status == "" {
-		st.Status = "draft"
+	st.ABRProfiles = profiles
+	v.stream(st)
+	if err := v.err(); err != nil {
+		return model.Stream{}, err
 	}
-	return s.repo.CreateStream(st)
+	created, err := s.repo.CreateStream(st)
+	if errors.Is(err, store.ErrAlreadyExists) {
+		return model.Stream{}, ErrStreamExists.withf("stream %q already exists", st.ID)
+	}
+	return created, err
+}
+
+func abrField(preset string) string {
+	if preset != "" {
+		return "abr_preset"
+	}
+	return "abr_profiles"
 }
 
-func (s *Service) PatchStream(id string, body map[string]any) (model.Stream, bool) {
-	return s.repo.UpdateStream(id, func(st *model.Stream) {
-		if v, ok := body["name"].(string); ok {
-			st.Name = v
+func (s *Service) PatchStream(ctx context.Context, id string, p StreamPatch, actor string, version int) (model.Stream, error) {
+	cur, ok := s.repo.GetStream(id)
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Stream{}, ErrStreamNotFound
+	}
+	if err := checkVersion(cur, version); err != nil {
+		return model.Stream{}, err
+	}
+	v := &validator{only: p.fields()}
+	profiles, hasABR, abrErr := p.resolveABR()
+	if abrErr != nil {
+		var preset string
+		set(&preset, p.ABRPreset)
+		v.abr(abrField(preset), abrErr)
+	}
+	apply := func(st *model.Stream) {
+		p.apply(st)
+		if hasABR && abrErr == nil {
+			st.ABRProfiles = profiles
 		}
-		if v, ok := body["ingest_url"].(string); ok {
-			st.IngestURL = v
+	}
+	candidate := cur
+	apply(&candidate)
+	v.stream(candidate)
+	if err := v.err(); err != nil {
+		return model.Stream{}, err
+	}
+	if candidate.Status != cur.Status {
+		from := candidate
+		from.Status = cur.Status
+		if err := checkTransition(from, candidate.Status); err != nil {
+			return model.Stream{}, err
 		}
-		if v, ok := body["status"].(string); ok {
-			st.Status = v
+	} else if isOnAir(cur.Status) {
+		if err := checkIngest(candidate); err != nil {
+			return model.Stream{}, err
+		}
+	}
+	if isOnAir(candidate.Status) && !isOnAir(cur.Status) {
+		if pr := s.probe(ctx, candidate); !pr.OK {
+			return model.Stream{}, &ProbeFailedError{Probe: pr}
 		}
-		if v, ok := body["points_rate"].(float64); ok {
-			st.PointsRate = int(v)
+	}
+	st, err := s.repo.CompareAndSwapStream(id, cur.Version, apply)
+	if err != nil {
+		return model.Stream{}, swapError(err, st, version)
+	}
+	if st.Status != cur.Status {
+		s.recordTransition(id, cur.Status, st.Status, actor, "patch")
+	}
+	if isOnAir(cur.Status) && isOnAir(st.Status) {
+		if changed := restartFields(cur, st); len(changed) > 0 {
+			s.restartStream(id, actor, changed)
 		}
-	})
+	}
+	return st, nil
 }
 
-func (s *Service) SetStreamState(id, state string) bool {
-	_, ok := s.repo.UpdateStream(id, func(st *model.Stream) { st.Status = state })
-	return ok
+func (s *Service) GetStream(id string) (model.Stream, bool) { return s.repo.GetStream(id) }
+
+type PlaybackRequest struct {
+	StreamID       string `json:"stream_id"`
+	RecordingID    string `json:"recording_id"`
+	ClipID         string `json:"clip_id"`
+	Token          string `json:"token"`
+	StartOffsetSec int    `json:"start_offset_sec"`
+	DVRMode        string `json:"dvr_mode"`
+	BillingMode    string `json:"billing_mode"`
+	IP             string `json:"-"`
+	UserAgent      string `json:"-"`
 }
 
-func (s *Service) StreamRuntime(id string) (map[string]any, bool) {
-	st, ok := s.repo.GetStream(id)
-	if !ok {
-		return nil, false
+func (s *Service) StartPlayback(req PlaybackRequest) (map[string]string, error) {
+	uid, _, err := s.tokens.ParseUserToken(req.Token)
+	if err != nil {
+		return nil, ErrInvalidToken
 	}
-	return map[string]any{"stream": st, "current_viewers": s.repo.ActiveViewerCount(id), "last_manifest_at": time.Now().UTC()}, true
+	return s.startSession(uid, req, "")
 }
 
-func (s *Service) StartPlayback(streamID, token, ip, userAgent string) (map[string]string, int, error) {
-	uid, _, err := auth.ParseUserToken(token)
+func (s *Service) startSession(uid string, req PlaybackRequest, source string) (map[string]string, error) {
+	billing, err := s.billingMode(req.BillingMode, source)
 	if err != nil {
-		return nil, 401, err
+		return nil, invalid(err)
 	}
-	st, ok := s.repo.GetStream(streamID)
-	if !ok || st.Status != "live" {
-		return nil, 400, fmt.Errorf("stream not live")
+	session := model.Session{UserID: uid, IP: req.IP, UserAgent: req.UserAgent, Source: source, BillingMode: billing}
+	maxSessions := 1
+	if req.ClipID != "" {
+		clip, ok := s.repo.GetClip(req.ClipID)
+		if !ok || clip.Status != "ready" {
+			return nil, ErrNotReady.withf("clip not ready")
+		}
+		if st, ok := s.repo.GetStream(clip.StreamID); ok {
+			maxSessions = st.MaxConcurrentSessions
+		}
+		session.StreamID, session.ClipID = clip.StreamID, clip.ID
+	} else if req.RecordingID != "" {
+		rec, ok := s.repo.GetRecording(req.RecordingID)
+		if !ok || rec.Status != "ready" {
+			return nil, ErrNotReady.withf("recording not ready")
+		}
+		if st, ok := s.repo.GetStream(rec.StreamID); ok {
+			maxSessions = st.MaxConcurrentSessions
+		}
+		session.StreamID, session.RecordingID = rec.StreamID, rec.ID
+	} else {
+		st, ok := s.repo.GetStream(req.StreamID)
+		if !ok || st.Status != StateLive {
+			return nil, ErrStreamNotLive
+		}
+		mode, err := dvrMode(st, req.StartOffsetSec, req.DVRMode)
+		if err != nil {
+			return nil, err
+		}
+		maxSessions = st.MaxConcurrentSessions
+		session.StreamID, session.TimeshiftOffsetSec, session.DVRMode = st.ID, req.StartOffsetSec, mode
 	}
 	wallet, ok := s.repo.GetWallet(uid)
 	if !ok || wallet.Balance <= 0 {
-		return nil, 402, fmt.Errorf("insufficient points")
+		return nil, ErrInsufficientPoints
 	}
-	if s.repo.ActiveUserSessionCount(uid) >= st.MaxConcurrentSessions {
-		return nil, 429, fmt.Errorf("too many concurrent sessions")
+	if s.repo.ActiveUserSessionCount(uid) >= maxSessions {
+		return nil, ErrTooManySessions
 	}
-	ss := s.repo.CreateSession(uid, streamID, ip, userAgent)
-	playToken := fmt.Sprintf("play:%s:%d", ss.ID, time.Now().Add(90*time.Second).Unix())
-	playURL := fmt.Sprintf("http://localhost:8088/play/%s/master.m3u8?token=%s", ss.ID, playToken)
-	return map[string]string{"session_id": ss.ID, "play_token": playToken, "play_url": playURL}, 200, nil
+	ss := s.repo.CreateSession(session)
+	return s.playbackGrant(ss), nil
 }
 
-func (s *Service) RenewPlayback(sessionID string) (map[string]string, int, error) {
+func (s *Service) RenewPlayback(sessionID string) (map[string]string, error) {
 	ss, ok := s.repo.GetSession(sessionID)
 	if !ok {
-		return nil, 404, fmt.Errorf("session not found")
+		return nil, ErrSessionNotFound
 	}
 	if ss.State != "active" {
-		return nil, 403, fmt.Errorf("session not active")
+		return nil, ErrSessionNotActive
 	}
 	s.repo.TouchSession(ss.ID)
-	playToken := fmt.Sprintf("play:%s:%d", ss.ID, time.Now().Add(90*time.Second).Unix())
-	playURL := fmt.Sprintf("http://localhost:8088/play/%s/master.m3u8?token=%s", ss.ID, playToken)
-	return map[string]string{"session_id": ss.ID, "play_token": playToken, "play_url": playURL}, 200, nil
+	return s.playbackGrant(ss), nil
 }
 
-func (s *Service) Heartbeat(sessionID string) (map[string]any, int) {
+func (s *Service) playbackGrant(ss model.Session) map[string]string {
+	opts := s.options()
+	ttl := opts.PlayTokenTTL
+	if ss.Source == SessionSourceLauncher {
+		ttl = opts.LauncherTokenTTL
+	}
+	playToken := s.tokens.PlayToken(ss.ID, time.Now().Add(ttl))
+	entry := "master.m3u8"
+	if clip, ok := s.repo.GetClip(ss.ClipID); ok && clip.Format == "mp4" {
+		entry = clipMP4Name
+	}
+	playURL := fmt.Sprintf("%s/play/%s/%s/%s?token=%s", opts.PublicBaseURL, ss.ID, assetPrefix(ss), entry, playToken)
+	if ss.DVRMode != "" {
+		playURL += "&dvr=" + ss.DVRMode
+	}
+	return map[string]string{"session_id": ss.ID, "play_token": playToken, "play_url": playURL}
+}
+
+func (s *Service) Heartbeat(sessionID string) (map[string]any, error) {
 	ss, ok := s.repo.GetSession(sessionID)
 	if !ok {
-		return map[string]any{"error": "session not found"}, 404
+		return nil, ErrSessionNotFound
 	}
-	st, ok := s.repo.GetStream(ss.StreamID)
-	if !ok {
-		return map[string]any{"error": "stream not found"}, 404
+	if ss.BillingMode == BillingModeSegments {
+		return nil, ErrSegmentBilled
 	}
-	remaining, err := s.repo.DeductPoints(ss.UserID, ss.StreamID, ss.ID, int64(st.PointsRate))
-	if err != nil || remaining <= 0 {
-		s.repo.UpdateSessionState(ss.ID, "blocked")
-		return map[string]any{"state": "blocked", "balance_points": remaining}, 402
+	return s.charge(ss, "heartbeat_deduction")
+}
+
+func (s *Service) sessionPointsRate(ss model.Session) (int, bool) {
+	if ss.ClipID != "" {
+		clip, ok := s.repo.GetClip(ss.ClipID)
+		return clip.PointsRate, ok
 	}
-	s.repo.TouchSession(ss.ID)
-	return map[string]any{"state": "active", "balance_points": remaining}, 200
+	if ss.RecordingID != "" {
+		rec, ok := s.repo.GetRecording(ss.RecordingID)
+		return rec.PointsRate, ok
+	}
+	st, ok := s.repo.GetStream(ss.StreamID)
+	return st.PointsRate, ok
 }
 
 func (s *Service) StopSession(sessionID string) { s.repo.UpdateSessionState(sessionID, "stopped") }
 func (s *Service) KickSession(sessionID string) { s.repo.UpdateSessionState(sessionID, "blocked") }
-func (s *Service) Metrics() map[string]int      { return s.repo.Metrics() }
 
-func (s *Service) ValidatePlaybackToken(token, sessionID string) (int, string) {
-	parts := strings.Split(token, ":")
-	if len(parts) != 3 || parts[0] != "play" || parts[1] != sessionID {
-		return 401, "invalid"
+func (s *Service) Metrics() map[string]int {
+	m := s.repo.Metrics()
+	m["retention_runs"] = int(s.retention.runs.Load())
+	m["retention_deleted_objects"] = int(s.retention.deletedObjects.Load())
+	m["retention_reclaimed_bytes"] = int(s.retention.reclaimedBytes.Load())
+	m["segment_billing_charges"] = int(s.fetches.charges.Load())
+	m["segment_sessions_expired"] = int(s.fetches.expired.Load())
+	return m
+}
+
+func (s *Service) ValidatePlaybackToken(token, sessionID string) error {
+	sid, exp, err := s.tokens.ParsePlayToken(token)
+	if err != nil || sid != sessionID {
+		return ErrInvalidToken
 	}
-	exp, _ := strconv.ParseInt(parts[2], 10, 64)
-	if time.Now().Unix() > exp {
-		return 401, "expired"
+	if time.Now().After(exp) {
+		return ErrPlayTokenExpired
 	}
 	ss, ok := s.repo.GetSession(sessionID)
 	if !ok || ss.State != "active" {
-		return 403, "blocked"
+		return ErrSessionNotActive
+	}
+	return nil
+}
+
+func (s *Service) ValidatePlaybackAsset(token, sessionID, asset string) error {
+	if err := s.ValidatePlaybackToken(token, sessionID); err != nil {
+		return err
+	}
+	ss, _ := s.repo.GetSession(sessionID)
+	if !strings.HasPrefix(asset, assetPrefix(ss)+"/") {
+		return ErrAssetMismatch
 	}
-	return 200, "ok"
+	return nil
 }
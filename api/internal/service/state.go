@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/store"
+)
+
+const (
+	StateDraft    = "draft"
+	StateLive     = "live"
+	StatePaused   = "paused"
+	StateDisabled = "disabled"
+	StateStarting = "starting"
+	StateStopping = "stopping"
+
+	stateHistoryLimit = 50
+)
+
+var streamStates = []string{StateDraft, StateStarting, StateLive, StateStopping, StatePaused, StateDisabled}
+
+var streamTransitions = map[string][]string{
+	StateDraft:    {StateStarting, StateLive, StateDisabled},
+	StateStarting: {StateLive, StatePaused, StateDisabled},
+	StateLive:     {StateStopping, StatePaused, StateDisabled},
+	StateStopping: {StatePaused, StateDisabled},
+	StatePaused:   {StateStarting, StateLive, StateDisabled},
+	StateDisabled: {StateDraft, StatePaused},
+}
+
+var initialStates = []string{StateDraft, StatePaused, StateDisabled}
+
+type TransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move stream from %s to %s", e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error { return ErrInvalidTransition }
+
+func allowedTransitions(from string) []string {
+	if next, ok := streamTransitions[from]; ok {
+		return next
+	}
+	return []string{}
+}
+
+func isOnAir(state string) bool { return state == StateLive || state == StateStarting }
+
+func validateInitialState(state string) error {
+	if !slices.Contains(initialStates, state) {
+		return fmt.Errorf("status must be one of %s when creating a stream; use /streams/{id}/state to go live", strings.Join(initialStates, ", "))
+	}
+	return nil
+}
+
+func checkIngest(st model.Stream) error {
+	if st.IngestMode == IngestModePush {
+		if st.StreamKey == "" {
+			return ErrIngestNotReady.withf("push stream has no stream key")
+		}
+		return nil
+	}
+	u, err := url.Parse(st.IngestURL)
+	if st.IngestURL == "" || err != nil || u.Scheme == "" || (u.Host == "" && u.Scheme != "file") {
+		return ErrIngestNotReady.withf("ingest_url must be a valid absolute url before the stream can go live")
+	}
+	return nil
+}
+
+func checkTransition(st model.Stream, to string) error {
+	if !slices.Contains(streamStates, to) {
+		return invalidf("unknown state %q, must be one of %s", to, strings.Join(streamStates, ", "))
+	}
+	if !slices.Contains(allowedTransitions(st.Status), to) {
+		return &TransitionError{From: st.Status, To: to, Allowed: allowedTransitions(st.Status)}
+	}
+	if isOnAir(to) {
+		return checkIngest(st)
+	}
+	return nil
+}
+
+func (s *Service) TransitionStream(ctx context.Context, id, to, actor, reason string, version int) (model.Stream, error) {
+	cur, ok := s.repo.GetStream(id)
+	if !ok || !cur.DeletedAt.IsZero() {
+		return model.Stream{}, ErrStreamNotFound
+	}
+	if err := checkVersion(cur, version); err != nil {
+		return model.Stream{}, err
+	}
+	if cur.Status == to {
+		return cur, nil
+	}
+	if err := checkTransition(cur, to); err != nil {
+		return model.Stream{}, err
+	}
+	if isOnAir(to) && !isOnAir(cur.Status) {
+		if pr := s.probe(ctx, cur); !pr.OK {
+			return model.Stream{}, &ProbeFailedError{Probe: pr}
+		}
+	}
+	return s.commitTransition(cur, to, actor, reason, version)
+}
+
+func (s *Service) commitTransition(cur model.Stream, to, actor, reason string, version int) (model.Stream, error) {
+	raced := false
+	apply := func(st *model.Stream) {
+		if raced = st.Status != cur.Status; !raced {
+			st.Status = to
+		}
+	}
+	var st model.Stream
+	var err error
+	if version != 0 {
+		st, err = s.repo.CompareAndSwapStream(cur.ID, version, apply)
+	} else if updated, ok := s.repo.UpdateStream(cur.ID, apply); ok {
+		st = updated
+	} else {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		return model.Stream{}, swapError(err, st, version)
+	}
+	if raced {
+		return model.Stream{}, &TransitionError{From: st.Status, To: to, Allowed: allowedTransitions(st.Status)}
+	}
+	s.recordTransition(cur.ID, cur.Status, to, actor, reason)
+	return st, nil
+}
+
+func (s *Service) recordTransition(streamID, from, to, actor, reason string) {
+	s.repo.AppendStateChange(model.StreamStateChange{StreamID: streamID, From: from, To: to, Actor: actor, Reason: reason, At: time.Now().UTC()})
+}
+
+func (s *Service) StreamState(id string) (map[string]any, error) {
+	st, ok := s.repo.GetStream(id)
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	return map[string]any{
+		"stream_id": st.ID,
+		"state":     st.Status,
+		"allowed":   allowedTransitions(st.Status),
+		"history":   s.repo.ListStateHistory(id, stateHistoryLimit),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"streamweb/api/internal/model"
+)
+
+func TestStreamTransitions(t *testing.T) {
+	for _, from := range streamStates {
+		for _, to := range streamStates {
+			want := slices.Contains(streamTransitions[from], to)
+			st := model.Stream{Status: from, IngestMode: IngestModeURL, IngestURL: "rtmp://in/live"}
+			err := checkTransition(st, to)
+			if want && err != nil {
+				t.Errorf("%s -> %s = %v, want allowed", from, to, err)
+			}
+			var terr *TransitionError
+			if !want && (!errors.As(err, &terr) || !errors.Is(err, ErrInvalidTransition) || !slices.Equal(terr.Allowed, allowedTransitions(from))) {
+				t.Errorf("%s -> %s = %v, want transition error", from, to, err)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		from, to string
+		ok       bool
+	}{
+		{StateDraft, StateLive, true},
+		{StateDraft, StatePaused, false},
+		{StateLive, StateDraft, false},
+		{StateLive, StateStopping, true},
+		{StateStopping, StateLive, false},
+		{StatePaused, StateStarting, true},
+		{StateDisabled, StateLive, false},
+		{StateDisabled, StateDraft, true},
+	} {
+		if got := slices.Contains(allowedTransitions(tc.from), tc.to); got != tc.ok {
+			t.Errorf("allowedTransitions(%s) contains %s = %v, want %v", tc.from, tc.to, got, tc.ok)
+		}
+	}
+	if got := allowedTransitions("archived"); got == nil || len(got) != 0 {
+		t.Errorf("allowedTransitions(unknown) = %#v, want empty", got)
+	}
+	if err := checkTransition(model.Stream{Status: StateDraft}, "archived"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("unknown target state = %v", err)
+	}
+}
+
+func TestTransitionChecksIngest(t *testing.T) {
+	for _, tc := range []struct {
+		st model.Stream
+		ok bool
+	}{
+		{model.Stream{Status: StatePaused, IngestMode: IngestModeURL, IngestURL: "srt://in:9000"}, true},
+		{model.Stream{Status: StatePaused, IngestMode: IngestModeURL, IngestURL: "file:///srv/loop.ts"}, true},
+		{model.Stream{Status: StatePaused, IngestMode: IngestModeURL}, false},
+		{model.Stream{Status: StatePaused, IngestMode: IngestModeURL, IngestURL: "rtmp:///live"}, false},
+		{model.Stream{Status: StatePaused, IngestMode: IngestModePush, StreamKey: "k"}, true},
+		{model.Stream{Status: StatePaused, IngestMode: IngestModePush}, false},
+	} {
+		for _, to := range []string{StateLive, StateStarting} {
+			err := checkTransition(tc.st, to)
+			if (err == nil) != tc.ok || (err != nil && !errors.Is(err, ErrIngestNotReady)) {
+				t.Errorf("%+v -> %s = %v, want ok=%v", tc.st, to, err, tc.ok)
+			}
+		}
+		if err := checkTransition(tc.st, StateDisabled); err != nil {
+			t.Errorf("%+v -> disabled = %v", tc.st, err)
+		}
+	}
+}
+
+func TestStateHelpers(t *testing.T) {
+	for _, state := range streamStates {
+		if got, want := isOnAir(state), state == StateLive || state == StateStarting; got != want {
+			t.Errorf("isOnAir(%s) = %v", state, got)
+		}
+		if err, want := validateInitialState(state), slices.Contains(initialStates, state); (err == nil) != want {
+			t.Errorf("validateInitialState(%s) = %v", state, err)
+		}
+	}
+	if validateInitialState("") == nil {
+		t.Error("validateInitialState accepted an empty state")
+	}
+}
+
+func TestTransitionStream(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+	if _, err := svc.TransitionStream(ctx, "stream-1", StateDraft, "admin", "", 0); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("paused -> draft = %v", err)
+	}
+	st, err := svc.TransitionStream(ctx, "stream-1", StateDisabled, "admin", "maintenance", 0)
+	if err != nil || st.Status != StateDisabled {
+		t.Fatalf("paused -> disabled = %+v, %v", st, err)
+	}
+	if st, err := svc.TransitionStream(ctx, "stream-1", StateDisabled, "admin", "", 0); err != nil || st.Status != StateDisabled {
+		t.Errorf("disabled -> disabled = %+v, %v", st, err)
+	}
+	state, err := svc.StreamState("stream-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := state["history"].([]model.StreamStateChange)
+	if len(history) != 1 || history[0].From != StatePaused || history[0].To != StateDisabled || history[0].Reason != "maintenance" {
+		t.Errorf("history = %+v", history)
+	}
+	if !slices.Equal(state["allowed"].([]string), streamTransitions[StateDisabled]) {
+		t.Errorf("allowed = %v", state["allowed"])
+	}
+	if _, err := svc.TransitionStream(ctx, "missing", StateLive, "admin", "", 0); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("unknown stream = %v", err)
+	}
+}
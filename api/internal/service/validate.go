@@ -0,0 +1,257 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"streamweb/api/internal/model"
+)
+
+const (
+	maxStreamIDLength            = 48
+	maxStreamNameLength          = 200
+	maxExternalIDLength          = 128
+	maxGroupLength               = 100
+	maxSegmentDurationSec        = 30
+	maxPlaylistWindowMinutes     = 60
+	maxPointsRate                = 10000
+	maxConcurrentSessionsLimit   = 100
+	defaultSegmentDurationSec    = 4
+	defaultPlaylistWindowMinutes = 2
+	defaultMaxConcurrentSessions = 1
+)
+
+var ingestSchemes = []string{"http", "https", "rtmp", "rtmps", "rtsp", "srt", "udp", "file"}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 1 {
+		return e.Fields[0].Field + ": " + e.Fields[0].Message
+	}
+	return fmt.Sprintf("%d invalid fields", len(e.Fields))
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+type StreamPatch struct {
+	Name                  *string             `json:"name"`
+	Status                *string             `json:"status"`
+	IngestMode            *string             `json:"ingest_mode"`
+	IngestURL             *string             `json:"ingest_url"`
+	ExternalID            *string             `json:"external_id"`
+	Group                 *string             `json:"group"`
+	LogoURL               *string             `json:"logo_url"`
+	BackupIngestURLs      *[]string           `json:"backup_ingest_urls"`
+	FailoverAfterSec      *int                `json:"failover_after_sec"`
+	ABRPreset             *string             `json:"abr_preset"`
+	ABRProfiles           *[]model.ABRProfile `json:"abr_profiles"`
+	SegmentDurationSec    *int                `json:"segment_duration_sec"`
+	PlaylistWindowMinutes *int                `json:"playlist_window_minutes"`
+	DVRWindowMinutes      *int                `json:"dvr_window_minutes"`
+	RecordingEnabled      *bool               `json:"recording_enabled"`
+	RecordingPointsRate   *int                `json:"recording_points_rate"`
+	PointsRate            *int                `json:"points_rate"`
+	MaxConcurrentSessions *int                `json:"max_concurrent_sessions"`
+}
+
+func (p StreamPatch) fields() map[string]bool {
+	out := map[string]bool{}
+	v, t := reflect.ValueOf(p), reflect.TypeOf(p)
+	for i := range t.NumField() {
+		if !v.Field(i).IsNil() {
+			out[t.Field(i).Tag.Get("json")] = true
+		}
+	}
+	return out
+}
+
+func (p StreamPatch) apply(st *model.Stream) {
+	set(&st.Name, p.Name)
+	set(&st.Status, p.Status)
+	set(&st.IngestMode, p.IngestMode)
+	set(&st.IngestURL, p.IngestURL)
+	set(&st.ExternalID, p.ExternalID)
+	set(&st.Group, p.Group)
+	set(&st.LogoURL, p.LogoURL)
+	set(&st.BackupIngestURLs, p.BackupIngestURLs)
+	set(&st.FailoverAfterSec, p.FailoverAfterSec)
+	set(&st.SegmentDurationSec, p.SegmentDurationSec)
+	set(&st.PlaylistWindowMinutes, p.PlaylistWindowMinutes)
+	set(&st.DVRWindowMinutes, p.DVRWindowMinutes)
+	set(&st.RecordingEnabled, p.RecordingEnabled)
+	set(&st.RecordingPointsRate, p.RecordingPointsRate)
+	set(&st.PointsRate, p.PointsRate)
+	set(&st.MaxConcurrentSessions, p.MaxConcurrentSessions)
+	if st.IngestMode == IngestModePush && st.StreamKey == "" {
+		st.StreamKey = newStreamKey()
+	}
+}
+
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func (p StreamPatch) resolveABR() (profiles []model.ABRProfile, ok bool, err error) {
+	if p.ABRPreset == nil && p.ABRProfiles == nil {
+		return nil, false, nil
+	}
+	var preset string
+	var custom []model.ABRProfile
+	set(&preset, p.ABRPreset)
+	set(&custom, p.ABRProfiles)
+	profiles, err = resolveABR(preset, custom)
+	return profiles, true, err
+}
+
+type validator struct {
+	fields []FieldError
+	only   map[string]bool
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) checks(field string) bool {
+	return v.only == nil || v.only[field]
+}
+
+func (v *validator) intRange(field string, n, lo, hi int) {
+	if v.checks(field) && (n < lo || n > hi) {
+		v.add(field, "must be between %d and %d", lo, hi)
+	}
+}
+
+func (v *validator) length(field, s string, limit int) {
+	if v.checks(field) && len(s) > limit {
+		v.add(field, "must be at most %d characters", limit)
+	}
+}
+
+func (v *validator) abr(field string, err error) {
+	msg := err.Error()
+	if f, rest, ok := strings.Cut(msg, ": "); ok && strings.HasPrefix(f, "abr_profiles[") {
+		field, msg = f, rest
+	}
+	v.add(field, "%s", msg)
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+func ingestURLProblem(raw string) string {
+	u, err := url.Parse(raw)
+	switch {
+	case err != nil || u.Scheme == "":
+		return "must be an absolute url"
+	case !slices.Contains(ingestSchemes, u.Scheme):
+		return "scheme must be one of " + strings.Join(ingestSchemes, ", ")
+	case u.Host == "" && u.Scheme != "file":
+		return "must include a host"
+	}
+	return ""
+}
+
+func streamIDProblem(id string) string {
+	switch {
+	case len(id) > maxStreamIDLength:
+		return fmt.Sprintf("must be at most %d characters", maxStreamIDLength)
+	case strings.ContainsFunc(id, func(r rune) bool { return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' }):
+		return "may only contain lowercase letters, digits and -"
+	case strings.HasPrefix(id, "-") || strings.HasSuffix(id, "-"):
+		return "must start and end with a letter or digit"
+	case reservedStreamIDs[id]:
+		return fmt.Sprintf("%q is reserved", id)
+	}
+	return ""
+}
+
+func (v *validator) stream(st model.Stream) {
+	if v.checks("id") {
+		if msg := streamIDProblem(st.ID); msg != "" {
+			v.add("id", "%s", msg)
+		}
+	}
+	if v.checks("name") && strings.TrimSpace(st.Name) == "" {
+		v.add("name", "is required")
+	}
+	v.length("name", st.Name, maxStreamNameLength)
+	v.length("external_id", st.ExternalID, maxExternalIDLength)
+	v.length("group", st.Group, maxGroupLength)
+	if v.checks("logo_url") && st.LogoURL != "" {
+		if u, err := url.Parse(st.LogoURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("logo_url", "must be an http or https url")
+		}
+	}
+	if v.checks("ingest_mode") && st.IngestMode != IngestModeURL && st.IngestMode != IngestModePush {
+		v.add("ingest_mode", "must be %s or %s", IngestModeURL, IngestModePush)
+	}
+	if v.checks("ingest_url") && st.IngestURL != "" {
+		if msg := ingestURLProblem(st.IngestURL); msg != "" {
+			v.add("ingest_url", "%s", msg)
+		}
+	}
+	if v.checks("backup_ingest_urls") {
+		if len(st.BackupIngestURLs) > maxBackupIngestURLs {
+			v.add("backup_ingest_urls", "at most %d urls allowed", maxBackupIngestURLs)
+		}
+		for i, raw := range st.BackupIngestURLs {
+			field := fmt.Sprintf("backup_ingest_urls[%d]", i)
+			if msg := ingestURLProblem(raw); msg != "" {
+				v.add(field, "%s", msg)
+			} else if raw == st.IngestURL || slices.Index(st.BackupIngestURLs, raw) < i {
+				v.add(field, "duplicate ingest url")
+			}
+		}
+	}
+	v.intRange("failover_after_sec", st.FailoverAfterSec, 0, maxFailoverAfterSec)
+	v.intRange("segment_duration_sec", st.SegmentDurationSec, 1, maxSegmentDurationSec)
+	v.intRange("playlist_window_minutes", st.PlaylistWindowMinutes, 1, maxPlaylistWindowMinutes)
+	v.intRange("dvr_window_minutes", st.DVRWindowMinutes, 0, maxDVRWindowMinutes)
+	v.intRange("points_rate", st.PointsRate, 0, maxPointsRate)
+	v.intRange("recording_points_rate", st.RecordingPointsRate, 0, maxPointsRate)
+	v.intRange("max_concurrent_sessions", st.MaxConcurrentSessions, 1, maxConcurrentSessionsLimit)
+	if (v.checks("segment_duration_sec") || v.checks("abr_profiles") || v.checks("abr_preset")) && st.SegmentDurationSec > 0 {
+		for _, p := range st.ABRProfiles {
+			if p.KeyframeIntervalSec > 0 && st.SegmentDurationSec%p.KeyframeIntervalSec != 0 {
+				v.add("segment_duration_sec", "must be a multiple of the %ds keyframe interval of rendition %s", p.KeyframeIntervalSec, p.Name)
+				break
+			}
+		}
+	}
+}
+
+func restartFields(a, b model.Stream) []string {
+	var out []string
+	diff := func(field string, changed bool) {
+		if changed {
+			out = append(out, field)
+		}
+	}
+	diff("ingest_mode", a.IngestMode != b.IngestMode)
+	diff("ingest_url", a.IngestURL != b.IngestURL)
+	diff("backup_ingest_urls", !slices.Equal(a.BackupIngestURLs, b.BackupIngestURLs))
+	diff("failover_after_sec", a.FailoverAfterSec != b.FailoverAfterSec)
+	diff("abr_profiles", !slices.Equal(a.ABRProfiles, b.ABRProfiles))
+	diff("segment_duration_sec", a.SegmentDurationSec != b.SegmentDurationSec)
+	diff("playlist_window_minutes", a.PlaylistWindowMinutes != b.PlaylistWindowMinutes)
+	diff("recording_enabled", a.RecordingEnabled != b.RecordingEnabled)
+	return out
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/store"
+)
+
+func newTestService() *Service {
+	return New(store.NewMemoryStore(ids.NewULID()), nil, ids.NewULID(), nil)
+}
+
+func fieldErrors(err error) map[string]string {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	out := map[string]string{}
+	for _, f := range verr.Fields {
+		out[f.Field] = f.Message
+	}
+	return out
+}
+
+func TestCreateStreamID(t *testing.T) {
+	name := "Channel"
+	for _, tc := range []struct {
+		id, wantErr string
+	}{
+		{id: "news-24"},
+		{id: "0"},
+		{id: strings.Repeat("a", maxStreamIDLength)},
+		{id: strings.Repeat("a", maxStreamIDLength+1), wantErr: "must be at most 48 characters"},
+		{id: "News", wantErr: "may only contain lowercase letters, digits and -"},
+		{id: "news_24", wantErr: "may only contain lowercase letters, digits and -"},
+		{id: "news/24", wantErr: "may only contain lowercase letters, digits and -"},
+		{id: "café", wantErr: "may only contain lowercase letters, digits and -"},
+		{id: "-news", wantErr: "must start and end with a letter or digit"},
+		{id: "news-", wantErr: "must start and end with a letter or digit"},
+		{id: "recordings", wantErr: `"recordings" is reserved`},
+		{id: "import", wantErr: `"import" is reserved`},
+	} {
+		st, err := newTestService().CreateStream(tc.id, StreamPatch{Name: &name})
+		if tc.wantErr == "" {
+			if err != nil || st.ID != tc.id {
+				t.Errorf("CreateStream(%q) = %q, %v", tc.id, st.ID, err)
+			}
+			continue
+		}
+		if got := fieldErrors(err); got["id"] != tc.wantErr || len(got) != 1 {
+			t.Errorf("CreateStream(%q) fields = %v (%v), want id: %s", tc.id, got, err, tc.wantErr)
+		}
+	}
+
+	st, err := newTestService().CreateStream("", StreamPatch{Name: &name})
+	if err != nil || streamIDProblem(st.ID) != "" {
+		t.Errorf("generated id %q: %v", st.ID, err)
+	}
+}
+
+func TestUniqueStreamID(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	for _, tc := range []struct {
+		base string
+		used map[string]bool
+		want string
+	}{
+		{base: "BBC One HD", want: "bbc-one-hd"},
+		{base: "  --Das Erste!! ", want: "das-erste"},
+		{base: "***", want: "channel"},
+		{base: "import", want: "import-2"},
+		{base: "news", used: map[string]bool{"news": true, "news-2": true}, want: "news-3"},
+		{base: long, want: long[:maxStreamIDLength]},
+		{base: long, used: map[string]bool{long[:maxStreamIDLength]: true}, want: long[:maxStreamIDLength-2] + "-2"},
+		{base: strings.Repeat("x", 46) + " y", used: map[string]bool{strings.Repeat("x", 46) + "-y": true}, want: strings.Repeat("x", 46) + "-2"},
+	} {
+		got := uniqueStreamID(tc.base, tc.used)
+		if got != tc.want || streamIDProblem(got) != "" {
+			t.Errorf("uniqueStreamID(%q) = %q (%s), want %q", tc.base, got, streamIDProblem(got), tc.want)
+		}
+	}
+}
+
+func ref[T any](v T) *T { return &v }
+
+func TestStreamPatchValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    StreamPatch
+		want map[string]string
+	}{
+		{name: "valid", p: StreamPatch{Name: ref("News"), IngestURL: ref("srt://in:9000"), LogoURL: ref("https://x/l.png"), BackupIngestURLs: ref([]string{"rtmp://b/live"})}},
+		{name: "blank name", p: StreamPatch{Name: ref("  ")}, want: map[string]string{"name": "is required"}},
+		{name: "long name", p: StreamPatch{Name: ref(strings.Repeat("n", maxStreamNameLength+1))}, want: map[string]string{"name": "must be at most 200 characters"}},
+		{name: "long group and external id", p: StreamPatch{Group: ref(strings.Repeat("g", maxGroupLength+1)), ExternalID: ref(strings.Repeat("e", maxExternalIDLength+1))},
+			want: map[string]string{"group": "must be at most 100 characters", "external_id": "must be at most 128 characters"}},
+		{name: "logo scheme", p: StreamPatch{LogoURL: ref("ftp://x/l.png")}, want: map[string]string{"logo_url": "must be an http or https url"}},
+		{name: "logo without host", p: StreamPatch{LogoURL: ref("https:///l.png")}, want: map[string]string{"logo_url": "must be an http or https url"}},
+		{name: "ingest mode", p: StreamPatch{IngestMode: ref("pull")}, want: map[string]string{"ingest_mode": "must be url or push"}},
+		{name: "relative ingest url", p: StreamPatch{IngestURL: ref("/live/in")}, want: map[string]string{"ingest_url": "must be an absolute url"}},
+		{name: "ingest scheme", p: StreamPatch{IngestURL: ref("gopher://in/x")}, want: map[string]string{"ingest_url": "scheme must be one of " + strings.Join(ingestSchemes, ", ")}},
+		{name: "ingest without host", p: StreamPatch{IngestURL: ref("rtmp:///live")}, want: map[string]string{"ingest_url": "must include a host"}},
+		{name: "file ingest", p: StreamPatch{IngestURL: ref("file:///srv/loop.ts")}},
+		{name: "backup duplicates", p: StreamPatch{IngestURL: ref("rtmp://a/live"), BackupIngestURLs: ref([]string{"rtmp://a/live", "rtmp://b/live", "rtmp://b/live", "nope"})},
+			want: map[string]string{"backup_ingest_urls[0]": "duplicate ingest url", "backup_ingest_urls[2]": "duplicate ingest url", "backup_ingest_urls[3]": "must be an absolute url"}},
+		{name: "too many backups", p: StreamPatch{BackupIngestURLs: ref([]string{"udp://a:1", "udp://a:2", "udp://a:3", "udp://a:4", "udp://a:5", "udp://a:6", "udp://a:7", "udp://a:8", "udp://a:9"})},
+			want: map[string]string{"backup_ingest_urls": "at most 8 urls allowed"}},
+		{name: "int ranges", p: StreamPatch{FailoverAfterSec: ref(-1), SegmentDurationSec: ref(0), PlaylistWindowMinutes: ref(61), DVRWindowMinutes: ref(maxDVRWindowMinutes + 1), PointsRate: ref(-1), RecordingPointsRate: ref(maxPointsRate + 1), MaxConcurrentSessions: ref(0)},
+			want: map[string]string{
+				"failover_after_sec":      "must be between 0 and 600",
+				"segment_duration_sec":    "must be between 1 and 30",
+				"playlist_window_minutes": "must be between 1 and 60",
+				"dvr_window_minutes":      "must be between 0 and 1440",
+				"points_rate":             "must be between 0 and 10000",
+				"recording_points_rate":   "must be between 0 and 10000",
+				"max_concurrent_sessions": "must be between 1 and 100",
+			}},
+		{name: "range bounds", p: StreamPatch{FailoverAfterSec: ref(maxFailoverAfterSec), SegmentDurationSec: ref(30), PlaylistWindowMinutes: ref(1), DVRWindowMinutes: ref(0), MaxConcurrentSessions: ref(100)}},
+		{name: "segment not a keyframe multiple", p: StreamPatch{ABRPreset: ref("hd"), SegmentDurationSec: ref(5)},
+			want: map[string]string{"segment_duration_sec": "must be a multiple of the 2s keyframe interval of rendition 1080p"}},
+		{name: "keyframe multiple", p: StreamPatch{ABRPreset: ref("sd"), SegmentDurationSec: ref(6)}},
+		{name: "unknown preset", p: StreamPatch{ABRPreset: ref("4k")}, want: map[string]string{"abr_preset": `unknown abr_preset "4k" (available: [hd sd source-only])`}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := newTestService()
+			_, err := svc.PatchStream(context.Background(), "stream-1", tc.p, "admin", 0)
+			got := fieldErrors(err)
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("PatchStream = %v (%v)", err, got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("PatchStream fields = %v (%v), want %v", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestStreamPatchChecksOnlyPatchedFields(t *testing.T) {
+	svc := newTestService()
+	if _, err := svc.repo.CreateStream(model.Stream{ID: "legacy", Name: "Legacy", Status: StatePaused, IngestMode: IngestModeURL, Group: strings.Repeat("g", maxGroupLength+1),
+		IngestURL: "gopher://old/in", SegmentDurationSec: 5, PlaylistWindowMinutes: 2, MaxConcurrentSessions: 1, ABRProfiles: abrPresets["sd"]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.PatchStream(context.Background(), "legacy", StreamPatch{Name: ref("Renamed"), PointsRate: ref(3)}, "admin", 0); err != nil {
+		t.Errorf("patching valid fields of a stream with stale invalid fields = %v", err)
+	}
+	_, err := svc.PatchStream(context.Background(), "legacy", StreamPatch{Group: ref("News")}, "admin", 0)
+	if err != nil {
+		t.Errorf("fixing the invalid group = %v", err)
+	}
+	_, err = svc.PatchStream(context.Background(), "legacy", StreamPatch{ABRPreset: ref("hd")}, "admin", 0)
+	if got := fieldErrors(err); got["segment_duration_sec"] == "" || len(got) != 1 {
+		t.Errorf("changing the ladder under a 5s segment = %v (%v)", got, err)
+	}
+}
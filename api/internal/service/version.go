@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"streamweb/api/internal/model"
+	"streamweb/api/internal/store"
+)
+
+type VersionMismatchError struct {
+	Current int
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("stream has been modified, current version is %d", e.Current)
+}
+
+func (e *VersionMismatchError) Unwrap() error { return ErrVersionMismatch }
+
+func checkVersion(st model.Stream, version int) error {
+	if version != 0 && st.Version != version {
+		return &VersionMismatchError{Current: st.Version}
+	}
+	return nil
+}
+
+func swapError(err error, current model.Stream, version int) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return ErrStreamNotFound
+	case errors.Is(err, store.ErrVersionMismatch) && version != 0:
+		return &VersionMismatchError{Current: current.Version}
+	case errors.Is(err, store.ErrVersionMismatch):
+		return ErrConcurrentUpdate
+	}
+	return err
+}
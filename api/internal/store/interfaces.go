@@ -1,19 +1,62 @@
 package store
 
-import "streamweb/api/internal/model"
+import (
+	"errors"
+	"time"
+
+	"streamweb/api/internal/model"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrVersionMismatch = errors.New("version mismatch")
+	ErrAlreadyExists   = errors.New("already exists")
+)
 
 type Repository interface {
 	FindUserByEmail(email string) (model.User, bool)
-	CreateStream(st model.Stream) model.Stream
+	GetUser(id string) (model.User, bool)
+	FindUserByPlaylistKey(key string) (model.User, bool)
+	UpdateUser(id string, fn func(*model.User)) (model.User, bool)
+	CreateStream(st model.Stream) (model.Stream, error)
 	UpdateStream(id string, fn func(*model.Stream)) (model.Stream, bool)
+	CompareAndSwapStream(id string, version int, fn func(*model.Stream)) (model.Stream, error)
 	GetStream(id string) (model.Stream, bool)
+	FindStreamByKey(key string) (model.Stream, bool)
+	ListStreams() []model.Stream
+	QueryStreams(q StreamQuery) ([]model.Stream, int)
+	GetRuntime(streamID string) (model.StreamRuntime, bool)
+	UpdateRuntime(streamID string, fn func(*model.StreamRuntime)) model.StreamRuntime
+	CreateRecording(rec model.Recording) model.Recording
+	GetRecording(id string) (model.Recording, bool)
+	ListRecordings(streamID string) []model.Recording
+	UpdateRecording(id string, fn func(*model.Recording)) (model.Recording, bool)
+	DeleteRecording(id string) bool
+	CreateClip(c model.Clip) model.Clip
+	GetClip(id string) (model.Clip, bool)
+	ListClips(streamID string) []model.Clip
+	UpdateClip(id string, fn func(*model.Clip)) (model.Clip, bool)
+	DeleteClip(id string) bool
+	CreateSchedule(sc model.Schedule) model.Schedule
+	GetSchedule(id string) (model.Schedule, bool)
+	ListSchedules(streamID string) []model.Schedule
+	UpdateSchedule(id string, fn func(*model.Schedule)) (model.Schedule, bool)
+	DeleteSchedule(id string) bool
+	ReplaceProgrammes(streamID string, from, to time.Time, ps []model.Programme) int
+	ListProgrammes(streamID string, from, to time.Time) []model.Programme
+	AppendStateChange(c model.StreamStateChange) model.StreamStateChange
+	ListStateHistory(streamID string, limit int) []model.StreamStateChange
+	AppendAudit(e model.AuditEntry) model.AuditEntry
+	ListAudit(streamID string, limit int) []model.AuditEntry
 	ActiveViewerCount(streamID string) int
 	ActiveUserSessionCount(userID string) int
 	GetWallet(userID string) (model.Wallet, bool)
-	CreateSession(userID, streamID, ip, ua string) model.Session
+	CreateSession(ss model.Session) model.Session
 	GetSession(sessionID string) (model.Session, bool)
+	ListUserSessions(userID string) []model.Session
+	ListActiveSessions() []model.Session
 	UpdateSessionState(sessionID, state string) bool
 	TouchSession(sessionID string) bool
-	DeductPoints(userID, streamID, sessionID string, points int64) (int64, error)
+	DeductPoints(userID, streamID, sessionID, reason string, points int64) (int64, error)
 	Metrics() map[string]int
 }
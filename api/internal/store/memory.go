@@ -2,26 +2,42 @@ package store
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"streamweb/api/internal/ids"
 	"streamweb/api/internal/model"
 )
 
 type MemoryStore struct {
 	mu       sync.Mutex
+	ids      ids.Generator
 	users    map[string]model.User
 	wallets  map[string]model.Wallet
 	streams  map[string]model.Stream
+	runtime  map[string]model.StreamRuntime
+	records  map[string]model.Recording
+	clips    map[string]model.Clip
+	schedule map[string]model.Schedule
+	epg      map[string][]model.Programme
+	audit    []model.AuditEntry
+	history  []model.StreamStateChange
 	sessions map[string]model.Session
 	ledger   []model.LedgerEntry
 }
 
-func NewMemoryStore() *MemoryStore {
+func NewMemoryStore(gen ids.Generator) *MemoryStore {
 	s := &MemoryStore{
+		ids:      gen,
 		users:    map[string]model.User{},
 		wallets:  map[string]model.Wallet{},
 		streams:  map[string]model.Stream{},
+		runtime:  map[string]model.StreamRuntime{},
+		records:  map[string]model.Recording{},
+		clips:    map[string]model.Clip{},
+		schedule: map[string]model.Schedule{},
+		epg:      map[string][]model.Programme{},
 		sessions: map[string]model.Session{},
 		ledger:   []model.LedgerEntry{},
 	}
@@ -30,7 +46,7 @@ func NewMemoryStore() *MemoryStore {
 	s.users[admin.Email] = admin
 	s.users[demo.Email] = demo
 	s.wallets[demo.ID] = model.Wallet{UserID: demo.ID, Balance: 1000}
-	s.streams["stream-1"] = model.Stream{ID: "stream-1", Name: "Default Stream", Status: "paused", IngestMode: "url", SegmentDurationSec: 4, PlaylistWindowMinutes: 2, PointsRate: 5, MaxConcurrentSessions: 2}
+	s.streams["stream-1"] = model.Stream{ID: "stream-1", Name: "Default Stream", Status: "paused", IngestMode: "url", ABRProfiles: []model.ABRProfile{{Name: "source", Codec: "copy"}}, SegmentDurationSec: 4, PlaylistWindowMinutes: 2, PointsRate: 5, MaxConcurrentSessions: 2, Version: 1, CreatedAt: time.Now().UTC()}
 	return s
 }
 
@@ -41,11 +57,56 @@ func (s *MemoryStore) FindUserByEmail(email string) (model.User, bool) {
 	return u, ok
 }
 
-func (s *MemoryStore) CreateStream(st model.Stream) model.Stream {
+func (s *MemoryStore) GetUser(id string) (model.User, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, true
+		}
+	}
+	return model.User{}, false
+}
+
+func (s *MemoryStore) FindUserByPlaylistKey(key string) (model.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key == "" {
+		return model.User{}, false
+	}
+	for _, u := range s.users {
+		if u.PlaylistKey == key {
+			return u, true
+		}
+	}
+	return model.User{}, false
+}
+
+func (s *MemoryStore) UpdateUser(id string, fn func(*model.User)) (model.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for email, u := range s.users {
+		if u.ID == id {
+			fn(&u)
+			s.users[email] = u
+			return u, true
+		}
+	}
+	return model.User{}, false
+}
+
+func (s *MemoryStore) CreateStream(st model.Stream) (model.Stream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.streams[st.ID]; ok {
+		return model.Stream{}, ErrAlreadyExists
+	}
+	if st.CreatedAt.IsZero() {
+		st.CreatedAt = time.Now().UTC()
+	}
+	st.Version = 1
 	s.streams[st.ID] = st
-	return st
+	return st, nil
 }
 
 func (s *MemoryStore) UpdateStream(id string, fn func(*model.Stream)) (model.Stream, bool) {
@@ -55,9 +116,28 @@ func (s *MemoryStore) UpdateStream(id string, fn func(*model.Stream)) (model.Str
 	if !ok {
 		return model.Stream{}, false
 	}
+	return s.putStream(st, fn), true
+}
+
+func (s *MemoryStore) CompareAndSwapStream(id string, version int, fn func(*model.Stream)) (model.Stream, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[id]
+	if !ok {
+		return model.Stream{}, ErrNotFound
+	}
+	if st.Version != version {
+		return st, ErrVersionMismatch
+	}
+	return s.putStream(st, fn), nil
+}
+
+func (s *MemoryStore) putStream(st model.Stream, fn func(*model.Stream)) model.Stream {
+	version := st.Version
 	fn(&st)
-	s.streams[id] = st
-	return st, true
+	st.Version = version + 1
+	s.streams[st.ID] = st
+	return st
 }
 
 func (s *MemoryStore) GetStream(id string) (model.Stream, bool) {
@@ -67,6 +147,304 @@ func (s *MemoryStore) GetStream(id string) (model.Stream, bool) {
 	return st, ok
 }
 
+func (s *MemoryStore) FindStreamByKey(key string) (model.Stream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.streams {
+		if key != "" && st.StreamKey == key {
+			return st, true
+		}
+	}
+	return model.Stream{}, false
+}
+
+func (s *MemoryStore) ListStreams() []model.Stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]model.Stream, 0, len(s.streams))
+	for _, st := range s.streams {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *MemoryStore) QueryStreams(q StreamQuery) ([]model.Stream, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Stream{}
+	for _, st := range s.streams {
+		if q.Match(st) {
+			out = append(out, st)
+		}
+	}
+	sortStreams(out, q.Sort, q.Desc)
+	total := len(out)
+	if q.Offset >= total {
+		return []model.Stream{}, total
+	}
+	out = out[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(out) {
+		out = out[:q.Limit]
+	}
+	return out, total
+}
+
+func (s *MemoryStore) GetRuntime(streamID string) (model.StreamRuntime, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.runtime[streamID]
+	return rt, ok
+}
+
+func (s *MemoryStore) UpdateRuntime(streamID string, fn func(*model.StreamRuntime)) model.StreamRuntime {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.runtime[streamID]
+	if !ok {
+		rt = model.StreamRuntime{StreamID: streamID}
+	}
+	fn(&rt)
+	s.runtime[streamID] = rt
+	return rt
+}
+
+func (s *MemoryStore) CreateRecording(rec model.Recording) model.Recording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec.ID = "rec_" + s.ids.New()
+	s.records[rec.ID] = rec
+	return rec
+}
+
+func (s *MemoryStore) GetRecording(id string) (model.Recording, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	return rec, ok
+}
+
+func (s *MemoryStore) ListRecordings(streamID string) []model.Recording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Recording{}
+	for _, rec := range s.records {
+		if streamID == "" || rec.StreamID == streamID {
+			out = append(out, rec)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out
+}
+
+func (s *MemoryStore) UpdateRecording(id string, fn func(*model.Recording)) (model.Recording, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok {
+		return model.Recording{}, false
+	}
+	fn(&rec)
+	s.records[id] = rec
+	return rec, true
+}
+
+func (s *MemoryStore) DeleteRecording(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[id]; !ok {
+		return false
+	}
+	delete(s.records, id)
+	return true
+}
+
+func (s *MemoryStore) CreateClip(c model.Clip) model.Clip {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.ID = "clip_" + s.ids.New()
+	c.CreatedAt = time.Now().UTC()
+	s.clips[c.ID] = c
+	return c
+}
+
+func (s *MemoryStore) GetClip(id string) (model.Clip, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clips[id]
+	return c, ok
+}
+
+func (s *MemoryStore) ListClips(streamID string) []model.Clip {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Clip{}
+	for _, c := range s.clips {
+		if streamID == "" || c.StreamID == streamID {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+func (s *MemoryStore) UpdateClip(id string, fn func(*model.Clip)) (model.Clip, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.clips[id]
+	if !ok {
+		return model.Clip{}, false
+	}
+	fn(&c)
+	s.clips[id] = c
+	return c, true
+}
+
+func (s *MemoryStore) DeleteClip(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clips[id]; !ok {
+		return false
+	}
+	delete(s.clips, id)
+	return true
+}
+
+func (s *MemoryStore) CreateSchedule(sc model.Schedule) model.Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc.ID = "sch_" + s.ids.New()
+	sc.CreatedAt = time.Now().UTC()
+	s.schedule[sc.ID] = sc
+	return sc
+}
+
+func (s *MemoryStore) GetSchedule(id string) (model.Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.schedule[id]
+	return sc, ok
+}
+
+func (s *MemoryStore) ListSchedules(streamID string) []model.Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Schedule{}
+	for _, sc := range s.schedule {
+		if streamID == "" || sc.StreamID == streamID {
+			out = append(out, sc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (s *MemoryStore) UpdateSchedule(id string, fn func(*model.Schedule)) (model.Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.schedule[id]
+	if !ok {
+		return model.Schedule{}, false
+	}
+	fn(&sc)
+	s.schedule[id] = sc
+	return sc, true
+}
+
+func (s *MemoryStore) DeleteSchedule(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedule[id]; !ok {
+		return false
+	}
+	delete(s.schedule, id)
+	return true
+}
+
+func (s *MemoryStore) ReplaceProgrammes(streamID string, from, to time.Time, ps []model.Programme) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := []model.Programme{}
+	for _, p := range s.epg[streamID] {
+		if p.StartAt.Before(to) && p.EndAt.After(from) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	removed := len(s.epg[streamID]) - len(kept)
+	now := time.Now().UTC()
+	for _, p := range ps {
+		p.ID = "p_" + s.ids.New()
+		p.StreamID, p.ImportedAt = streamID, now
+		kept = append(kept, p)
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].StartAt.Before(kept[j].StartAt) })
+	s.epg[streamID] = kept
+	return removed
+}
+
+func (s *MemoryStore) ListProgrammes(streamID string, from, to time.Time) []model.Programme {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Programme{}
+	for id, ps := range s.epg {
+		if streamID != "" && id != streamID {
+			continue
+		}
+		for _, p := range ps {
+			if p.StartAt.Before(to) && p.EndAt.After(from) {
+				out = append(out, p)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartAt.Before(out[j].StartAt) })
+	return out
+}
+
+func (s *MemoryStore) AppendStateChange(c model.StreamStateChange) model.StreamStateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.ID = "sh_" + s.ids.New()
+	if c.At.IsZero() {
+		c.At = time.Now().UTC()
+	}
+	s.history = append(s.history, c)
+	return c
+}
+
+func (s *MemoryStore) ListStateHistory(streamID string, limit int) []model.StreamStateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.StreamStateChange{}
+	for i := len(s.history) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		if s.history[i].StreamID == streamID {
+			out = append(out, s.history[i])
+		}
+	}
+	return out
+}
+
+func (s *MemoryStore) AppendAudit(e model.AuditEntry) model.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = "a_" + s.ids.New()
+	e.At = time.Now().UTC()
+	s.audit = append(s.audit, e)
+	return e
+}
+
+func (s *MemoryStore) ListAudit(streamID string, limit int) []model.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.AuditEntry{}
+	for i := len(s.audit) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		if streamID == "" || s.audit[i].StreamID == streamID {
+			out = append(out, s.audit[i])
+		}
+	}
+	return out
+}
+
 func (s *MemoryStore) ActiveViewerCount(streamID string) int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -98,13 +476,15 @@ func (s *MemoryStore) GetWallet(userID string) (model.Wallet, bool) {
 	return w, ok
 }
 
-func (s *MemoryStore) CreateSession(userID, streamID, ip, ua string) model.Session {
+func (s *MemoryStore) CreateSession(ss model.Session) model.Session {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	now := time.Now().UTC()
-	sid := fmt.Sprintf("s_%d", time.Now().UnixNano())
-	ss := model.Session{ID: sid, UserID: userID, StreamID: streamID, State: "active", StartedAt: now, LastSeenAt: now, IP: ip, UserAgent: ua}
-	s.sessions[sid] = ss
+	ss.ID = "s_" + s.ids.New()
+	ss.State = "active"
+	ss.StartedAt = now
+	ss.LastSeenAt = now
+	s.sessions[ss.ID] = ss
 	return ss
 }
 
@@ -115,6 +495,31 @@ func (s *MemoryStore) GetSession(sessionID string) (model.Session, bool) {
 	return ss, ok
 }
 
+func (s *MemoryStore) ListUserSessions(userID string) []model.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Session{}
+	for _, ss := range s.sessions {
+		if ss.UserID == userID {
+			out = append(out, ss)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+func (s *MemoryStore) ListActiveSessions() []model.Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []model.Session{}
+	for _, ss := range s.sessions {
+		if ss.State == "active" {
+			out = append(out, ss)
+		}
+	}
+	return out
+}
+
 func (s *MemoryStore) UpdateSessionState(sessionID, state string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -140,7 +545,7 @@ func (s *MemoryStore) TouchSession(sessionID string) bool {
 	return true
 }
 
-func (s *MemoryStore) DeductPoints(userID, streamID, sessionID string, points int64) (int64, error) {
+func (s *MemoryStore) DeductPoints(userID, streamID, sessionID, reason string, points int64) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	wallet, ok := s.wallets[userID]
@@ -155,7 +560,7 @@ func (s *MemoryStore) DeductPoints(userID, streamID, sessionID string, points in
 		wallet.Balance = 0
 	}
 	s.wallets[userID] = wallet
-	s.ledger = append(s.ledger, model.LedgerEntry{ID: fmt.Sprintf("l_%d", time.Now().UnixNano()), UserID: userID, Delta: -points, Reason: "heartbeat_deduction", StreamID: streamID, SessionID: sessionID, CreatedAt: time.Now().UTC()})
+	s.ledger = append(s.ledger, model.LedgerEntry{ID: "l_" + s.ids.New(), UserID: userID, Delta: -points, Reason: reason, StreamID: streamID, SessionID: sessionID, CreatedAt: time.Now().UTC()})
 	return wallet.Balance, nil
 }
 
@@ -0,0 +1,745 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+)
+
+type PostgresStore struct {
+	db  *sql.DB
+	ids ids.Generator
+}
+
+func OpenPostgres(dsn string, gen ids.Generator) (*PostgresStore, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return newPostgresStore(db, gen), nil
+}
+
+func newPostgresStore(db *sql.DB, gen ids.Generator) *PostgresStore {
+	return &PostgresStore{db: db, ids: gen}
+}
+
+func (s *PostgresStore) Close() error { return s.db.Close() }
+
+type scanner interface{ Scan(dest ...any) error }
+
+type utcTime struct{ t *time.Time }
+
+func (u utcTime) Scan(v any) error {
+	switch v := v.(type) {
+	case nil:
+		*u.t = time.Time{}
+	case time.Time:
+		*u.t = v.UTC()
+	default:
+		return fmt.Errorf("cannot scan %T into time", v)
+	}
+	return nil
+}
+
+type jsonColumn struct{ v any }
+
+func (j jsonColumn) Scan(v any) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, j.v)
+	case string:
+		return json.Unmarshal([]byte(v), j.v)
+	default:
+		return fmt.Errorf("cannot scan %T into json", v)
+	}
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func now() time.Time { return time.Now().UTC().Truncate(time.Microsecond) }
+
+func (s *PostgresStore) found(op string, err error) bool {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("store: %s: %v", op, err)
+	}
+	return err == nil
+}
+
+func (s *PostgresStore) logged(op string, err error) {
+	if err != nil {
+		log.Printf("store: %s: %v", op, err)
+	}
+}
+
+func (s *PostgresStore) tx(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) affected(op string, res sql.Result, err error) bool {
+	if err != nil {
+		s.logged(op, err)
+		return false
+	}
+	n, err := res.RowsAffected()
+	return err == nil && n > 0
+}
+
+func queryRows[T any](s *PostgresStore, op string, scan func(scanner) (T, error), query string, args ...any) []T {
+	out, err := func() ([]T, error) {
+		rows, err := s.db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		out := []T{}
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, rows.Err()
+	}()
+	if err != nil {
+		s.logged(op, err)
+		return []T{}
+	}
+	return out
+}
+
+const userColumns = `id, email, password_hash, role, status, coalesce(playlist_key, '')`
+
+func scanUser(r scanner) (model.User, error) {
+	var u model.User
+	err := r.Scan(&u.ID, &u.Email, &u.Password, &u.Role, &u.Status, &u.PlaylistKey)
+	return u, err
+}
+
+func (s *PostgresStore) user(where string, arg any) (model.User, bool) {
+	u, err := scanUser(s.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE `+where, arg))
+	return u, s.found("get user", err)
+}
+
+func (s *PostgresStore) FindUserByEmail(email string) (model.User, bool) {
+	return s.user(`email = $1`, email)
+}
+
+func (s *PostgresStore) GetUser(id string) (model.User, bool) {
+	return s.user(`id = $1`, id)
+}
+
+func (s *PostgresStore) FindUserByPlaylistKey(key string) (model.User, bool) {
+	if key == "" {
+		return model.User{}, false
+	}
+	return s.user(`playlist_key = $1`, key)
+}
+
+func (s *PostgresStore) UpdateUser(id string, fn func(*model.User)) (model.User, bool) {
+	var u model.User
+	err := s.tx(func(tx *sql.Tx) error {
+		var err error
+		if u, err = scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1 FOR UPDATE`, id)); err != nil {
+			return err
+		}
+		fn(&u)
+		_, err = tx.Exec(`UPDATE users SET email = $2, password_hash = $3, role = $4, status = $5, playlist_key = $6 WHERE id = $1`,
+			id, u.Email, u.Password, u.Role, u.Status, nullString(u.PlaylistKey))
+		return err
+	})
+	return u, s.found("update user", err)
+}
+
+const streamColumns = `id, name, status, coalesce(ingest_mode, ''), coalesce(ingest_url, ''), coalesce(external_id, ''), coalesce(group_title, ''),
+	coalesce(logo_url, ''), coalesce(stream_key, ''), to_json(backup_ingest_urls), failover_after_sec, abr_profiles_json, segment_duration_sec,
+	playlist_window_minutes, dvr_window_minutes, recording_enabled, recording_points_rate, points_rate, max_concurrent_sessions, version, created_at, deleted_at`
+
+func scanStream(r scanner) (model.Stream, error) {
+	var st model.Stream
+	err := r.Scan(&st.ID, &st.Name, &st.Status, &st.IngestMode, &st.IngestURL, &st.ExternalID, &st.Group,
+		&st.LogoURL, &st.StreamKey, jsonColumn{&st.BackupIngestURLs}, &st.FailoverAfterSec, jsonColumn{&st.ABRProfiles}, &st.SegmentDurationSec,
+		&st.PlaylistWindowMinutes, &st.DVRWindowMinutes, &st.RecordingEnabled, &st.RecordingPointsRate, &st.PointsRate, &st.MaxConcurrentSessions, &st.Version, utcTime{&st.CreatedAt}, utcTime{&st.DeletedAt})
+	if len(st.BackupIngestURLs) == 0 {
+		st.BackupIngestURLs = nil
+	}
+	return st, err
+}
+
+func streamArgs(st model.Stream) ([]any, error) {
+	abr, err := json.Marshal(st.ABRProfiles)
+	if err != nil {
+		return nil, err
+	}
+	backups := st.BackupIngestURLs
+	if backups == nil {
+		backups = []string{}
+	}
+	return []any{st.ID, st.Name, st.Status, nullString(st.IngestMode), nullString(st.IngestURL), nullString(st.ExternalID), nullString(st.Group),
+		nullString(st.LogoURL), nullString(st.StreamKey), backups, st.FailoverAfterSec, string(abr), st.SegmentDurationSec,
+		st.PlaylistWindowMinutes, st.DVRWindowMinutes, st.RecordingEnabled, st.RecordingPointsRate, st.PointsRate, st.MaxConcurrentSessions, nullTime(st.DeletedAt)}, nil
+}
+
+func (s *PostgresStore) CreateStream(st model.Stream) (model.Stream, error) {
+	if st.CreatedAt.IsZero() {
+		st.CreatedAt = now()
+	}
+	st.Version = 1
+	args, err := streamArgs(st)
+	if err != nil {
+		return model.Stream{}, err
+	}
+	res, err := s.db.Exec(`INSERT INTO streams (id, name, status, ingest_mode, ingest_url, external_id, group_title,
+		logo_url, stream_key, backup_ingest_urls, failover_after_sec, abr_profiles_json, segment_duration_sec,
+		playlist_window_minutes, dvr_window_minutes, recording_enabled, recording_points_rate, points_rate, max_concurrent_sessions, deleted_at, created_at, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		ON CONFLICT (id) DO NOTHING`, append(args, st.CreatedAt, st.Version)...)
+	if err != nil {
+		return model.Stream{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return model.Stream{}, ErrAlreadyExists
+	}
+	return st, nil
+}
+
+func (s *PostgresStore) UpdateStream(id string, fn func(*model.Stream)) (model.Stream, bool) {
+	st, err := s.swapStream(id, -1, fn)
+	if errors.Is(err, ErrNotFound) {
+		return model.Stream{}, false
+	}
+	s.logged("update stream", err)
+	return st, err == nil
+}
+
+func (s *PostgresStore) CompareAndSwapStream(id string, version int, fn func(*model.Stream)) (model.Stream, error) {
+	return s.swapStream(id, version, fn)
+}
+
+func (s *PostgresStore) swapStream(id string, version int, fn func(*model.Stream)) (model.Stream, error) {
+	var st model.Stream
+	err := s.tx(func(tx *sql.Tx) error {
+		var err error
+		st, err = scanStream(tx.QueryRow(`SELECT `+streamColumns+` FROM streams WHERE id = $1 FOR UPDATE`, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if version >= 0 && st.Version != version {
+			return ErrVersionMismatch
+		}
+		cur := st.Version
+		fn(&st)
+		st.ID = id
+		args, err := streamArgs(st)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(`UPDATE streams SET name = $2, status = $3, ingest_mode = $4, ingest_url = $5, external_id = $6, group_title = $7,
+			logo_url = $8, stream_key = $9, backup_ingest_urls = $10, failover_after_sec = $11, abr_profiles_json = $12, segment_duration_sec = $13,
+			playlist_window_minutes = $14, dvr_window_minutes = $15, recording_enabled = $16, recording_points_rate = $17, points_rate = $18,
+			max_concurrent_sessions = $19, deleted_at = $20, version = version + 1
+			WHERE id = $1 AND version = $21`, append(args, cur)...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return ErrVersionMismatch
+		}
+		st.Version = cur + 1
+		return nil
+	})
+	if errors.Is(err, ErrVersionMismatch) {
+		cur, _ := s.GetStream(id)
+		return cur, err
+	}
+	if err != nil {
+		return model.Stream{}, err
+	}
+	return st, nil
+}
+
+func (s *PostgresStore) GetStream(id string) (model.Stream, bool) {
+	st, err := scanStream(s.db.QueryRow(`SELECT `+streamColumns+` FROM streams WHERE id = $1`, id))
+	return st, s.found("get stream", err)
+}
+
+func (s *PostgresStore) FindStreamByKey(key string) (model.Stream, bool) {
+	if key == "" {
+		return model.Stream{}, false
+	}
+	st, err := scanStream(s.db.QueryRow(`SELECT `+streamColumns+` FROM streams WHERE stream_key = $1`, key))
+	return st, s.found("find stream by key", err)
+}
+
+func (s *PostgresStore) ListStreams() []model.Stream {
+	return queryRows(s, "list streams", scanStream, `SELECT `+streamColumns+` FROM streams ORDER BY id COLLATE "C"`)
+}
+
+var streamOrder = map[string]string{
+	"id":          `id COLLATE "C"`,
+	"status":      `status COLLATE "C"`,
+	"created_at":  `created_at`,
+	"points_rate": `points_rate`,
+}
+
+func (s *PostgresStore) QueryStreams(q StreamQuery) ([]model.Stream, int) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if !q.IncludeDeleted {
+		where = append(where, `deleted_at IS NULL`)
+	}
+	if len(q.Statuses) > 0 {
+		where = append(where, `status = ANY(`+arg(q.Statuses)+`)`)
+	}
+	if q.Category != "" {
+		where = append(where, `lower(group_title) = lower(`+arg(q.Category)+`::text)`)
+	}
+	if q.Visible != nil {
+		where = append(where, `(status = ANY(`+arg(q.Visible.Statuses)+`) OR id = ANY(`+arg(q.Visible.IDs)+`))`)
+	}
+	if q.Search != "" {
+		where = append(where, `strpos(lower(concat_ws(E'\n', id, name, external_id, group_title)), lower(`+arg(q.Search)+`::text)) > 0`)
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	var total int
+	if err := s.db.QueryRow(`SELECT count(*) FROM streams`+filter, args...).Scan(&total); err != nil {
+		s.logged("query streams", err)
+		return []model.Stream{}, 0
+	}
+	order, ok := streamOrder[q.Sort]
+	if !ok {
+		order = `lower(name) COLLATE "C"`
+	}
+	if q.Desc {
+		order += ` DESC`
+	}
+	var limit any
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	query := `SELECT ` + streamColumns + ` FROM streams` + filter + ` ORDER BY ` + order + `, id COLLATE "C" LIMIT ` + arg(limit) + ` OFFSET ` + arg(max(q.Offset, 0))
+	return queryRows(s, "query streams", scanStream, query, args...), total
+}
+
+const runtimeColumns = `stream_id, coalesce(desired_state, ''), coalesce(actual_state, ''), coalesce(worker_id, ''), last_heartbeat_at, last_manifest_at,
+	ingest_bitrate_kbps, segment_count, coalesce(last_error, ''), active_source, failover_count, last_failover_at, coalesce(last_failover, ''), config_generation`
+
+func scanRuntime(r scanner) (model.StreamRuntime, error) {
+	var rt model.StreamRuntime
+	err := r.Scan(&rt.StreamID, &rt.DesiredState, &rt.ActualState, &rt.WorkerID, utcTime{&rt.LastHeartbeatAt}, utcTime{&rt.LastManifestAt},
+		&rt.IngestBitrateKbps, &rt.SegmentCount, &rt.LastError, &rt.ActiveSource, &rt.FailoverCount, utcTime{&rt.LastFailoverAt}, &rt.LastFailover, &rt.ConfigGeneration)
+	return rt, err
+}
+
+func (s *PostgresStore) GetRuntime(streamID string) (model.StreamRuntime, bool) {
+	rt, err := scanRuntime(s.db.QueryRow(`SELECT `+runtimeColumns+` FROM stream_runtime WHERE stream_id = $1`, streamID))
+	return rt, s.found("get runtime", err)
+}
+
+func (s *PostgresStore) UpdateRuntime(streamID string, fn func(*model.StreamRuntime)) model.StreamRuntime {
+	rt := model.StreamRuntime{StreamID: streamID}
+	err := s.tx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO stream_runtime (stream_id) VALUES ($1) ON CONFLICT (stream_id) DO NOTHING`, streamID); err != nil {
+			return err
+		}
+		var err error
+		if rt, err = scanRuntime(tx.QueryRow(`SELECT `+runtimeColumns+` FROM stream_runtime WHERE stream_id = $1 FOR UPDATE`, streamID)); err != nil {
+			return err
+		}
+		fn(&rt)
+		rt.StreamID = streamID
+		_, err = tx.Exec(`UPDATE stream_runtime SET desired_state = $2, actual_state = $3, worker_id = $4, last_heartbeat_at = $5, last_manifest_at = $6,
+			ingest_bitrate_kbps = $7, segment_count = $8, last_error = $9, active_source = $10, failover_count = $11, last_failover_at = $12,
+			last_failover = $13, config_generation = $14 WHERE stream_id = $1`,
+			streamID, nullString(rt.DesiredState), nullString(rt.ActualState), nullString(rt.WorkerID), nullTime(rt.LastHeartbeatAt), nullTime(rt.LastManifestAt),
+			rt.IngestBitrateKbps, rt.SegmentCount, nullString(rt.LastError), rt.ActiveSource, rt.FailoverCount, nullTime(rt.LastFailoverAt),
+			nullString(rt.LastFailover), rt.ConfigGeneration)
+		return err
+	})
+	s.logged("update runtime", err)
+	return rt
+}
+
+const recordingColumns = `id, stream_id, title, status, started_at, ended_at, duration_sec, segment_count, size_bytes, points_rate`
+
+func scanRecording(r scanner) (model.Recording, error) {
+	var rec model.Recording
+	err := r.Scan(&rec.ID, &rec.StreamID, &rec.Title, &rec.Status, utcTime{&rec.StartedAt}, utcTime{&rec.EndedAt}, &rec.DurationSec, &rec.SegmentCount, &rec.SizeBytes, &rec.PointsRate)
+	return rec, err
+}
+
+func (s *PostgresStore) CreateRecording(rec model.Recording) model.Recording {
+	rec.ID = "rec_" + s.ids.New()
+	_, err := s.db.Exec(`INSERT INTO recordings (id, stream_id, title, status, started_at, ended_at, duration_sec, segment_count, size_bytes, points_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		rec.ID, rec.StreamID, rec.Title, rec.Status, rec.StartedAt, nullTime(rec.EndedAt), rec.DurationSec, rec.SegmentCount, rec.SizeBytes, rec.PointsRate)
+	s.logged("create recording", err)
+	return rec
+}
+
+func (s *PostgresStore) GetRecording(id string) (model.Recording, bool) {
+	rec, err := scanRecording(s.db.QueryRow(`SELECT `+recordingColumns+` FROM recordings WHERE id = $1`, id))
+	return rec, s.found("get recording", err)
+}
+
+func (s *PostgresStore) ListRecordings(streamID string) []model.Recording {
+	return queryRows(s, "list recordings", scanRecording, `SELECT `+recordingColumns+` FROM recordings WHERE $1 = '' OR stream_id = $1 ORDER BY started_at DESC`, streamID)
+}
+
+func (s *PostgresStore) UpdateRecording(id string, fn func(*model.Recording)) (model.Recording, bool) {
+	var rec model.Recording
+	err := s.tx(func(tx *sql.Tx) error {
+		var err error
+		if rec, err = scanRecording(tx.QueryRow(`SELECT `+recordingColumns+` FROM recordings WHERE id = $1 FOR UPDATE`, id)); err != nil {
+			return err
+		}
+		fn(&rec)
+		_, err = tx.Exec(`UPDATE recordings SET title = $2, status = $3, started_at = $4, ended_at = $5, duration_sec = $6, segment_count = $7,
+			size_bytes = $8, points_rate = $9 WHERE id = $1`,
+			id, rec.Title, rec.Status, rec.StartedAt, nullTime(rec.EndedAt), rec.DurationSec, rec.SegmentCount, rec.SizeBytes, rec.PointsRate)
+		return err
+	})
+	return rec, s.found("update recording", err)
+}
+
+func (s *PostgresStore) DeleteRecording(id string) bool {
+	res, err := s.db.Exec(`DELETE FROM recordings WHERE id = $1`, id)
+	return s.affected("delete recording", res, err)
+}
+
+const clipColumns = `id, stream_id, coalesce(recording_id, ''), title, format, status, start_at, end_at, duration_sec, size_bytes, points_rate,
+	created_by, created_at, coalesce(error, '')`
+
+func scanClip(r scanner) (model.Clip, error) {
+	var c model.Clip
+	err := r.Scan(&c.ID, &c.StreamID, &c.RecordingID, &c.Title, &c.Format, &c.Status, utcTime{&c.StartAt}, utcTime{&c.EndAt}, &c.DurationSec, &c.SizeBytes, &c.PointsRate,
+		&c.CreatedBy, utcTime{&c.CreatedAt}, &c.Error)
+	return c, err
+}
+
+func (s *PostgresStore) CreateClip(c model.Clip) model.Clip {
+	c.ID = "clip_" + s.ids.New()
+	c.CreatedAt = now()
+	_, err := s.db.Exec(`INSERT INTO clips (id, stream_id, recording_id, title, format, status, start_at, end_at, duration_sec, size_bytes, points_rate,
+		created_by, created_at, error) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		c.ID, c.StreamID, nullString(c.RecordingID), c.Title, c.Format, c.Status, c.StartAt, c.EndAt, c.DurationSec, c.SizeBytes, c.PointsRate,
+		c.CreatedBy, c.CreatedAt, nullString(c.Error))
+	s.logged("create clip", err)
+	return c
+}
+
+func (s *PostgresStore) GetClip(id string) (model.Clip, bool) {
+	c, err := scanClip(s.db.QueryRow(`SELECT `+clipColumns+` FROM clips WHERE id = $1`, id))
+	return c, s.found("get clip", err)
+}
+
+func (s *PostgresStore) ListClips(streamID string) []model.Clip {
+	return queryRows(s, "list clips", scanClip, `SELECT `+clipColumns+` FROM clips WHERE $1 = '' OR stream_id = $1 ORDER BY created_at DESC`, streamID)
+}
+
+func (s *PostgresStore) UpdateClip(id string, fn func(*model.Clip)) (model.Clip, bool) {
+	var c model.Clip
+	err := s.tx(func(tx *sql.Tx) error {
+		var err error
+		if c, err = scanClip(tx.QueryRow(`SELECT `+clipColumns+` FROM clips WHERE id = $1 FOR UPDATE`, id)); err != nil {
+			return err
+		}
+		fn(&c)
+		_, err = tx.Exec(`UPDATE clips SET title = $2, status = $3, start_at = $4, end_at = $5, duration_sec = $6, size_bytes = $7, points_rate = $8,
+			error = $9 WHERE id = $1`,
+			id, c.Title, c.Status, c.StartAt, c.EndAt, c.DurationSec, c.SizeBytes, c.PointsRate, nullString(c.Error))
+		return err
+	})
+	return c, s.found("update clip", err)
+}
+
+func (s *PostgresStore) DeleteClip(id string) bool {
+	res, err := s.db.Exec(`DELETE FROM clips WHERE id = $1`, id)
+	return s.affected("delete clip", res, err)
+}
+
+const scheduleColumns = `id, stream_id, title, description, category, start_at, coalesce(cron, ''), timezone, duration_minutes, enabled,
+	created_by, created_at, last_started_at, last_stopped_at, coalesce(last_error, '')`
+
+func scanSchedule(r scanner) (model.Schedule, error) {
+	var sc model.Schedule
+	err := r.Scan(&sc.ID, &sc.StreamID, &sc.Title, &sc.Description, &sc.Category, utcTime{&sc.StartAt}, &sc.Cron, &sc.Timezone, &sc.DurationMinutes, &sc.Enabled,
+		&sc.CreatedBy, utcTime{&sc.CreatedAt}, utcTime{&sc.LastStartedAt}, utcTime{&sc.LastStoppedAt}, &sc.LastError)
+	return sc, err
+}
+
+func (s *PostgresStore) CreateSchedule(sc model.Schedule) model.Schedule {
+	sc.ID = "sch_" + s.ids.New()
+	sc.CreatedAt = now()
+	_, err := s.db.Exec(`INSERT INTO stream_schedules (id, stream_id, title, description, category, start_at, cron, timezone, duration_minutes, enabled,
+		created_by, created_at, last_started_at, last_stopped_at, last_error) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		sc.ID, sc.StreamID, sc.Title, sc.Description, sc.Category, nullTime(sc.StartAt), nullString(sc.Cron), sc.Timezone, sc.DurationMinutes, sc.Enabled,
+		sc.CreatedBy, sc.CreatedAt, nullTime(sc.LastStartedAt), nullTime(sc.LastStoppedAt), nullString(sc.LastError))
+	s.logged("create schedule", err)
+	return sc
+}
+
+func (s *PostgresStore) GetSchedule(id string) (model.Schedule, bool) {
+	sc, err := scanSchedule(s.db.QueryRow(`SELECT `+scheduleColumns+` FROM stream_schedules WHERE id = $1`, id))
+	return sc, s.found("get schedule", err)
+}
+
+func (s *PostgresStore) ListSchedules(streamID string) []model.Schedule {
+	return queryRows(s, "list schedules", scanSchedule, `SELECT `+scheduleColumns+` FROM stream_schedules WHERE $1 = '' OR stream_id = $1 ORDER BY created_at, id`, streamID)
+}
+
+func (s *PostgresStore) UpdateSchedule(id string, fn func(*model.Schedule)) (model.Schedule, bool) {
+	var sc model.Schedule
+	err := s.tx(func(tx *sql.Tx) error {
+		var err error
+		if sc, err = scanSchedule(tx.QueryRow(`SELECT `+scheduleColumns+` FROM stream_schedules WHERE id = $1 FOR UPDATE`, id)); err != nil {
+			return err
+		}
+		fn(&sc)
+		_, err = tx.Exec(`UPDATE stream_schedules SET title = $2, description = $3, category = $4, start_at = $5, cron = $6, timezone = $7,
+			duration_minutes = $8, enabled = $9, last_started_at = $10, last_stopped_at = $11, last_error = $12 WHERE id = $1`,
+			id, sc.Title, sc.Description, sc.Category, nullTime(sc.StartAt), nullString(sc.Cron), sc.Timezone,
+			sc.DurationMinutes, sc.Enabled, nullTime(sc.LastStartedAt), nullTime(sc.LastStoppedAt), nullString(sc.LastError))
+		return err
+	})
+	return sc, s.found("update schedule", err)
+}
+
+func (s *PostgresStore) DeleteSchedule(id string) bool {
+	res, err := s.db.Exec(`DELETE FROM stream_schedules WHERE id = $1`, id)
+	return s.affected("delete schedule", res, err)
+}
+
+const programmeColumns = `id, stream_id, channel_id, title, description, category, start_at, end_at, imported_at`
+
+func scanProgramme(r scanner) (model.Programme, error) {
+	var p model.Programme
+	err := r.Scan(&p.ID, &p.StreamID, &p.ChannelID, &p.Title, &p.Description, &p.Category, utcTime{&p.StartAt}, utcTime{&p.EndAt}, utcTime{&p.ImportedAt})
+	return p, err
+}
+
+func (s *PostgresStore) ReplaceProgrammes(streamID string, from, to time.Time, ps []model.Programme) int {
+	var removed int64
+	err := s.tx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM epg_programmes WHERE stream_id = $1 AND start_at < $3 AND end_at > $2`, streamID, from, to)
+		if err != nil {
+			return err
+		}
+		if removed, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		imported := now()
+		for _, p := range ps {
+			if _, err := tx.Exec(`INSERT INTO epg_programmes (id, stream_id, channel_id, title, description, category, start_at, end_at, imported_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+				"p_"+s.ids.New(), streamID, p.ChannelID, p.Title, p.Description, p.Category, p.StartAt, p.EndAt, imported); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logged("replace programmes", err)
+		return 0
+	}
+	return int(removed)
+}
+
+func (s *PostgresStore) ListProgrammes(streamID string, from, to time.Time) []model.Programme {
+	return queryRows(s, "list programmes", scanProgramme, `SELECT `+programmeColumns+` FROM epg_programmes
+		WHERE ($1 = '' OR stream_id = $1) AND start_at < $3 AND end_at > $2 ORDER BY start_at, id`, streamID, from, to)
+}
+
+func limitArg(limit int) any {
+	if limit <= 0 {
+		return nil
+	}
+	return limit
+}
+
+func (s *PostgresStore) AppendStateChange(c model.StreamStateChange) model.StreamStateChange {
+	c.ID = "sh_" + s.ids.New()
+	if c.At.IsZero() {
+		c.At = now()
+	}
+	_, err := s.db.Exec(`INSERT INTO stream_state_history (id, stream_id, from_state, to_state, actor, reason, at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		c.ID, c.StreamID, c.From, c.To, c.Actor, c.Reason, c.At)
+	s.logged("append state change", err)
+	return c
+}
+
+func (s *PostgresStore) ListStateHistory(streamID string, limit int) []model.StreamStateChange {
+	return queryRows(s, "list state history", func(r scanner) (model.StreamStateChange, error) {
+		var c model.StreamStateChange
+		err := r.Scan(&c.ID, &c.StreamID, &c.From, &c.To, &c.Actor, &c.Reason, utcTime{&c.At})
+		return c, err
+	}, `SELECT id, stream_id, from_state, to_state, actor, reason, at FROM stream_state_history
+		WHERE stream_id = $1 ORDER BY at DESC, id DESC LIMIT $2`, streamID, limitArg(limit))
+}
+
+func (s *PostgresStore) AppendAudit(e model.AuditEntry) model.AuditEntry {
+	e.ID = "a_" + s.ids.New()
+	e.At = now()
+	_, err := s.db.Exec(`INSERT INTO audit_log (id, at, actor, action, stream_id, detail) VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.ID, e.At, e.Actor, e.Action, nullString(e.StreamID), e.Detail)
+	s.logged("append audit", err)
+	return e
+}
+
+func (s *PostgresStore) ListAudit(streamID string, limit int) []model.AuditEntry {
+	return queryRows(s, "list audit", func(r scanner) (model.AuditEntry, error) {
+		var e model.AuditEntry
+		err := r.Scan(&e.ID, utcTime{&e.At}, &e.Actor, &e.Action, &e.StreamID, &e.Detail)
+		return e, err
+	}, `SELECT id, at, actor, action, coalesce(stream_id, ''), detail FROM audit_log
+		WHERE $1 = '' OR stream_id = $1 ORDER BY at DESC, id DESC LIMIT $2`, streamID, limitArg(limit))
+}
+
+func (s *PostgresStore) count(op, query string, args ...any) int {
+	var n int
+	s.logged(op, s.db.QueryRow(query, args...).Scan(&n))
+	return n
+}
+
+func (s *PostgresStore) ActiveViewerCount(streamID string) int {
+	return s.count("active viewer count", `SELECT count(*) FROM playback_sessions WHERE stream_id = $1 AND state = 'active'`, streamID)
+}
+
+func (s *PostgresStore) ActiveUserSessionCount(userID string) int {
+	return s.count("active user session count", `SELECT count(*) FROM playback_sessions WHERE user_id = $1 AND state = 'active'`, userID)
+}
+
+func (s *PostgresStore) GetWallet(userID string) (model.Wallet, bool) {
+	var w model.Wallet
+	err := s.db.QueryRow(`SELECT user_id, balance_points FROM wallets WHERE user_id = $1`, userID).Scan(&w.UserID, &w.Balance)
+	return w, s.found("get wallet", err)
+}
+
+const sessionColumns = `id, user_id, stream_id, state, started_at, last_seen_at, coalesce(ip, ''), coalesce(user_agent, ''), timeshift_offset_sec,
+	coalesce(dvr_mode, ''), coalesce(recording_id, ''), coalesce(clip_id, ''), coalesce(source, ''), billing_mode`
+
+func scanSession(r scanner) (model.Session, error) {
+	var ss model.Session
+	err := r.Scan(&ss.ID, &ss.UserID, &ss.StreamID, &ss.State, utcTime{&ss.StartedAt}, utcTime{&ss.LastSeenAt}, &ss.IP, &ss.UserAgent, &ss.TimeshiftOffsetSec,
+		&ss.DVRMode, &ss.RecordingID, &ss.ClipID, &ss.Source, &ss.BillingMode)
+	return ss, err
+}
+
+func (s *PostgresStore) CreateSession(ss model.Session) model.Session {
+	ss.ID = "s_" + s.ids.New()
+	ss.State = "active"
+	ss.StartedAt = now()
+	ss.LastSeenAt = ss.StartedAt
+	_, err := s.db.Exec(`INSERT INTO playback_sessions (id, user_id, stream_id, state, started_at, last_seen_at, ip, user_agent, timeshift_offset_sec,
+		dvr_mode, recording_id, clip_id, source, billing_mode) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		ss.ID, ss.UserID, ss.StreamID, ss.State, ss.StartedAt, ss.LastSeenAt, nullString(ss.IP), nullString(ss.UserAgent), ss.TimeshiftOffsetSec,
+		nullString(ss.DVRMode), nullString(ss.RecordingID), nullString(ss.ClipID), nullString(ss.Source), ss.BillingMode)
+	s.logged("create session", err)
+	return ss
+}
+
+func (s *PostgresStore) GetSession(sessionID string) (model.Session, bool) {
+	ss, err := scanSession(s.db.QueryRow(`SELECT `+sessionColumns+` FROM playback_sessions WHERE id = $1`, sessionID))
+	return ss, s.found("get session", err)
+}
+
+func (s *PostgresStore) ListUserSessions(userID string) []model.Session {
+	return queryRows(s, "list user sessions", scanSession, `SELECT `+sessionColumns+` FROM playback_sessions WHERE user_id = $1 ORDER BY started_at, id`, userID)
+}
+
+func (s *PostgresStore) ListActiveSessions() []model.Session {
+	return queryRows(s, "list active sessions", scanSession, `SELECT `+sessionColumns+` FROM playback_sessions WHERE state = 'active'`)
+}
+
+func (s *PostgresStore) UpdateSessionState(sessionID, state string) bool {
+	res, err := s.db.Exec(`UPDATE playback_sessions SET state = $2, last_seen_at = $3 WHERE id = $1`, sessionID, state, now())
+	return s.affected("update session state", res, err)
+}
+
+func (s *PostgresStore) TouchSession(sessionID string) bool {
+	res, err := s.db.Exec(`UPDATE playback_sessions SET last_seen_at = $2 WHERE id = $1`, sessionID, now())
+	return s.affected("touch session", res, err)
+}
+
+func (s *PostgresStore) DeductPoints(userID, streamID, sessionID, reason string, points int64) (int64, error) {
+	var balance int64
+	err := s.tx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`SELECT balance_points FROM wallets WHERE user_id = $1 FOR UPDATE`, userID).Scan(&balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("wallet not found")
+		}
+		if err != nil {
+			return err
+		}
+		if balance <= 0 {
+			return fmt.Errorf("insufficient points")
+		}
+		balance = max(balance-points, 0)
+		at := now()
+		if _, err := tx.Exec(`UPDATE wallets SET balance_points = $2, updated_at = $3 WHERE user_id = $1`, userID, balance, at); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO wallet_ledger (id, user_id, delta_points, reason, stream_id, session_id, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			"l_"+s.ids.New(), userID, -points, reason, nullString(streamID), nullString(sessionID), at)
+		return err
+	})
+	return balance, err
+}
+
+func (s *PostgresStore) Metrics() map[string]int {
+	return map[string]int{
+		"active_sessions": s.count("metrics", `SELECT count(*) FROM playback_sessions WHERE state = 'active'`),
+		"ledger_entries":  s.count("metrics", `SELECT count(*) FROM wallet_ledger`),
+	}
+}
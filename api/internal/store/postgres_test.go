@@ -0,0 +1,95 @@
+package store
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+)
+
+func openTestPostgres(t *testing.T) func() *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv("STREAMWEB_TEST_DSN")
+	if dsn == "" {
+		t.Skip("STREAMWEB_TEST_DSN not set")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { admin.Close() })
+	schema := "streamweb_test_" + ids.NewULID().New()
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+	cfg.RuntimeParams["search_path"] = schema
+
+	open := func() *PostgresStore {
+		db := stdlib.OpenDB(*cfg)
+		t.Cleanup(func() { db.Close() })
+		return newPostgresStore(db, ids.NewULID())
+	}
+	s := open()
+	migrations, err := filepath.Glob("../../../db/migrations/*.sql")
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	for _, path := range migrations {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.db.Exec(string(b)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(path), err)
+		}
+	}
+	seed(t, s.db)
+	return open
+}
+
+func seed(t *testing.T, db *sql.DB) {
+	t.Helper()
+	stmts := []string{
+		`INSERT INTO users (id, email, password_hash, role, status) VALUES ('u_admin', 'admin@local', 'admin', 'admin', 'active'), ('u_demo', 'demo@local', 'demo', 'user', 'active')`,
+		`INSERT INTO wallets (user_id, balance_points) VALUES ('u_demo', 1000)`,
+	}
+	if _, err := db.Exec(strings.Join(stmts, ";\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	open := openTestPostgres(t)
+	testRepository(t, open())
+}
+
+func TestPostgresStoreSurvivesRestart(t *testing.T) {
+	open := openTestPostgres(t)
+	s := open()
+	st := suiteStream("restart", "Restart", "paused", 3)
+	if _, err := s.CreateStream(st); err != nil {
+		t.Fatal(err)
+	}
+	sc := s.CreateSchedule(model.Schedule{StreamID: st.ID, Title: "Nightly", Cron: "0 2 * * *", Timezone: "UTC", DurationMinutes: 30, Enabled: true, CreatedBy: "u_admin"})
+	sc, _ = s.UpdateSchedule(sc.ID, func(sc *model.Schedule) { sc.LastStartedAt, sc.LastStoppedAt = base, base.Add(30*time.Minute) })
+	s.UpdateStream(st.ID, func(st *model.Stream) { st.Status = "live" })
+	s.Close()
+
+	s = open()
+	if got, ok := s.GetSchedule(sc.ID); !ok || !got.LastStartedAt.Equal(base) || !got.LastStoppedAt.Equal(sc.LastStoppedAt) || !got.Enabled {
+		t.Errorf("schedule after reopen = %+v, %v; want %+v", got, ok, sc)
+	}
+	if got, ok := s.GetStream(st.ID); !ok || got.Status != "live" || got.Version != 2 {
+		t.Errorf("stream after reopen = %+v, %v", got, ok)
+	}
+}
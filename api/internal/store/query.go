@@ -0,0 +1,78 @@
+package store
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"streamweb/api/internal/model"
+)
+
+var StreamSortFields = []string{"name", "id", "status", "created_at", "points_rate"}
+
+type StreamVisibility struct {
+	Statuses []string
+	IDs      []string
+}
+
+type StreamQuery struct {
+	Statuses       []string
+	Category       string
+	Search         string
+	Visible        *StreamVisibility
+	IncludeDeleted bool
+	Sort           string
+	Desc           bool
+	Limit          int
+	Offset         int
+}
+
+func (q StreamQuery) Match(st model.Stream) bool {
+	if !q.IncludeDeleted && !st.DeletedAt.IsZero() {
+		return false
+	}
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, st.Status) {
+		return false
+	}
+	if q.Category != "" && !strings.EqualFold(st.Group, q.Category) {
+		return false
+	}
+	if q.Visible != nil && !slices.Contains(q.Visible.Statuses, st.Status) && !slices.Contains(q.Visible.IDs, st.ID) {
+		return false
+	}
+	if q.Search != "" {
+		needle := strings.ToLower(q.Search)
+		hay := strings.ToLower(strings.Join([]string{st.ID, st.Name, st.ExternalID, st.Group}, "\n"))
+		if !strings.Contains(hay, needle) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortStreams(out []model.Stream, field string, desc bool) {
+	less := func(a, b model.Stream) int {
+		switch field {
+		case "id":
+			return strings.Compare(a.ID, b.ID)
+		case "status":
+			return strings.Compare(a.Status, b.Status)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "points_rate":
+			return a.PointsRate - b.PointsRate
+		default:
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		c := less(out[i], out[j])
+		if c == 0 {
+			return out[i].ID < out[j].ID
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
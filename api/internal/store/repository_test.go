@@ -0,0 +1,400 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"streamweb/api/internal/ids"
+	"streamweb/api/internal/model"
+)
+
+var base = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testRepository(t *testing.T, r Repository) {
+	t.Helper()
+	testUsers(t, r)
+	testStreams(t, r)
+	testCompareAndSwapRace(t, r)
+	testRuntime(t, r)
+	testRecordingsAndClips(t, r)
+	testSchedules(t, r)
+	testProgrammes(t, r)
+	testHistory(t, r)
+	testSessions(t, r)
+}
+
+func testUsers(t *testing.T, r Repository) {
+	if u, ok := r.FindUserByEmail("admin@local"); !ok || u.ID != "u_admin" || u.Role != "admin" {
+		t.Errorf("FindUserByEmail(admin@local) = %+v, %v", u, ok)
+	}
+	if _, ok := r.GetUser("u_demo"); !ok {
+		t.Error("GetUser(u_demo) not found")
+	}
+	if _, ok := r.FindUserByPlaylistKey(""); ok {
+		t.Error("FindUserByPlaylistKey(\"\") found a user")
+	}
+	if u, ok := r.UpdateUser("u_demo", func(u *model.User) { u.PlaylistKey = "pk-demo" }); !ok || u.PlaylistKey != "pk-demo" {
+		t.Errorf("UpdateUser = %+v, %v", u, ok)
+	}
+	if u, ok := r.FindUserByPlaylistKey("pk-demo"); !ok || u.ID != "u_demo" {
+		t.Errorf("FindUserByPlaylistKey(pk-demo) = %+v, %v", u, ok)
+	}
+	if _, ok := r.UpdateUser("u_missing", func(*model.User) {}); ok {
+		t.Error("UpdateUser(u_missing) succeeded")
+	}
+}
+
+func suiteStream(id, name, status string, points int) model.Stream {
+	return model.Stream{
+		ID: id, Name: name, Status: status, IngestMode: "url", IngestURL: "https://origin.example/" + id + ".m3u8",
+		Group: "Suite", SegmentDurationSec: 4, PlaylistWindowMinutes: 2, PointsRate: points, MaxConcurrentSessions: 2,
+		CreatedAt: base.Add(time.Duration(points) * time.Minute),
+	}
+}
+
+func queryIDs(r Repository, q StreamQuery) ([]string, int) {
+	list, total := r.QueryStreams(q)
+	out := []string{}
+	for _, st := range list {
+		out = append(out, st.ID)
+	}
+	return out, total
+}
+
+func testStreams(t *testing.T, r Repository) {
+	a := suiteStream("suite-a", "Alpha", "live", 5)
+	a.ExternalID, a.LogoURL, a.StreamKey = "ext-alpha", "https://img.example/a.png", "key-alpha"
+	a.BackupIngestURLs, a.FailoverAfterSec = []string{"https://backup.example/a.m3u8"}, 10
+	a.ABRProfiles = []model.ABRProfile{{Name: "720p", Width: 1280, Height: 720, VideoBitrateKbps: 2800, AudioBitrateKbps: 128, Codec: "h264", Profile: "main", KeyframeIntervalSec: 2}}
+	a.DVRWindowMinutes, a.RecordingEnabled, a.RecordingPointsRate = 30, true, 2
+	created, err := r.CreateStream(a)
+	if err != nil {
+		t.Fatalf("CreateStream: %v", err)
+	}
+	if created.Version != 1 {
+		t.Errorf("CreateStream version = %d, want 1", created.Version)
+	}
+	if got, ok := r.GetStream("suite-a"); !ok || !reflect.DeepEqual(got, created) {
+		t.Errorf("GetStream = %+v, %v\nwant %+v", got, ok, created)
+	}
+	if _, err := r.CreateStream(a); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateStream duplicate = %v, want ErrAlreadyExists", err)
+	}
+	for _, st := range []model.Stream{suiteStream("suite-b", "bravo", "paused", 9), suiteStream("suite-c", "Charlie", "live", 1)} {
+		if _, err := r.CreateStream(st); err != nil {
+			t.Fatalf("CreateStream(%s): %v", st.ID, err)
+		}
+	}
+	if st, ok := r.FindStreamByKey("key-alpha"); !ok || st.ID != "suite-a" {
+		t.Errorf("FindStreamByKey = %+v, %v", st, ok)
+	}
+	if _, ok := r.FindStreamByKey(""); ok {
+		t.Error("FindStreamByKey(\"\") found a stream")
+	}
+
+	st, ok := r.UpdateStream("suite-a", func(st *model.Stream) { st.Name = "Alpha One" })
+	if !ok || st.Name != "Alpha One" || st.Version != 2 {
+		t.Errorf("UpdateStream = %+v, %v", st, ok)
+	}
+	if _, ok := r.UpdateStream("suite-missing", func(*model.Stream) {}); ok {
+		t.Error("UpdateStream(missing) succeeded")
+	}
+	called := false
+	st, err = r.CompareAndSwapStream("suite-a", 1, func(*model.Stream) { called = true })
+	if !errors.Is(err, ErrVersionMismatch) || called || st.Version != 2 {
+		t.Errorf("CompareAndSwapStream(stale) = version %d, called %v, %v; want the current version 2 and ErrVersionMismatch", st.Version, called, err)
+	}
+	st, err = r.CompareAndSwapStream("suite-a", 2, func(st *model.Stream) { st.PointsRate = 6 })
+	if err != nil || st.Version != 3 || st.PointsRate != 6 {
+		t.Errorf("CompareAndSwapStream(current) = %+v, %v", st, err)
+	}
+	if got, _ := r.GetStream("suite-a"); got.Version != 3 || got.PointsRate != 6 || got.Name != "Alpha One" {
+		t.Errorf("GetStream after swap = %+v", got)
+	}
+	if _, err := r.CompareAndSwapStream("suite-missing", 1, func(*model.Stream) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CompareAndSwapStream(missing) = %v, want ErrNotFound", err)
+	}
+
+	if _, ok := r.UpdateStream("suite-c", func(st *model.Stream) { st.Status, st.DeletedAt = "disabled", base }); !ok {
+		t.Fatal("soft delete failed")
+	}
+	if got, _ := r.GetStream("suite-c"); !got.DeletedAt.Equal(base) {
+		t.Errorf("DeletedAt = %v, want %v", got.DeletedAt, base)
+	}
+
+	suite := StreamQuery{Category: "SUITE"}
+	for _, tc := range []struct {
+		name  string
+		q     func(StreamQuery) StreamQuery
+		want  []string
+		total int
+	}{
+		{"category", func(q StreamQuery) StreamQuery { return q }, []string{"suite-a", "suite-b"}, 2},
+		{"include deleted", func(q StreamQuery) StreamQuery { q.IncludeDeleted = true; return q }, []string{"suite-a", "suite-b", "suite-c"}, 3},
+		{"status", func(q StreamQuery) StreamQuery { q.Statuses = []string{"paused"}; return q }, []string{"suite-b"}, 1},
+		{"search external id", func(q StreamQuery) StreamQuery { q.Search = "EXT-ALPH"; return q }, []string{"suite-a"}, 1},
+		{"search name", func(q StreamQuery) StreamQuery { q.Search = "brav"; return q }, []string{"suite-b"}, 1},
+		{"visible status", func(q StreamQuery) StreamQuery { q.Visible = &StreamVisibility{Statuses: []string{"live"}}; return q }, []string{"suite-a"}, 1},
+		{"visible id", func(q StreamQuery) StreamQuery {
+			q.Visible = &StreamVisibility{Statuses: []string{"live"}, IDs: []string{"suite-b"}}
+			return q
+		}, []string{"suite-a", "suite-b"}, 2},
+		{"sort points desc", func(q StreamQuery) StreamQuery {
+			q.IncludeDeleted, q.Sort, q.Desc = true, "points_rate", true
+			return q
+		}, []string{"suite-b", "suite-a", "suite-c"}, 3},
+		{"sort created_at", func(q StreamQuery) StreamQuery { q.IncludeDeleted, q.Sort = true, "created_at"; return q }, []string{"suite-c", "suite-a", "suite-b"}, 3},
+		{"sort id desc", func(q StreamQuery) StreamQuery { q.IncludeDeleted, q.Sort, q.Desc = true, "id", true; return q }, []string{"suite-c", "suite-b", "suite-a"}, 3},
+		{"page", func(q StreamQuery) StreamQuery { q.IncludeDeleted, q.Limit, q.Offset = true, 1, 1; return q }, []string{"suite-b"}, 3},
+		{"past the end", func(q StreamQuery) StreamQuery { q.Offset = 5; return q }, []string{}, 2},
+	} {
+		got, total := queryIDs(r, tc.q(suite))
+		if !slices.Equal(got, tc.want) || total != tc.total {
+			t.Errorf("QueryStreams %s = %v (total %d), want %v (total %d)", tc.name, got, total, tc.want, tc.total)
+		}
+	}
+
+	var listed []string
+	for _, st := range r.ListStreams() {
+		listed = append(listed, st.ID)
+	}
+	if !slices.IsSorted(listed) || !slices.Contains(listed, "suite-a") || !slices.Contains(listed, "suite-c") {
+		t.Errorf("ListStreams = %v, want every stream sorted by id", listed)
+	}
+}
+
+func testCompareAndSwapRace(t *testing.T, r Repository) {
+	st, err := r.CreateStream(suiteStream("suite-race", "Race", "paused", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const writers = 8
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		wins []int
+	)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.CompareAndSwapStream(st.ID, st.Version, func(st *model.Stream) { st.PointsRate = i + 1 })
+			switch {
+			case err == nil:
+				mu.Lock()
+				wins = append(wins, i+1)
+				mu.Unlock()
+			case !errors.Is(err, ErrVersionMismatch):
+				t.Errorf("CompareAndSwapStream: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, _ := r.GetStream(st.ID)
+	if len(wins) != 1 || got.Version != st.Version+1 || got.PointsRate != wins[0] {
+		t.Errorf("%d writers swapping version %d: winners %v, stored version %d points %d; want exactly one winner", writers, st.Version, wins, got.Version, got.PointsRate)
+	}
+}
+
+func testRuntime(t *testing.T, r Repository) {
+	if _, ok := r.GetRuntime("suite-b"); ok {
+		t.Error("GetRuntime before any update found a runtime")
+	}
+	rt := r.UpdateRuntime("suite-b", func(rt *model.StreamRuntime) {
+		rt.ActualState, rt.WorkerID, rt.LastHeartbeatAt, rt.SegmentCount = "running", "w1", base, 10
+	})
+	if rt.StreamID != "suite-b" || rt.ActualState != "running" {
+		t.Errorf("UpdateRuntime = %+v", rt)
+	}
+	r.UpdateRuntime("suite-b", func(rt *model.StreamRuntime) { rt.SegmentCount++ })
+	got, ok := r.GetRuntime("suite-b")
+	want := model.StreamRuntime{StreamID: "suite-b", ActualState: "running", WorkerID: "w1", LastHeartbeatAt: base, SegmentCount: 11}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRuntime = %+v, %v\nwant %+v", got, ok, want)
+	}
+}
+
+func testRecordingsAndClips(t *testing.T, r Repository) {
+	older := r.CreateRecording(model.Recording{StreamID: "suite-a", Title: "first", Status: "recording", StartedAt: base, PointsRate: 2})
+	newer := r.CreateRecording(model.Recording{StreamID: "suite-a", Title: "second", Status: "recording", StartedAt: base.Add(time.Hour), PointsRate: 2})
+	if older.ID == "" || older.ID == newer.ID {
+		t.Fatalf("CreateRecording ids = %q, %q", older.ID, newer.ID)
+	}
+	if list := r.ListRecordings("suite-a"); len(list) != 2 || list[0].ID != newer.ID || list[1].ID != older.ID {
+		t.Errorf("ListRecordings = %+v, want newest first", list)
+	}
+	rec, ok := r.UpdateRecording(older.ID, func(rec *model.Recording) {
+		rec.Status, rec.EndedAt, rec.DurationSec, rec.SegmentCount, rec.SizeBytes = "ready", base.Add(30*time.Minute), 1800.5, 450, 1<<30
+	})
+	if !ok {
+		t.Fatal("UpdateRecording failed")
+	}
+	if got, ok := r.GetRecording(older.ID); !ok || !reflect.DeepEqual(got, rec) {
+		t.Errorf("GetRecording = %+v, %v\nwant %+v", got, ok, rec)
+	}
+	if len(r.ListRecordings("")) < 2 || len(r.ListRecordings("suite-b")) != 0 {
+		t.Error("ListRecordings filters by stream only when one is given")
+	}
+
+	c := r.CreateClip(model.Clip{StreamID: "suite-a", RecordingID: older.ID, Title: "goal", Format: "hls", Status: "processing",
+		StartAt: base.Add(time.Minute), EndAt: base.Add(2 * time.Minute), PointsRate: 1, CreatedBy: "u_admin"})
+	if c.ID == "" || c.CreatedAt.IsZero() {
+		t.Errorf("CreateClip = %+v", c)
+	}
+	c, _ = r.UpdateClip(c.ID, func(c *model.Clip) { c.Status, c.Error = "failed", "no segments" })
+	if got, ok := r.GetClip(c.ID); !ok || !reflect.DeepEqual(got, c) {
+		t.Errorf("GetClip = %+v, %v\nwant %+v", got, ok, c)
+	}
+	if list := r.ListClips("suite-a"); len(list) != 1 || list[0].ID != c.ID {
+		t.Errorf("ListClips = %+v", list)
+	}
+	if !r.DeleteClip(c.ID) || r.DeleteClip(c.ID) {
+		t.Error("DeleteClip should succeed once")
+	}
+	if _, ok := r.GetClip(c.ID); ok {
+		t.Error("GetClip after delete found the clip")
+	}
+	if !r.DeleteRecording(newer.ID) || r.DeleteRecording(newer.ID) {
+		t.Error("DeleteRecording should succeed once")
+	}
+	if _, ok := r.GetRecording(newer.ID); ok {
+		t.Error("GetRecording after delete found the recording")
+	}
+}
+
+func testSchedules(t *testing.T, r Repository) {
+	once := r.CreateSchedule(model.Schedule{StreamID: "suite-a", Title: "Match", StartAt: base, Timezone: "UTC", DurationMinutes: 90, Enabled: true, CreatedBy: "u_admin"})
+	weekly := r.CreateSchedule(model.Schedule{StreamID: "suite-a", Title: "Show", Cron: "0 20 * * 5", Timezone: "Europe/Berlin", DurationMinutes: 60, CreatedBy: "u_admin"})
+	if once.ID == "" || once.CreatedAt.IsZero() {
+		t.Errorf("CreateSchedule = %+v", once)
+	}
+	if list := r.ListSchedules("suite-a"); len(list) != 2 || list[0].ID != once.ID || list[1].ID != weekly.ID {
+		t.Errorf("ListSchedules = %+v, want creation order", list)
+	}
+	sc, ok := r.UpdateSchedule(once.ID, func(sc *model.Schedule) {
+		sc.LastStartedAt, sc.LastStoppedAt, sc.LastError = base, base.Add(90*time.Minute), "probe failed"
+	})
+	if !ok {
+		t.Fatal("UpdateSchedule failed")
+	}
+	if got, ok := r.GetSchedule(once.ID); !ok || !reflect.DeepEqual(got, sc) {
+		t.Errorf("GetSchedule = %+v, %v\nwant %+v", got, ok, sc)
+	}
+	if got, _ := r.GetSchedule(weekly.ID); got.Cron != "0 20 * * 5" || !got.StartAt.IsZero() || got.Enabled {
+		t.Errorf("GetSchedule(cron) = %+v", got)
+	}
+	if !r.DeleteSchedule(weekly.ID) || r.DeleteSchedule(weekly.ID) {
+		t.Error("DeleteSchedule should succeed once")
+	}
+	if _, ok := r.UpdateSchedule(weekly.ID, func(*model.Schedule) {}); ok {
+		t.Error("UpdateSchedule after delete succeeded")
+	}
+}
+
+func testProgrammes(t *testing.T, r Repository) {
+	prog := func(title string, start time.Duration) model.Programme {
+		return model.Programme{ChannelID: "alpha.tv", Title: title, StartAt: base.Add(start), EndAt: base.Add(start + 30*time.Minute)}
+	}
+	if n := r.ReplaceProgrammes("suite-a", base, base.Add(2*time.Hour), []model.Programme{prog("late", time.Hour), prog("early", 0)}); n != 0 {
+		t.Errorf("ReplaceProgrammes into an empty guide removed %d", n)
+	}
+	list := r.ListProgrammes("suite-a", base, base.Add(2*time.Hour))
+	if len(list) != 2 || list[0].Title != "early" || list[1].Title != "late" || list[0].StreamID != "suite-a" || list[0].ImportedAt.IsZero() {
+		t.Errorf("ListProgrammes = %+v", list)
+	}
+	if n := r.ReplaceProgrammes("suite-a", base.Add(50*time.Minute), base.Add(3*time.Hour), []model.Programme{prog("new", 2*time.Hour)}); n != 1 {
+		t.Errorf("ReplaceProgrammes removed %d, want the one overlapping programme", n)
+	}
+	var titles []string
+	for _, p := range r.ListProgrammes("", base, base.Add(4*time.Hour)) {
+		titles = append(titles, p.Title)
+	}
+	if !slices.Equal(titles, []string{"early", "new"}) {
+		t.Errorf("ListProgrammes after replace = %v", titles)
+	}
+}
+
+func testHistory(t *testing.T, r Repository) {
+	for _, to := range []string{"starting", "live", "paused"} {
+		r.AppendStateChange(model.StreamStateChange{StreamID: "suite-b", From: "x", To: to, Actor: "user:u_admin"})
+	}
+	var got []string
+	for _, c := range r.ListStateHistory("suite-b", 2) {
+		got = append(got, c.To)
+	}
+	if !slices.Equal(got, []string{"paused", "live"}) {
+		t.Errorf("ListStateHistory(limit 2) = %v, want newest first", got)
+	}
+	if n := len(r.ListStateHistory("suite-b", 0)); n != 3 {
+		t.Errorf("ListStateHistory(no limit) = %d entries, want 3", n)
+	}
+
+	r.AppendAudit(model.AuditEntry{Actor: "user:u_admin", Action: "streams.import", Detail: "created 2"})
+	e := r.AppendAudit(model.AuditEntry{Actor: "user:u_admin", Action: "stream.delete", StreamID: "suite-c", Detail: "was live"})
+	if e.ID == "" || e.At.IsZero() {
+		t.Errorf("AppendAudit = %+v", e)
+	}
+	if list := r.ListAudit("suite-c", 0); len(list) != 1 || list[0].ID != e.ID || list[0].Detail != "was live" {
+		t.Errorf("ListAudit(suite-c) = %+v", list)
+	}
+	if list := r.ListAudit("", 2); len(list) != 2 || list[0].ID != e.ID || list[1].StreamID != "" {
+		t.Errorf("ListAudit(all, 2) = %+v, want newest first", list)
+	}
+}
+
+func testSessions(t *testing.T, r Repository) {
+	ledger := r.Metrics()["ledger_entries"]
+	ss := r.CreateSession(model.Session{UserID: "u_demo", StreamID: "suite-a", IP: "203.0.113.9", UserAgent: "vlc", TimeshiftOffsetSec: 60, DVRMode: "timeshift", BillingMode: "segments"})
+	if ss.ID == "" || ss.State != "active" || ss.StartedAt.IsZero() {
+		t.Errorf("CreateSession = %+v", ss)
+	}
+	if got, ok := r.GetSession(ss.ID); !ok || !reflect.DeepEqual(got, ss) {
+		t.Errorf("GetSession = %+v, %v\nwant %+v", got, ok, ss)
+	}
+	if r.ActiveViewerCount("suite-a") != 1 || r.ActiveUserSessionCount("u_demo") != 1 || r.Metrics()["active_sessions"] < 1 {
+		t.Error("active counts do not include the new session")
+	}
+	if !r.TouchSession(ss.ID) || r.TouchSession("s_missing") {
+		t.Error("TouchSession should succeed only for existing sessions")
+	}
+	if !r.UpdateSessionState(ss.ID, "expired") || r.UpdateSessionState("s_missing", "stopped") {
+		t.Error("UpdateSessionState should succeed only for existing sessions")
+	}
+	if r.ActiveViewerCount("suite-a") != 0 || slices.ContainsFunc(r.ListActiveSessions(), func(x model.Session) bool { return x.ID == ss.ID }) {
+		t.Error("expired session still counted as active")
+	}
+	if list := r.ListUserSessions("u_demo"); len(list) != 1 || list[0].State != "expired" {
+		t.Errorf("ListUserSessions = %+v", list)
+	}
+
+	for _, tc := range []struct {
+		user    string
+		points  int64
+		want    int64
+		wantErr bool
+	}{
+		{"u_demo", 400, 600, false},
+		{"u_demo", 700, 0, false},
+		{"u_demo", 1, 0, true},
+		{"u_admin", 1, 0, true},
+	} {
+		got, err := r.DeductPoints(tc.user, "suite-a", ss.ID, "heartbeat_deduction", tc.points)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("DeductPoints(%s, %d) = %d, %v; want %d, error %v", tc.user, tc.points, got, err, tc.want, tc.wantErr)
+		}
+	}
+	if w, ok := r.GetWallet("u_demo"); !ok || w.Balance != 0 {
+		t.Errorf("GetWallet = %+v, %v", w, ok)
+	}
+	if n := r.Metrics()["ledger_entries"]; n != ledger+2 {
+		t.Errorf("ledger_entries = %d, want %d", n, ledger+2)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testRepository(t, NewMemoryStore(ids.NewULID()))
+}
@@ -0,0 +1,101 @@
+package xmltv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const timeLayout = "20060102150405 -0700"
+
+var parseLayouts = []string{"20060102150405 -0700", "20060102150405 -07:00", "20060102150405", "200601021504", "20060102"}
+
+type TV struct {
+	XMLName       xml.Name    `xml:"tv"`
+	GeneratorName string      `xml:"generator-info-name,attr,omitempty"`
+	Channels      []Channel   `xml:"channel"`
+	Programmes    []Programme `xml:"programme"`
+}
+
+type Channel struct {
+	ID           string   `xml:"id,attr"`
+	DisplayNames []Text   `xml:"display-name"`
+	Icon         *Icon    `xml:"icon,omitempty"`
+	URLs         []string `xml:"url,omitempty"`
+}
+
+type Programme struct {
+	Start      string `xml:"start,attr"`
+	Stop       string `xml:"stop,attr,omitempty"`
+	Channel    string `xml:"channel,attr"`
+	Titles     []Text `xml:"title"`
+	SubTitles  []Text `xml:"sub-title,omitempty"`
+	Descs      []Text `xml:"desc,omitempty"`
+	Categories []Text `xml:"category,omitempty"`
+	Icon       *Icon  `xml:"icon,omitempty"`
+}
+
+type Text struct {
+	Lang  string `xml:"lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type Icon struct {
+	Src string `xml:"src,attr"`
+}
+
+func first(ts []Text) string {
+	for _, t := range ts {
+		if v := strings.TrimSpace(t.Value); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (c Channel) DisplayName() string   { return first(c.DisplayNames) }
+func (p Programme) Title() string       { return first(p.Titles) }
+func (p Programme) Description() string { return first(p.Descs) }
+func (p Programme) Category() string    { return first(p.Categories) }
+
+func Parse(r io.Reader) (TV, error) {
+	var tv TV
+	if err := xml.NewDecoder(r).Decode(&tv); err != nil {
+		return TV{}, fmt.Errorf("parse xmltv: %w", err)
+	}
+	return tv, nil
+}
+
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range parseLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid xmltv time %q", s)
+}
+
+func FormatTime(t time.Time) string { return t.UTC().Format(timeLayout) }
+
+func Texts(v string) []Text {
+	if v == "" {
+		return nil
+	}
+	return []Text{{Value: v}}
+}
+
+func Write(w io.Writer, tv TV) error {
+	if _, err := io.WriteString(w, xml.Header+`<!DOCTYPE tv SYSTEM "xmltv.dtd">`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(tv); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,87 @@
+package xmltv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"20240301120000 +0000", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"20240301120000 +0100", time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), true},
+		{"20240301120000 -05:30", time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC), true},
+		{" 20240301120000 ", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"202403011200", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"20240301", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-01T12:00:00Z", time.Time{}, false},
+		{"", time.Time{}, false},
+	} {
+		got, err := ParseTime(tc.in)
+		if (err == nil) != tc.ok || !got.Equal(tc.want) {
+			t.Errorf("ParseTime(%q) = %v, %v; want %v, ok=%v", tc.in, got, err, tc.want, tc.ok)
+		}
+		if err == nil && got.Location() != time.UTC {
+			t.Errorf("ParseTime(%q) location = %v, want UTC", tc.in, got.Location())
+		}
+	}
+}
+
+func TestTitles(t *testing.T) {
+	for _, tc := range []struct {
+		ts   []Text
+		want string
+	}{
+		{nil, ""},
+		{[]Text{{Value: "  "}, {Lang: "de", Value: " Nachrichten "}, {Value: "News"}}, "Nachrichten"},
+		{[]Text{{Value: "News"}}, "News"},
+	} {
+		if got := (Programme{Titles: tc.ts}).Title(); got != tc.want {
+			t.Errorf("Title(%+v) = %q, want %q", tc.ts, got, tc.want)
+		}
+	}
+	if Texts("") != nil {
+		t.Error(`Texts("") is not nil`)
+	}
+}
+
+func TestWriteParse(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tv := TV{
+		GeneratorName: "streamweb",
+		Channels:      []Channel{{ID: "one", DisplayNames: Texts("One & Co"), Icon: &Icon{Src: "http://x/l.png"}}},
+		Programmes: []Programme{{
+			Start: FormatTime(start), Stop: FormatTime(start.Add(time.Hour)), Channel: "one",
+			Titles: Texts("News <live>"), Descs: Texts("Headlines"), Categories: Texts("News"),
+		}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, tv); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml") || !strings.Contains(buf.String(), `<!DOCTYPE tv SYSTEM "xmltv.dtd">`) {
+		t.Errorf("Write header =\n%s", buf.String())
+	}
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Channels) != 1 || got.Channels[0].DisplayName() != "One & Co" || got.Channels[0].Icon == nil || got.Channels[0].Icon.Src != "http://x/l.png" {
+		t.Errorf("channels = %+v", got.Channels)
+	}
+	if len(got.Programmes) != 1 {
+		t.Fatalf("programmes = %+v", got.Programmes)
+	}
+	p := got.Programmes[0]
+	if p.Title() != "News <live>" || p.Description() != "Headlines" || p.Category() != "News" || p.Start != "20240301120000 +0000" || p.Stop != "20240301130000 +0000" {
+		t.Errorf("programme = %+v", p)
+	}
+	if _, err := Parse(strings.NewReader("<tv><channel")); err == nil {
+		t.Error("Parse accepted truncated xml")
+	}
+}
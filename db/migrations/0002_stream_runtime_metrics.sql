@@ -0,0 +1,2 @@
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS ingest_bitrate_kbps INT NOT NULL DEFAULT 0;
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS segment_count BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS dvr_window_minutes INT NOT NULL DEFAULT 0;
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS timeshift_offset_sec INT NOT NULL DEFAULT 0;
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS dvr_mode TEXT;
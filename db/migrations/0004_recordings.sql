@@ -0,0 +1,19 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS recording_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS recording_points_rate INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recordings (
+  id TEXT PRIMARY KEY,
+  stream_id TEXT NOT NULL REFERENCES streams(id),
+  title TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('recording','ready','failed')),
+  started_at TIMESTAMPTZ NOT NULL,
+  ended_at TIMESTAMPTZ,
+  duration_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+  segment_count INT NOT NULL DEFAULT 0,
+  size_bytes BIGINT NOT NULL DEFAULT 0,
+  points_rate INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_recordings_stream_started ON recordings(stream_id, started_at DESC);
+
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS recording_id TEXT REFERENCES recordings(id);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS clips (
+  id TEXT PRIMARY KEY,
+  stream_id TEXT NOT NULL REFERENCES streams(id),
+  recording_id TEXT REFERENCES recordings(id),
+  title TEXT NOT NULL,
+  format TEXT NOT NULL CHECK (format IN ('hls','mp4')),
+  status TEXT NOT NULL CHECK (status IN ('processing','ready','failed')),
+  start_at TIMESTAMPTZ NOT NULL,
+  end_at TIMESTAMPTZ NOT NULL,
+  duration_sec DOUBLE PRECISION NOT NULL DEFAULT 0,
+  size_bytes BIGINT NOT NULL DEFAULT 0,
+  points_rate INT NOT NULL,
+  created_by TEXT NOT NULL REFERENCES users(id),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_clips_stream_created ON clips(stream_id, created_at DESC);
+
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS clip_id TEXT REFERENCES clips(id);
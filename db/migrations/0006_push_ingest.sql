@@ -0,0 +1,3 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS stream_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streams_stream_key ON streams(stream_key) WHERE stream_key IS NOT NULL;
+UPDATE streams SET ingest_mode = 'url' WHERE ingest_mode IS NULL;
@@ -0,0 +1,18 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS backup_ingest_urls TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS failover_after_sec INT NOT NULL DEFAULT 0;
+
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS active_source INT NOT NULL DEFAULT 0;
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS failover_count INT NOT NULL DEFAULT 0;
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS last_failover_at TIMESTAMPTZ;
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS last_failover TEXT;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+  id TEXT PRIMARY KEY,
+  at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor TEXT NOT NULL,
+  action TEXT NOT NULL,
+  stream_id TEXT REFERENCES streams(id),
+  detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_stream_at ON audit_log(stream_id, at DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS external_id TEXT;
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS group_title TEXT;
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS logo_url TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streams_external_id ON streams(external_id) WHERE external_id IS NOT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS playlist_key TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_playlist_key ON users(playlist_key) WHERE playlist_key IS NOT NULL;
+
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS source TEXT;
+CREATE INDEX IF NOT EXISTS idx_playback_sessions_launcher ON playback_sessions(user_id, stream_id) WHERE source = 'launcher';
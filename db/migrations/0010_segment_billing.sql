@@ -0,0 +1,2 @@
+ALTER TABLE playback_sessions ADD COLUMN IF NOT EXISTS billing_mode TEXT NOT NULL DEFAULT 'heartbeat';
+CREATE INDEX IF NOT EXISTS idx_playback_sessions_segment_billing ON playback_sessions(state) WHERE billing_mode = 'segments';
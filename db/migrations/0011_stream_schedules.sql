@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS stream_schedules (
+  id TEXT PRIMARY KEY,
+  stream_id TEXT NOT NULL REFERENCES streams(id),
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  category TEXT NOT NULL DEFAULT '',
+  start_at TIMESTAMPTZ,
+  cron TEXT,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  duration_minutes INT NOT NULL CHECK (duration_minutes > 0),
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_started_at TIMESTAMPTZ,
+  last_stopped_at TIMESTAMPTZ,
+  last_error TEXT,
+  CHECK ((start_at IS NULL) <> (cron IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_schedules_stream ON stream_schedules(stream_id);
+CREATE INDEX IF NOT EXISTS idx_stream_schedules_enabled ON stream_schedules(enabled) WHERE enabled;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS epg_programmes (
+  id TEXT PRIMARY KEY,
+  stream_id TEXT NOT NULL REFERENCES streams(id),
+  channel_id TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  category TEXT NOT NULL DEFAULT '',
+  start_at TIMESTAMPTZ NOT NULL,
+  end_at TIMESTAMPTZ NOT NULL,
+  imported_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (end_at > start_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_epg_programmes_stream_time ON epg_programmes(stream_id, start_at, end_at);
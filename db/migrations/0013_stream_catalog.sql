@@ -0,0 +1,5 @@
+ALTER TABLE streams ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_streams_catalog_status ON streams(status) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_streams_catalog_group ON streams(lower(group_title)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_streams_catalog_name ON streams(lower(name)) WHERE deleted_at IS NULL;
@@ -0,0 +1,14 @@
+ALTER TABLE streams DROP CONSTRAINT IF EXISTS streams_status_check;
+ALTER TABLE streams ADD CONSTRAINT streams_status_check CHECK (status IN ('draft','starting','live','stopping','paused','disabled'));
+
+CREATE TABLE IF NOT EXISTS stream_state_history (
+  id TEXT PRIMARY KEY,
+  stream_id TEXT NOT NULL REFERENCES streams(id),
+  from_state TEXT NOT NULL,
+  to_state TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stream_state_history_stream_at ON stream_state_history(stream_id, at DESC);
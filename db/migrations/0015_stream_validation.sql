@@ -0,0 +1,5 @@
+UPDATE streams SET segment_duration_sec = 4 WHERE segment_duration_sec < 1;
+UPDATE streams SET playlist_window_minutes = 2 WHERE playlist_window_minutes < 1;
+UPDATE streams SET max_concurrent_sessions = 1 WHERE max_concurrent_sessions < 1;
+
+ALTER TABLE stream_runtime ADD COLUMN IF NOT EXISTS config_generation INT NOT NULL DEFAULT 0;
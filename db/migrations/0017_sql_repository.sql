@@ -0,0 +1,9 @@
+ALTER TABLE playback_sessions DROP CONSTRAINT IF EXISTS playback_sessions_state_check;
+ALTER TABLE playback_sessions ADD CONSTRAINT playback_sessions_state_check CHECK (state IN ('active','blocked','stopped','expired'));
+
+ALTER TABLE playback_sessions DROP CONSTRAINT IF EXISTS playback_sessions_recording_id_fkey;
+ALTER TABLE playback_sessions ADD CONSTRAINT playback_sessions_recording_id_fkey FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE SET NULL;
+ALTER TABLE playback_sessions DROP CONSTRAINT IF EXISTS playback_sessions_clip_id_fkey;
+ALTER TABLE playback_sessions ADD CONSTRAINT playback_sessions_clip_id_fkey FOREIGN KEY (clip_id) REFERENCES clips(id) ON DELETE SET NULL;
+ALTER TABLE clips DROP CONSTRAINT IF EXISTS clips_recording_id_fkey;
+ALTER TABLE clips ADD CONSTRAINT clips_recording_id_fkey FOREIGN KEY (recording_id) REFERENCES recordings(id) ON DELETE SET NULL;
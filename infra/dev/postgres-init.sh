@@ -0,0 +1,14 @@
+#!/bin/sh
+set -e
+
+for f in /migrations/*.sql; do
+  psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" -f "$f"
+done
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<'SQL'
+INSERT INTO users (id, email, password_hash, role, status) VALUES
+  ('u_admin', 'admin@local', 'admin', 'admin', 'active'),
+  ('u_demo', 'demo@local', 'demo', 'user', 'active')
+ON CONFLICT (id) DO NOTHING;
+INSERT INTO wallets (user_id, balance_points) VALUES ('u_demo', 1000) ON CONFLICT (user_id) DO NOTHING;
+SQL
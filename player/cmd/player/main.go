@@ -1,14 +1,16 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
+
+	"streamweb/player/internal/apiclient"
 )
 
 type SessionState struct {
@@ -35,30 +37,17 @@ func loadState() (SessionState, error) {
 	return s, err
 }
 
-func postJSON(url string, body any) (*http.Response, error) {
-	b, _ := json.Marshal(body)
-	return http.Post(url, "application/json", bytes.NewReader(b))
-}
-
-func login(api string) error {
+func login(c *apiclient.Client) error {
 	var email, password string
 	fmt.Print("email: ")
 	fmt.Scanln(&email)
 	fmt.Print("password: ")
 	fmt.Scanln(&password)
 
-	res, err := postJSON(api+"/auth/login", map[string]string{"email": email, "password": password})
+	out, err := c.Login(context.Background(), apiclient.LoginRequest{Email: email, Password: password})
 	if err != nil {
-		return err
+		return fmt.Errorf("login failed: %w", err)
 	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("login failed: %d", res.StatusCode)
-	}
-	var out struct {
-		AccessToken string `json:"access_token"`
-	}
-	_ = json.NewDecoder(res.Body).Decode(&out)
 	if out.AccessToken == "" {
 		return fmt.Errorf("empty token")
 	}
@@ -69,24 +58,16 @@ func login(api string) error {
 	return nil
 }
 
-func play(api, streamID string) error {
+func play(c *apiclient.Client, streamID string) error {
 	s, err := loadState()
 	if err != nil {
 		return fmt.Errorf("not logged in")
 	}
-	res, err := postJSON(api+"/playback/start", map[string]string{"stream_id": streamID, "token": s.Token})
+	ctx := context.Background()
+	out, err := c.StartPlayback(ctx, apiclient.PlaybackRequest{StreamID: streamID, Token: s.Token})
 	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
-	if res.StatusCode != 200 {
-		return fmt.Errorf("playback start failed: %d", res.StatusCode)
+		return fmt.Errorf("playback start failed: %w", err)
 	}
-	var out struct {
-		SessionID string `json:"session_id"`
-		PlayURL   string `json:"play_url"`
-	}
-	_ = json.NewDecoder(res.Body).Decode(&out)
 	fmt.Println("session:", out.SessionID)
 	fmt.Println("play_url:", out.PlayURL)
 
@@ -98,24 +79,21 @@ func play(api, streamID string) error {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
+	session := apiclient.SessionRequest{SessionID: out.SessionID}
 	for range ticker.C {
-		hbRes, err := postJSON(api+"/playback/heartbeat", map[string]string{"session_id": out.SessionID})
+		hb, err := c.PlaybackHeartbeat(ctx, session)
+		var problem *apiclient.Problem
+		if errors.As(err, &problem) && problem.Code == "insufficient_points" {
+			fmt.Printf("heartbeat state=blocked balance=%d\n", problem.BalancePoints)
+			_ = cmd.Process.Kill()
+			_, _ = c.StopPlayback(ctx, session)
+			return fmt.Errorf("session blocked (points exhausted or kicked)")
+		}
 		if err != nil {
 			fmt.Println("heartbeat error:", err)
 			continue
 		}
-		var hb struct {
-			State   string `json:"state"`
-			Balance int64  `json:"balance_points"`
-		}
-		_ = json.NewDecoder(hbRes.Body).Decode(&hb)
-		hbRes.Body.Close()
-		fmt.Printf("heartbeat state=%s balance=%d\n", hb.State, hb.Balance)
-		if hb.State == "blocked" || hbRes.StatusCode == 402 {
-			_ = cmd.Process.Kill()
-			_, _ = postJSON(api+"/playback/stop", map[string]string{"session_id": out.SessionID})
-			return fmt.Errorf("session blocked (points exhausted or kicked)")
-		}
+		fmt.Printf("heartbeat state=%s balance=%d\n", hb.State, hb.BalancePoints)
 	}
 	return nil
 }
@@ -137,13 +115,13 @@ func main() {
 	var err error
 	switch os.Args[1] {
 	case "login":
-		err = login(api)
+		err = login(apiclient.New(api))
 	case "play":
 		if len(os.Args) < 3 {
 			fmt.Println("usage: player play <stream_id>")
 			os.Exit(1)
 		}
-		err = play(api, os.Args[2])
+		err = play(apiclient.New(api), os.Args[2])
 	case "logout":
 		err = logout()
 	default:
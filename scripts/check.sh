@@ -0,0 +1,10 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+(cd api && go build ./... && go vet ./... && go test ./...)
+(cd player && go build ./... && go vet ./... && go test ./...)
+
+(cd api && go generate ./internal/httpapi)
+git diff --exit-code -- player/internal/apiclient